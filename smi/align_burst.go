@@ -0,0 +1,92 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// AlignBursts64 is a goroutine which splits read and write request frames
+// that cross a cache line boundary into a sequence of request frames which
+// each lie within a single cache line of the specified size. This allows
+// arbitrary upstream requests to be issued efficiently to memory controllers
+// which favour line aligned bursts. The first fragment runs from the request
+// address to the end of its cache line, subsequent fragments cover complete
+// cache lines and the final fragment carries any remaining tail. The address
+// of each fragment is incremented by the length of the preceding fragments,
+// so the write payload bytes for each fragment are those which map to its
+// address range. The options and tag bytes of the original request are
+// preserved and the fragment length for write requests is taken from the
+// write payload. Write requests with byte strobes are split in the same way,
+// with each fragment carrying the part of the strobe mask for its own write
+// data, and are forwarded unchanged if the strobe mask and write data do not
+// match the length field. All other frames, and requests which lie within a
+// single cache line, are forwarded unchanged. A separate response is
+// generated for each fragment, as for SplitBurst64. A line size of zero
+// disables splitting.
+//
+func AlignBursts64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	lineBytes uint64) {
+
+	for {
+		frame := receiveFrame64(smiInput)
+		frameBytes := unpackFrame64(frame)
+		isWrite := len(frameBytes) >= smiMemReqHeaderSize &&
+			frameBytes[0] == SmiMemWriteReq
+		isRead := len(frameBytes) >= smiMemReqHeaderSize &&
+			frameBytes[0] == SmiMemReadReq
+		if lineBytes == 0 || (!isWrite && !isRead) {
+			sendFrame64(smiOutput, frame)
+			continue
+		}
+
+		// Determine the total transfer length.
+		reqAddr := frameBytesAddr(frameBytes)
+		reqLength := uint64(frameBytesLength(frameBytes))
+		var reqEnables []bool
+		var reqData []byte
+		if isWrite {
+			reqStrobes, writeData, isValid := writePayload(frameBytes)
+			if !isValid {
+				sendFrame64(smiOutput, frame)
+				continue
+			}
+			reqData = writeData
+			reqLength = uint64(len(reqData))
+			reqEnables = writeEnables(reqStrobes, len(reqData))
+		}
+		if reqLength == 0 || reqAddr%lineBytes+reqLength <= lineBytes {
+			sendFrame64(smiOutput, frame)
+			continue
+		}
+
+		// Issue the fragments, ending each one on a cache line boundary.
+		fragmentStart := uint64(0)
+		for fragmentStart != reqLength {
+			fragmentAddr := reqAddr + fragmentStart
+			fragmentEnd := fragmentStart + lineBytes - fragmentAddr%lineBytes
+			if fragmentEnd > reqLength {
+				fragmentEnd = reqLength
+			}
+			var fragmentBytes []byte
+			if isWrite {
+				var fragmentEnables []bool
+				if reqEnables != nil {
+					fragmentEnables = reqEnables[fragmentStart:fragmentEnd]
+				}
+				fragmentBytes = strobedWriteReqBytes(fragmentAddr,
+					frameBytes[1], frameBytes[2], frameBytes[3],
+					fragmentEnables, reqData[fragmentStart:fragmentEnd])
+			} else {
+				fragmentBytes = readReqBytes(fragmentAddr,
+					frameBytes[1], frameBytes[2], frameBytes[3],
+					uint16(fragmentEnd-fragmentStart))
+			}
+			sendFrame64(smiOutput, packFrame64(fragmentBytes))
+			fragmentStart = fragmentEnd
+		}
+	}
+}
@@ -0,0 +1,83 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAlignBurstsWriteOffset40(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 64)
+	go AlignBursts64(smiInput, smiOutput, 64)
+
+	writeData := make([]byte, 100)
+	for i := range writeData {
+		writeData[i] = uint8(i * 3)
+	}
+	go sendFrame64(smiInput, packFrame64(writeReqBytes(40, DefaultOptions, 0x11, 0x22, writeData)))
+
+	expectAddrs := []uint64{40, 64, 128}
+	expectLengths := []int{24, 64, 12}
+	payloadStart := 0
+	for i, expectLength := range expectLengths {
+		frame := recvFrame(t, smiOutput)
+		frameBytes := unpackFrame64(frame)
+		header := [2]Flit64{frame[0], frame[1]}
+		if frameBytes[0] != SmiMemWriteReq || frameBytes[2] != 0x11 || frameBytes[3] != 0x22 ||
+			ReadAddr(header) != expectAddrs[i] || int(ReadLength(header)) != expectLength {
+			t.Fatalf("unexpected header for fragment %d %v", i, header)
+		}
+		if !bytes.Equal(frameBytes[smiMemReqHeaderSize:],
+			writeData[payloadStart:payloadStart+expectLength]) {
+			t.Fatalf("unexpected payload for fragment %d", i)
+		}
+		payloadStart += expectLength
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
+
+func TestAlignBurstsRead(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 16)
+	go AlignBursts64(smiInput, smiOutput, 64)
+
+	go sendFrame64(smiInput, ReadReqFrames64(0x1030, 200, 5))
+	expectAddrs := []uint64{0x1030, 0x1040, 0x1080, 0x10C0}
+	expectLengths := []uint16{16, 64, 64, 56}
+	for i, expectLength := range expectLengths {
+		frame := recvFrame(t, smiOutput)
+		header := [2]Flit64{frame[0], frame[1]}
+		if len(frame) != 2 || frame[0].Data[0] != SmiMemReadReq || frame[0].Data[3] != 5 ||
+			ReadAddr(header) != expectAddrs[i] || ReadLength(header) != expectLength {
+			t.Fatalf("unexpected fragment %d %v", i, frame)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
+
+func TestAlignBurstsWithinLine(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 16)
+	go AlignBursts64(smiInput, smiOutput, 64)
+
+	// Requests which end exactly on a line boundary are not split.
+	frames := [][]Flit64{
+		WriteReqFrames64(0x20, make([]byte, 32), nil, 1),
+		ReadReqFrames64(0x40, 64, 2),
+	}
+	for i, frame := range frames {
+		go sendFrame64(smiInput, frame)
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("request %d: unexpected frame %v", i, output)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
@@ -0,0 +1,131 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX2Aging is a variant of ArbitrateX2 which provides a tunable
+// tradeoff between strict priority and round robin arbitration. Upstream
+// port A is the high priority port and is granted access whenever port B has
+// no pending request. When both ports are waiting, port A wins and the aging
+// counter for port B is incremented by the specified aging rate. Once the
+// aging counter reaches 255, port B wins the next arbitration and its aging
+// counter is reset. The aging rate is therefore measured in counter units
+// per frame which port B loses to port A. An aging rate of zero gives strict
+// priority to port A, which may starve port B indefinitely. An aging rate of
+// 255 allows port B to win after losing once, which is equivalent to round
+// robin arbitration when both ports are busy. Intermediate values allow
+// port A to issue approximately 255 / agingRate frames for each frame issued
+// by port B when both are busy.
+//
+func ArbitrateX2Aging(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	agingRate uint8) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		isPendingA := false
+		isPendingB := false
+		agingCount := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// port so that contention can be detected.
+			if !isPendingA && !isPendingB {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				case <-transferReqB:
+					isPendingB = true
+				}
+			}
+			if !isPendingA {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				default:
+				}
+			}
+			if !isPendingB {
+				select {
+				case <-transferReqB:
+					isPendingB = true
+				default:
+				}
+			}
+
+			// Select the port to be granted, updating the aging counter.
+			var portId uint8
+			if isPendingB && (!isPendingA || agingCount == 255) {
+				portId = 2
+				isPendingB = false
+				agingCount = 0
+			} else {
+				portId = 1
+				isPendingA = false
+				if isPendingB {
+					if agingCount > 255-agingRate {
+						agingCount = 255
+					} else {
+						agingCount += agingRate
+					}
+				}
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
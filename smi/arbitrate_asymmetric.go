@@ -0,0 +1,217 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// manageReadUpstreamPort is a variant of manageUpstreamPort for upstream
+// ports which only issue read requests. Since read request frames always
+// consist of exactly two flits, the request frame is copied without checking
+// the end of frame markers.
+//
+func manageReadUpstreamPort(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		for {
+
+			// Do tag replacement on header.
+			headerFlit := <-upstreamRequest
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy the second read request flit from upstream to downstream.
+			taggedRequest <- <-upstreamRequest
+		}
+	}()
+
+	// Carry out tag replacement on responses.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-taggedResponse
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		tagFifo <- tagId
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
+
+//
+// ArbitrateR6W1 is a goroutine for providing arbitration between six read
+// only pairs of SMI request/response channels and a single read/write pair.
+// This uses the same tag matching and substitution scheme as ArbitrateX4,
+// but the read only ports A to F must only issue SmiMemReadReq frames. This
+// allows the request paths for those ports to be simplified, since each
+// request frame always consists of exactly two flits. Issuing any other
+// request type on a read only port will result in the downstream request
+// stream becoming corrupted. The read/write port W may issue any request.
+//
+func ArbitrateR6W1(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	upstreamRequestD <-chan Flit64,
+	upstreamResponseD chan<- Flit64,
+	upstreamRequestE <-chan Flit64,
+	upstreamResponseE chan<- Flit64,
+	upstreamRequestF <-chan Flit64,
+	upstreamResponseF chan<- Flit64,
+	upstreamRequestW <-chan Flit64,
+	upstreamResponseW chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	taggedRequestD := make(chan Flit64, 1)
+	taggedResponseD := make(chan Flit64, 1)
+	taggedRequestE := make(chan Flit64, 1)
+	taggedResponseE := make(chan Flit64, 1)
+	taggedRequestF := make(chan Flit64, 1)
+	taggedResponseF := make(chan Flit64, 1)
+	taggedRequestW := make(chan Flit64, 1)
+	taggedResponseW := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+	transferReqD := make(chan uint8, 1)
+	transferReqE := make(chan uint8, 1)
+	transferReqF := make(chan uint8, 1)
+	transferReqW := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageReadUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageReadUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+	go manageReadUpstreamPort(upstreamRequestC, upstreamResponseC,
+		taggedRequestC, taggedResponseC, transferReqC, uint8(3))
+	go manageReadUpstreamPort(upstreamRequestD, upstreamResponseD,
+		taggedRequestD, taggedResponseD, transferReqD, uint8(4))
+	go manageReadUpstreamPort(upstreamRequestE, upstreamResponseE,
+		taggedRequestE, taggedResponseE, transferReqE, uint8(5))
+	go manageReadUpstreamPort(upstreamRequestF, upstreamResponseF,
+		taggedRequestF, taggedResponseF, transferReqF, uint8(6))
+	go manageUpstreamPort(upstreamRequestW, upstreamResponseW,
+		taggedRequestW, taggedResponseW, transferReqW, uint8(7))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			case portId = <-transferReqC:
+			case portId = <-transferReqD:
+			case portId = <-transferReqE:
+			case portId = <-transferReqF:
+			case portId = <-transferReqW:
+			}
+
+			// Copy over input data. Read only ports always issue two flits.
+			switch portId {
+			case 1:
+				downstreamRequest <- <-taggedRequestA
+				downstreamRequest <- <-taggedRequestA
+			case 2:
+				downstreamRequest <- <-taggedRequestB
+				downstreamRequest <- <-taggedRequestB
+			case 3:
+				downstreamRequest <- <-taggedRequestC
+				downstreamRequest <- <-taggedRequestC
+			case 4:
+				downstreamRequest <- <-taggedRequestD
+				downstreamRequest <- <-taggedRequestD
+			case 5:
+				downstreamRequest <- <-taggedRequestE
+				downstreamRequest <- <-taggedRequestE
+			case 6:
+				downstreamRequest <- <-taggedRequestF
+				downstreamRequest <- <-taggedRequestF
+			default:
+				moreFlits := true
+				for moreFlits {
+					reqFlit := <-taggedRequestW
+					downstreamRequest <- reqFlit
+					moreFlits = reqFlit.Eofc == 0
+				}
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		case 4:
+			taggedResponseD <- respFlit
+		case 5:
+			taggedResponseE <- respFlit
+		case 6:
+			taggedResponseF <- respFlit
+		case 7:
+			taggedResponseW <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,149 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// manageCutThroughRequestPort provides the request side transaction
+// management for the cut-through arbiter. This carries out the same tag
+// substitution as manageUpstreamPort, but the tag tables and tag FIFO are
+// supplied by the caller so that tag restoration can be carried out directly
+// by the response steering logic.
+//
+func manageCutThroughRequestPort(
+	upstreamRequest <-chan Flit64,
+	taggedRequest chan<- Flit64,
+	transferReq chan<- uint8,
+	tagFifo chan uint8,
+	tagTableLower *[4]uint8,
+	tagTableUpper *[4]uint8,
+	portId uint8) {
+
+	for {
+
+		// Do tag replacement on header.
+		headerFlit := <-upstreamRequest
+		tagId := <-tagFifo
+		tagTableLower[tagId] = headerFlit.Data[2]
+		tagTableUpper[tagId] = headerFlit.Data[3]
+		headerFlit.Data[2] = portId
+		headerFlit.Data[3] = tagId
+		transferReq <- portId
+		taggedRequest <- headerFlit
+
+		// Copy remaining flits from upstream to downstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-upstreamRequest
+			moreFlits = bodyFlit.Eofc == 0
+			taggedRequest <- bodyFlit
+		}
+	}
+}
+
+//
+// ArbitrateX2CutThrough is a variant of ArbitrateX2 with a guaranteed minimum
+// latency response path. The response steering logic restores the original
+// tags itself and sends each response flit directly to the selected upstream
+// response channel, so each flit is offered to the upstream port in the same
+// handshake in which it is received from the downstream port, regardless of
+// the length of the frame. No intermediate buffering is used on the response
+// path, which means that an upstream port which does not accept its response
+// flits will also stall the responses for the other port. The upstream
+// response channels may be buffered by the caller if isolation between ports
+// is required, at the cost of the additional buffer latency.
+//
+func ArbitrateX2CutThrough(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Set up the tag tables and local tag values for each port.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLowerA [4]uint8
+	var tagTableUpperA [4]uint8
+	var tagTableLowerB [4]uint8
+	var tagTableUpperB [4]uint8
+	tagFifoA := make(chan uint8, 4)
+	tagFifoB := make(chan uint8, 4)
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifoA <- tagInit
+		tagFifoB <- tagInit
+	}
+
+	// Run the upstream port request management routines.
+	go manageCutThroughRequestPort(upstreamRequestA, taggedRequestA,
+		transferReqA, tagFifoA, &tagTableLowerA, &tagTableUpperA, uint8(1))
+	go manageCutThroughRequestPort(upstreamRequestB, taggedRequestB,
+		transferReqB, tagFifoB, &tagTableLowerB, &tagTableUpperB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses, restoring the tags on header flits.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+			tagId := respFlit.Data[3] & 0x03
+			switch portId {
+			case 1:
+				respFlit.Data[2] = tagTableLowerA[tagId]
+				respFlit.Data[3] = tagTableUpperA[tagId]
+				tagFifoA <- tagId
+			case 2:
+				respFlit.Data[2] = tagTableLowerB[tagId]
+				respFlit.Data[3] = tagTableUpperB[tagId]
+				tagFifoB <- tagId
+			}
+		}
+		switch portId {
+		case 1:
+			upstreamResponseA <- respFlit
+		case 2:
+			upstreamResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,62 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCutThroughFirstPayloadFlit(t *testing.T) {
+	for _, readLength := range []uint32{16, 256, 2048} {
+		upstreamRequestA := make(chan Flit64)
+		upstreamResponseA := make(chan Flit64)
+		upstreamRequestB := make(chan Flit64)
+		upstreamResponseB := make(chan Flit64)
+		downstreamRequest := make(chan Flit64)
+		downstreamResponse := make(chan Flit64)
+		go ArbitrateX2CutThrough(upstreamRequestA, upstreamResponseA,
+			upstreamRequestB, upstreamResponseB,
+			downstreamRequest, downstreamResponse)
+
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0x1000, readLength, 0x5A))
+		reqHeader := recvFrame(t, downstreamRequest)[0]
+		respBytes := append([]byte{SmiMemReadResp, 0, reqHeader.Data[2], reqHeader.Data[3]},
+			make([]byte, readLength)...)
+		for i := 4; i != len(respBytes); i++ {
+			respBytes[i] = uint8(i)
+		}
+		respFrame := packFrame64(respBytes)
+
+		// Each flit must reach the upstream port before the next flit is
+		// accepted from downstream, so that none are held in the arbiter.
+		// Only the header and first payload flit are checked, with the
+		// rest of the frame being sent after the first payload flit has
+		// been received.
+		for i := 0; i != 2; i++ {
+			sendFrame(t, downstreamResponse, respFrame[i:i+1])
+			if tryOffer(downstreamResponse, respFrame[i+1], 10*time.Millisecond) {
+				t.Fatalf("length %d: flit %d buffered by arbiter", readLength, i)
+			}
+			respFlit := recvFlit(t, upstreamResponseA)
+			if i == 0 {
+				expectHeader := respFrame[0]
+				expectHeader.Data[2] = 0
+				expectHeader.Data[3] = 0x5A
+				if respFlit != expectHeader {
+					t.Fatalf("length %d: unexpected header %v", readLength, respFlit)
+				}
+			} else if respFlit != respFrame[1] {
+				t.Fatalf("length %d: unexpected payload flit %v", readLength, respFlit)
+			}
+		}
+		go sendFrame64(downstreamResponse, respFrame[2:])
+		if remaining := recvFrame(t, upstreamResponseA); len(remaining) != len(respFrame)-2 {
+			t.Fatalf("length %d: unexpected frame length", readLength)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Request frames for ArbitrateX2Deadline carry an 8-bit deadline hint in the
+// most significant address byte, which is byte 3 of the second header flit.
+// The deadline is an absolute value of the 8-bit tick counter maintained by
+// the arbiter, so a master which wants its request to be issued within N
+// ticks sets the deadline to the current tick count plus N. Deadlines are
+// compared using the wrapping difference from the current tick count, so
+// deadlines which are up to 127 ticks in the future or up to 128 ticks in
+// the past are ordered correctly. The deadline byte is cleared by the
+// arbiter before the request is forwarded, which limits the usable address
+// range to 56 bits. Since the deadline byte is also the most commonly unused
+// header byte, it is reserved for the deadline hint and can not be selected
+// as a frame stamp position for StampFrames64 or ReadFrameStamps64.
+//
+
+//
+// ReadDeadline extracts the deadline hint from the header flits of a request
+// frame.
+//
+func ReadDeadline(header [2]Flit64) uint8 {
+	return header[1].Data[3]
+}
+
+//
+// WriteDeadline inserts the deadline hint into the header flits of a request
+// frame. Since the deadline shares a byte with the memory address, it must
+// be written after the address has been set using WriteAddr.
+//
+func WriteDeadline(header *[2]Flit64, deadline uint8) {
+	header[1].Data[3] = deadline
+}
+
+//
+// ArbitrateX2Deadline is a variant of ArbitrateX2 which provides soft
+// deadline scheduling. A goroutine counts the ticks received on the tick
+// channel, so the tick source is never blocked while a frame is being
+// transferred. The arbiter reads both header flits of each pending request
+// so that the deadline hint can be inspected. When both ports have a pending
+// request, the port whose deadline is nearest to the current tick count is
+// granted access, with deadlines which have already passed being the most
+// urgent. Requests with equal deadlines are granted in round robin order.
+// When only one port has a pending request it is granted immediately,
+// regardless of its deadline, so a request is only overtaken if the other
+// request is already waiting when the arbitration decision is made.
+//
+func ArbitrateX2Deadline(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	tick <-chan struct{}) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	tickCount := make(chan uint8)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Maintain the shared tick counter.
+	go func() {
+		tickValue := uint8(0)
+		for {
+			select {
+			case <-tick:
+				tickValue++
+			case tickCount <- tickValue:
+			}
+		}
+	}()
+
+	// Arbitrate between transfer requests.
+	go func() {
+		var headerA [2]Flit64
+		var headerB [2]Flit64
+		isPendingA := false
+		isPendingB := false
+		lastPortId := uint8(2)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// port so that contention can be detected. The header flits of
+			// each new request are held for deadline inspection.
+			if !isPendingA && !isPendingB {
+				select {
+				case <-transferReqA:
+					headerA = receiveDeadlineHeader(taggedRequestA)
+					isPendingA = true
+				case <-transferReqB:
+					headerB = receiveDeadlineHeader(taggedRequestB)
+					isPendingB = true
+				}
+			}
+			if !isPendingA {
+				select {
+				case <-transferReqA:
+					headerA = receiveDeadlineHeader(taggedRequestA)
+					isPendingA = true
+				default:
+				}
+			}
+			if !isPendingB {
+				select {
+				case <-transferReqB:
+					headerB = receiveDeadlineHeader(taggedRequestB)
+					isPendingB = true
+				default:
+				}
+			}
+
+			// Select the port with the nearest deadline, using round robin
+			// ordering for equal deadlines.
+			portId := uint8(1)
+			if !isPendingA {
+				portId = 2
+			} else if isPendingB {
+				currentTick := <-tickCount
+				slackA := int8(ReadDeadline(headerA) - currentTick)
+				slackB := int8(ReadDeadline(headerB) - currentTick)
+				if slackB < slackA || (slackB == slackA && lastPortId == 1) {
+					portId = 2
+				}
+			}
+			lastPortId = portId
+
+			// Forward the held header flits, then copy over the remaining
+			// input data.
+			header := headerA
+			if portId == 1 {
+				isPendingA = false
+			} else {
+				header = headerB
+				isPendingB = false
+			}
+			WriteDeadline(&header, 0)
+			downstreamRequest <- header[0]
+			moreFlits := header[0].Eofc == 0
+			if moreFlits {
+				downstreamRequest <- header[1]
+				moreFlits = header[1].Eofc == 0
+			}
+			for moreFlits {
+				var reqFlit Flit64
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
+
+//
+// receiveDeadlineHeader reads the header flits of a request frame from a
+// tagged request channel. Only the first flit is read if it is the last flit
+// in the frame, in which case the deadline hint is treated as zero.
+//
+func receiveDeadlineHeader(taggedRequest <-chan Flit64) [2]Flit64 {
+	var header [2]Flit64
+	header[0] = <-taggedRequest
+	if header[0].Eofc == 0 {
+		header[1] = <-taggedRequest
+	}
+	return header
+}
@@ -0,0 +1,89 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// deadlineReqFrame builds a header only read request frame carrying the
+// specified deadline hint.
+//
+func deadlineReqFrame(tag uint8, deadline uint8) []Flit64 {
+	frame := ReadReqFrames64(0x1000, 8, tag)
+	var header [2]Flit64
+	copy(header[:], frame)
+	WriteDeadline(&header, deadline)
+	return header[:]
+}
+
+//
+// checkDeadlineOrder sends a filler frame followed by a frame with deadline
+// A on port A and then a frame with deadline B on port B, while the
+// downstream request channel is stalled. Once the downstream channel is
+// serviced, the frames from the two ports must be issued in the expected
+// order, with the deadline bytes cleared.
+//
+func checkDeadlineOrder(t *testing.T, deadlineA uint8, deadlineB uint8, expectBFirst bool) {
+	t.Helper()
+	upstreamRequestA := make(chan Flit64)
+	upstreamRequestB := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	tick := make(chan struct{})
+	go ArbitrateX2Deadline(upstreamRequestA, make(chan Flit64),
+		upstreamRequestB, make(chan Flit64),
+		downstreamRequest, make(chan Flit64), tick)
+
+	// Advance the tick counter to 10.
+	for i := 0; i != 10; i++ {
+		tick <- struct{}{}
+	}
+	go func() {
+		sendFrame64(upstreamRequestA, deadlineReqFrame(0, 0))
+		sendFrame64(upstreamRequestA, deadlineReqFrame(1, deadlineA))
+	}()
+	time.Sleep(5 * time.Millisecond)
+	go sendFrame64(upstreamRequestB, deadlineReqFrame(2, deadlineB))
+	time.Sleep(5 * time.Millisecond)
+
+	recvFrame(t, downstreamRequest)
+	expectedPorts := []uint8{1, 2}
+	if expectBFirst {
+		expectedPorts = []uint8{2, 1}
+	}
+	for _, expectedPort := range expectedPorts {
+		frame := recvFrame(t, downstreamRequest)
+		if frame[0].Data[2] != expectedPort || frame[1].Data[3] != 0 {
+			t.Fatalf("unexpected frame %v, expected port %d", frame, expectedPort)
+		}
+	}
+}
+
+func TestArbitrateDeadlineEarlierWins(t *testing.T) {
+	// Port A requests first, but port B has the nearer deadline. This is
+	// repeated so that a random choice between the ports would fail.
+	for i := 0; i != 50; i++ {
+		checkDeadlineOrder(t, 10+100, 10+5, true)
+	}
+}
+
+func TestArbitrateDeadlinePassedWins(t *testing.T) {
+	for i := 0; i != 50; i++ {
+		checkDeadlineOrder(t, 10-3, 10+5, false)
+	}
+}
+
+func TestStampPositionExcludesDeadline(t *testing.T) {
+	if isValidStampPosition(1, 3) {
+		t.Fatal("deadline byte accepted as stamp position")
+	}
+	if !isValidStampPosition(1, 2) {
+		t.Fatal("recommended stamp position rejected")
+	}
+}
@@ -0,0 +1,89 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX2Decoupled is a variant of ArbitrateX2 in which request
+// arbitration and response steering are decoupled by frame buffers. In the
+// standard arbitrator the internal request and response channels only hold a
+// single flit, so a slow upstream response consumer stalls response steering
+// for all ports, and request transfers proceed in lockstep with the upstream
+// producers. Here each internal channel has capacity for a complete frame,
+// which allows request arbitration to run ahead of response steering up to
+// the in-flight limit when the downstream accepts requests faster than it
+// returns responses, and allows the response steering to accept a complete
+// frame for one port while the upstream consumer for another port is busy.
+// TODO: Update once there is a fix for the channel size compiler limitation.
+//
+func ArbitrateX2Decoupled(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+	taggedResponseA := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+	taggedRequestB := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+	taggedResponseB := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+	transferReqA := make(chan uint8, 4 /* SmiMemInFlightLimit */)
+	transferReqB := make(chan uint8, 4 /* SmiMemInFlightLimit */)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
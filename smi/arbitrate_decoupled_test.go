@@ -0,0 +1,91 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// asymmetricLatencyModel is a downstream endpoint which accepts requests
+// immediately, but returns a 24 byte read response for each request at a
+// slower fixed pace.
+//
+func asymmetricLatencyModel(
+	smiRequest <-chan Flit64,
+	smiResponse chan<- Flit64,
+	respInterval time.Duration) {
+
+	pendingHeaders := make(chan Flit64, 64)
+	go func() {
+		for {
+			frame := receiveFrame64(smiRequest)
+			pendingHeaders <- frame[0]
+			putFrame64(frame)
+		}
+	}()
+	for reqHeader := range pendingHeaders {
+		time.Sleep(respInterval)
+		respBytes := append([]byte{SmiMemReadResp, 0, reqHeader.Data[2], reqHeader.Data[3]},
+			make([]byte, 24)...)
+		sendFrame64(smiResponse, packFrame64(respBytes))
+	}
+}
+
+//
+// benchmarkArbiterX2 issues read requests on both ports of an arbiter, with
+// the port B responses being consumed by a slow master, and times how long
+// it takes to receive all the responses.
+//
+func benchmarkArbiterX2(b *testing.B, arbitrateX2 func(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64)) {
+
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go arbitrateX2(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse)
+	go asymmetricLatencyModel(downstreamRequest, downstreamResponse, 10*time.Microsecond)
+
+	b.ResetTimer()
+	done := make(chan bool)
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			for i := 0; i < b.N; i++ {
+				sendFrame64(upstreamRequests[port], ReadReqFrames64(0, 24, uint8(i)))
+			}
+		}()
+		go func() {
+			for i := 0; i < b.N; i++ {
+				receiveFrame64(upstreamResponses[port])
+				if port == 1 {
+					time.Sleep(40 * time.Microsecond)
+				}
+			}
+			done <- true
+		}()
+	}
+	<-done
+	<-done
+}
+
+func BenchmarkArbitrateX2AsymmetricLatency(b *testing.B) {
+	benchmarkArbiterX2(b, ArbitrateX2)
+}
+
+func BenchmarkArbitrateX2DecoupledAsymmetricLatency(b *testing.B) {
+	benchmarkArbiterX2(b, ArbitrateX2Decoupled)
+}
@@ -0,0 +1,85 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbitrateDepthMatching(t *testing.T) {
+	type arbiterFunc func(
+		upstreamRequestA <-chan Flit64,
+		upstreamResponseA chan<- Flit64,
+		upstreamRequestB <-chan Flit64,
+		upstreamResponseB chan<- Flit64,
+		downstreamRequest chan<- Flit64,
+		downstreamResponse <-chan Flit64)
+	arbiters := []struct {
+		depth   int
+		arbiter arbiterFunc
+	}{
+		{1, ArbitrateX2Depth1},
+		{2, ArbitrateX2Depth2},
+		{8, ArbitrateX2Depth8},
+		{16, ArbitrateX2Depth16},
+	}
+	for _, a := range arbiters {
+		upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+		upstreamResponses := []chan Flit64{make(chan Flit64, 64), make(chan Flit64, 64)}
+		downstreamRequest := make(chan Flit64, 256)
+		downstreamResponse := make(chan Flit64)
+		go a.arbiter(upstreamRequests[0], upstreamResponses[0],
+			upstreamRequests[1], upstreamResponses[1],
+			downstreamRequest, downstreamResponse)
+
+		// Each port issues one more request than its in-flight limit, so
+		// the final request on each port is stalled waiting for a tag.
+		for port := 0; port != 2; port++ {
+			port := port
+			go func() {
+				for i := 0; i <= a.depth; i++ {
+					reqId := uint8(port<<7 | i)
+					sendFrame64(upstreamRequests[port], ReadReqFrames64(uint64(reqId), 8, reqId))
+				}
+			}()
+		}
+		reqHeaders := make([]Flit64, 2*a.depth)
+		for i := range reqHeaders {
+			reqHeaders[i] = recvFrame(t, downstreamRequest)[0]
+		}
+		expectIdle(t, downstreamRequest, 10*time.Millisecond)
+
+		// Respond in reverse order, with the read data holding the port ID
+		// and the request address, which is the same as the original tag.
+		for i := len(reqHeaders) - 1; i >= 0; i-- {
+			respBytes := []byte{SmiMemReadResp, 0, reqHeaders[i].Data[2], reqHeaders[i].Data[3],
+				reqHeaders[i].Data[2], reqHeaders[i].Data[4], 0, 0, 0, 0, 0, 0}
+			sendFrame(t, downstreamResponse, packFrame64(respBytes))
+		}
+		for port := 0; port != 2; port++ {
+			seenTags := make(map[uint8]bool)
+			for i := 0; i != a.depth; i++ {
+				readData, tag, err := ParseReadResp64(upstreamResponses[port])
+				if err != nil || int(tag>>7) != port || seenTags[tag] ||
+					int(readData[0]) != port+1 || readData[1] != tag {
+					t.Fatalf("depth %d: unexpected response %v %d %v on port %d",
+						a.depth, readData, tag, err, port+1)
+				}
+				seenTags[tag] = true
+			}
+		}
+
+		// The stalled requests are now issued.
+		for i := 0; i != 2; i++ {
+			reqHeader := recvFrame(t, downstreamRequest)[0]
+			if int(reqHeader.Data[3]) >= a.depth {
+				t.Fatalf("depth %d: local tag %d out of range", a.depth, reqHeader.Data[3])
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// manageDrainedUpstreamPort is a variant of manageUpstreamPort which adds a
+// drain request signal. Once a drain request has been received, no new
+// request frames are accepted at the next frame boundary and a drain
+// complete signal is sent once all the local tags have been returned to the
+// tag FIFO, which indicates that there are no outstanding transactions.
+// Responses are always forwarded, so all transactions which were in flight
+// when the drain request was received will be allowed to complete.
+//
+func manageDrainedUpstreamPort(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	drainReq <-chan bool,
+	drainDone chan<- bool,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		isDraining := false
+		for !isDraining {
+
+			// Only accept new request frames until a drain is requested.
+			var headerFlit Flit64
+			select {
+			case headerFlit = <-upstreamRequest:
+			case <-drainReq:
+				isDraining = true
+				continue
+			}
+
+			// Do tag replacement on header.
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+
+		// Wait for all the local tags to be returned by the response path.
+		for tagCount := uint8(0); tagCount != 4; tagCount++ {
+			<-tagFifo
+		}
+		drainDone <- true
+	}()
+
+	// Carry out tag replacement on responses. This continues while draining
+	// so that outstanding transactions always complete.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-taggedResponse
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+
+		// Only release the tag once the response has been forwarded, so
+		// that drain completion implies all responses have been delivered.
+		tagFifo <- tagId
+	}
+}
+
+//
+// ArbitrateX2Drained is a variant of ArbitrateX2 which supports a drain mode
+// for clean shutdown. Sending on the drain request channel stops both
+// upstream ports from accepting new request frames at their next frame
+// boundary, which applies backpressure to the upstream request channels.
+// Response steering continues until all outstanding transactions have
+// completed and their responses have been forwarded upstream, after which a
+// single drain complete signal is sent. This differs from an immediate
+// shutdown in that no outstanding responses are lost. Once drained, the
+// arbiter does not accept any further requests.
+//
+func ArbitrateX2Drained(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	drainReq <-chan bool,
+	drainDone chan<- bool) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	drainReqA := make(chan bool, 1)
+	drainReqB := make(chan bool, 1)
+	drainDoneA := make(chan bool, 1)
+	drainDoneB := make(chan bool, 1)
+
+	// Run the upstream port management routines.
+	go manageDrainedUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, drainReqA, drainDoneA, uint8(1))
+	go manageDrainedUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, drainReqB, drainDoneB, uint8(2))
+
+	// Distribute the drain request and wait for both ports to complete.
+	go func() {
+		<-drainReq
+		drainReqA <- true
+		drainReqB <- true
+		<-drainDoneA
+		<-drainDoneB
+		drainDone <- true
+	}()
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
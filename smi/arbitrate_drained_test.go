@@ -0,0 +1,72 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbitrateX2DrainedKeepsResponses(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 16)
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	drainReq := make(chan bool)
+	drainDone := make(chan bool, 1)
+	go ArbitrateX2Drained(upstreamRequestA, upstreamResponseA,
+		upstreamRequestB, upstreamResponseB,
+		downstreamRequest, downstreamResponse, drainReq, drainDone)
+
+	// Leave three requests from port A and one from port B outstanding.
+	reqHeaders := []Flit64{}
+	for i := 0; i != 4; i++ {
+		upstreamRequest := upstreamRequestA
+		if i == 3 {
+			upstreamRequest = upstreamRequestB
+		}
+		go sendFrame64(upstreamRequest, ReadReqFrames64(0, 8, uint8(i)))
+		reqHeaders = append(reqHeaders, recvFrame(t, downstreamRequest)[0])
+	}
+	drainReq <- true
+	time.Sleep(10 * time.Millisecond)
+
+	// No new requests are accepted while draining.
+	select {
+	case upstreamRequestA <- ReadReqFrames64(0, 8, 4)[0]:
+		t.Fatal("request accepted while draining")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Drain only completes once all the responses have been delivered.
+	for i := 3; i >= 0; i-- {
+		select {
+		case <-drainDone:
+			t.Fatalf("drain completed with %d responses outstanding", i+1)
+		default:
+		}
+		respBytes := append([]byte{SmiMemReadResp, 0, reqHeaders[i].Data[2], reqHeaders[i].Data[3]},
+			uint8(i), 0, 0, 0, 0, 0, 0, 0)
+		sendFrame(t, downstreamResponse, packFrame64(respBytes))
+	}
+	select {
+	case <-drainDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for drain completion")
+	}
+	for _, i := range []int{2, 1, 0} {
+		frame := recvFrame(t, upstreamResponseA)
+		if respTag(frame[0]) != uint16(i)<<8 || frame[0].Data[4] != uint8(i) {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+	if frame := recvFrame(t, upstreamResponseB); respTag(frame[0]) != 3<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+}
@@ -0,0 +1,157 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX2ByteFair is a variant of ArbitrateX2 which shares the downstream
+// request bandwidth equally between the two upstream ports, regardless of
+// the sizes of the frames they issue. This uses deficit round robin
+// arbitration, with the deficit being counted in flits. Since the size of a
+// request frame is not known until it has been transferred, each port may
+// continue to be granted access for as long as its deficit counter is
+// positive, and the number of flits in each transferred frame is then
+// subtracted from the deficit counter. When no waiting port has a positive
+// deficit, each waiting port has its deficit counter incremented by a
+// quantum of SmiMemFrame64Size flits. Ports which have no waiting requests
+// do not accumulate deficit. Frames are always transferred atomically, with
+// the deficit counters only being used to select the next port.
+//
+func ArbitrateX2ByteFair(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		isPendingA := false
+		isPendingB := false
+		deficitA := int32(0)
+		deficitB := int32(0)
+		currentPort := uint8(1)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// port so that contention can be detected.
+			if !isPendingA && !isPendingB {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				case <-transferReqB:
+					isPendingB = true
+				}
+			}
+			if !isPendingA {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				default:
+				}
+			}
+			if !isPendingB {
+				select {
+				case <-transferReqB:
+					isPendingB = true
+				default:
+				}
+			}
+
+			// Idle ports do not accumulate deficit.
+			if !isPendingA && deficitA > 0 {
+				deficitA = 0
+			}
+			if !isPendingB && deficitB > 0 {
+				deficitB = 0
+			}
+
+			// Select the next port with a positive deficit, starting with the
+			// current port and adding a quantum to the waiting ports after
+			// each unsuccessful round.
+			portId := uint8(0)
+			for portId == 0 {
+				for i := 0; i != 2 && portId == 0; i++ {
+					if currentPort == 1 && isPendingA && deficitA > 0 {
+						portId = 1
+					} else if currentPort == 2 && isPendingB && deficitB > 0 {
+						portId = 2
+					} else {
+						currentPort = 3 - currentPort
+					}
+				}
+				if portId == 0 {
+					if isPendingA {
+						deficitA += 34 /* SmiMemFrame64Size */
+					}
+					if isPendingB {
+						deficitB += 34 /* SmiMemFrame64Size */
+					}
+				}
+			}
+
+			// Copy over input data, counting the flits.
+			flitCount := int32(0)
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+				flitCount++
+			}
+
+			// Update the deficit for the granted port.
+			switch portId {
+			case 1:
+				isPendingA = false
+				deficitA -= flitCount
+			default:
+				isPendingB = false
+				deficitB -= flitCount
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestArbitrateByteFairMismatchedSizes(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64, 64)
+	go ArbitrateX2ByteFair(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse)
+
+	// Port A issues two flit read requests and port B issues 27 flit write
+	// requests, keeping both ports saturated.
+	frames := [][]Flit64{ReadReqFrames64(0, 8, 0), WriteReqFrames64(0, make([]byte, 200), nil, 0)}
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			for {
+				sendFrame64(upstreamRequests[port], frames[port])
+			}
+		}()
+		go func() {
+			for {
+				receiveFrame64(upstreamResponses[port])
+			}
+		}()
+	}
+
+	// Act as the downstream endpoint, counting the request flits issued for
+	// each port. Each flit is only accepted after yielding to the other
+	// goroutines, so that the responses have been returned and both ports
+	// have requests waiting whenever the arbiter selects the next port.
+	var flitCounts [2]int
+	for frameCount := 0; frameCount != 400; frameCount++ {
+		frame := []Flit64{}
+		moreFlits := true
+		for moreFlits {
+			for i := 0; i != 20; i++ {
+				runtime.Gosched()
+			}
+			reqFlit := recvFlit(t, downstreamRequest)
+			frame = append(frame, reqFlit)
+			moreFlits = reqFlit.Eofc == 0
+		}
+		flitCounts[frame[0].Data[2]-1] += len(frame)
+		downstreamResponse <- Flit64{
+			Data: [8]uint8{SmiMemWriteResp, 0, frame[0].Data[2], frame[0].Data[3]}, Eofc: 4}
+	}
+	flitsA := float64(flitCounts[0])
+	flitsB := float64(flitCounts[1])
+	if flitsA < 0.8*flitsB || flitsA > 1.25*flitsB {
+		t.Fatalf("unfair flit counts %v", flitCounts)
+	}
+}
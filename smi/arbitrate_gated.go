@@ -0,0 +1,175 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// manageGatedUpstreamPort is a variant of manageUpstreamPort which adds a port
+// enable signal. While the port is disabled no new request frames are
+// accepted, which applies backpressure to the upstream request channel.
+// Responses are always forwarded, so transactions which were already in
+// flight when the port was disabled will be allowed to complete. The enable
+// state is only sampled between request frames and the port starts in the
+// enabled state.
+//
+func manageGatedUpstreamPort(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	portEnable <-chan bool,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		portEnabled := true
+		for {
+
+			// Only accept new request frames while the port is enabled.
+			var headerFlit Flit64
+			if portEnabled {
+				select {
+				case headerFlit = <-upstreamRequest:
+				case portEnabled = <-portEnable:
+					continue
+				}
+			} else {
+				portEnabled = <-portEnable
+				continue
+			}
+
+			// Do tag replacement on header.
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Carry out tag replacement on responses. This is independent of the
+	// port enable state so that outstanding transactions always complete.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-taggedResponse
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		tagFifo <- tagId
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
+
+//
+// ArbitrateX2Gated is a variant of ArbitrateX2 which provides a per-port
+// enable signal. Sending false on a port enable channel stops the port from
+// accepting new request frames at the next frame boundary, and sending true
+// resumes normal operation. Transactions which are already outstanding on a
+// disabled port are always allowed to complete, with their responses being
+// forwarded upstream as normal.
+//
+func ArbitrateX2Gated(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamEnableA <-chan bool,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamEnableB <-chan bool,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageGatedUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, upstreamEnableA, uint8(1))
+	go manageGatedUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, upstreamEnableB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,140 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// ResponseLatency extracts the service latency code from a response header
+// flit generated by ArbitrateX2LatencyStamped. The latency code is held in
+// the upper four bits of the status byte (byte 1) and is a saturating base 2
+// logarithmic encoding of the measured latency in handshakes. A code of zero
+// indicates zero latency, a code of N in the range 1 to 14 indicates a
+// latency of at least 2^(N-1) and less than 2^N handshakes and a code of 15
+// indicates a latency of 2^14 handshakes or more. The latency code uses the
+// same bits as the header check value, so latency stamping can not be
+// combined with header checking.
+//
+func ResponseLatency(headerFlit Flit64) uint8 {
+	return headerFlit.Data[1] >> 4
+}
+
+//
+// ArbitrateX2LatencyStamped is a variant of ArbitrateX2 which stamps each
+// response header with an estimate of the service latency experienced by the
+// transaction, so that masters can implement congestion aware throttling.
+// Latency is measured using a handshake counter which counts all flits
+// transferred on the downstream request and response channels. The issue time
+// is sampled when the request header flit is sent downstream and the latency
+// is calculated when the response header flit is received. The latency code
+// is written into the upper four bits of the response status byte before the
+// response is forwarded upstream, as described for ResponseLatency.
+//
+func ArbitrateX2LatencyStamped(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Set up the shared handshake counter and issue time tables.
+	// TODO: The array sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var clockLock sync.Mutex
+	handshakeCount := uint32(0)
+	var issueTimesA [4]uint32
+	var issueTimesB [4]uint32
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data, recording the issue time on the header.
+			var reqFlit Flit64
+			isHeaderFlit := true
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				clockLock.Lock()
+				if isHeaderFlit {
+					if portId == 1 {
+						issueTimesA[reqFlit.Data[3]&0x03] = handshakeCount
+					} else {
+						issueTimesB[reqFlit.Data[3]&0x03] = handshakeCount
+					}
+				}
+				handshakeCount++
+				clockLock.Unlock()
+				downstreamRequest <- reqFlit
+				isHeaderFlit = false
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses, stamping the latency on header flits.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		clockLock.Lock()
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+			issueTime := issueTimesA[respFlit.Data[3]&0x03]
+			if portId == 2 {
+				issueTime = issueTimesB[respFlit.Data[3]&0x03]
+			}
+			latency := handshakeCount - issueTime
+			latencyCode := uint8(0)
+			for latency != 0 && latencyCode != 15 {
+				latency >>= 1
+				latencyCode++
+			}
+			respFlit.Data[1] = (respFlit.Data[1] & 0x0F) | (latencyCode << 4)
+		}
+		handshakeCount++
+		clockLock.Unlock()
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,160 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX4Priority is a variant of ArbitrateX4 which uses fixed priority
+// arbitration. Upstream port A has the highest priority and port D has the
+// lowest priority, so that when more than one port has a pending transfer
+// request the highest priority waiting port is always granted access. This
+// is intended for use where a latency sensitive port shares the downstream
+// channel with bulk data ports. Frames are never preempted, so a high
+// priority request may still have to wait for the completion of a lower
+// priority frame which is already being transferred. Lower priority ports
+// may be starved indefinitely by sustained traffic on higher priority ports.
+//
+func ArbitrateX4Priority(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	upstreamRequestD <-chan Flit64,
+	upstreamResponseD chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	taggedRequestD := make(chan Flit64, 1)
+	taggedResponseD := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+	transferReqD := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+	go manageUpstreamPort(upstreamRequestC, upstreamResponseC,
+		taggedRequestC, taggedResponseC, transferReqC, uint8(3))
+	go manageUpstreamPort(upstreamRequestD, upstreamResponseD,
+		taggedRequestD, taggedResponseD, transferReqD, uint8(4))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		pendingMask := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// ports so that contention can be detected.
+			if pendingMask == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				case <-transferReqB:
+					pendingMask |= 0x02
+				case <-transferReqC:
+					pendingMask |= 0x04
+				case <-transferReqD:
+					pendingMask |= 0x08
+				}
+			}
+			if pendingMask&0x01 == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				default:
+				}
+			}
+			if pendingMask&0x02 == 0 {
+				select {
+				case <-transferReqB:
+					pendingMask |= 0x02
+				default:
+				}
+			}
+			if pendingMask&0x04 == 0 {
+				select {
+				case <-transferReqC:
+					pendingMask |= 0x04
+				default:
+				}
+			}
+			if pendingMask&0x08 == 0 {
+				select {
+				case <-transferReqD:
+					pendingMask |= 0x08
+				default:
+				}
+			}
+
+			// Select the highest priority waiting port.
+			var portId uint8
+			switch {
+			case pendingMask&0x01 != 0:
+				portId = 1
+			case pendingMask&0x02 != 0:
+				portId = 2
+			case pendingMask&0x04 != 0:
+				portId = 3
+			default:
+				portId = 4
+			}
+			pendingMask &^= uint8(1) << (portId - 1)
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				case 2:
+					reqFlit = <-taggedRequestB
+				case 3:
+					reqFlit = <-taggedRequestC
+				default:
+					reqFlit = <-taggedRequestD
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		case 4:
+			taggedResponseD <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,68 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbitrateX4PriorityOrder(t *testing.T) {
+	upstreamRequests := make([]chan Flit64, 4)
+	upstreamResponses := make([]chan Flit64, 4)
+	for i := range upstreamRequests {
+		upstreamRequests[i] = make(chan Flit64)
+		upstreamResponses[i] = make(chan Flit64, 8)
+	}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX4Priority(
+		upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		upstreamRequests[2], upstreamResponses[2],
+		upstreamRequests[3], upstreamResponses[3],
+		downstreamRequest, downstreamResponse)
+
+	// In each round the first port issues a request which is granted
+	// immediately and stalls on the downstream channel. The remaining ports
+	// then issue requests in the specified order while the first frame is
+	// in progress, and must be serviced in priority order once it completes.
+	rounds := [][]int{
+		{1, 2, 3, 0},
+		{3, 0, 2, 1},
+		{2, 3, 1, 0},
+		{0, 3, 2, 1},
+		{1, 3, 0, 2},
+	}
+	for round, portOrder := range rounds {
+		for i, port := range portOrder {
+			go sendFrame64(upstreamRequests[port], ReadReqFrames64(0, 8, uint8(round)))
+			if i == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		expectOrder := []int{portOrder[0]}
+		for port := 0; port != 4; port++ {
+			if port != portOrder[0] {
+				expectOrder = append(expectOrder, port)
+			}
+		}
+		for _, port := range expectOrder {
+			reqHeader := recvFrame(t, downstreamRequest)[0]
+			if int(reqHeader.Data[2]) != port+1 {
+				t.Fatalf("round %d: port %d granted, expected %d",
+					round, reqHeader.Data[2], port+1)
+			}
+			sendFrame(t, downstreamResponse, []Flit64{{
+				Data: [8]uint8{SmiMemErrorResp, 0, reqHeader.Data[2], reqHeader.Data[3],
+					SmiMemErrDecode}, Eofc: 5}})
+			recvFrame(t, upstreamResponses[port])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX2Queryable is a variant of ArbitrateX2 which allows the current
+// grant owner to be queried for debugging. Each value received on the grant
+// query channel causes the port ID of the upstream port which currently holds
+// the downstream request path to be sent on the grant owner channel. Port A
+// has ID 1, port B has ID 2 and a value of 0 indicates that no port is
+// currently transferring a frame. Queries are serviced by the arbitration
+// logic itself between flit transfers, so the reported owner is always
+// consistent with the grant state. A port holds the grant from the transfer
+// of its header flit until the transfer of its final flit. Queries do not
+// otherwise affect arbitration, but the grant owner channel must be serviced
+// after each query to avoid stalling the request path.
+//
+func ArbitrateX2Queryable(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	grantQuery <-chan bool,
+	grantOwner chan<- uint8) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input, reporting an idle grant state
+			// while waiting.
+			portId := uint8(0)
+			for portId == 0 {
+				select {
+				case portId = <-transferReqA:
+				case portId = <-transferReqB:
+				case <-grantQuery:
+					grantOwner <- portId
+				}
+			}
+
+			// Copy over input data, reporting the active port ID while the
+			// transfer is in progress.
+			var taggedRequest <-chan Flit64
+			switch portId {
+			case 1:
+				taggedRequest = taggedRequestA
+			default:
+				taggedRequest = taggedRequestB
+			}
+			moreFlits := true
+			for moreFlits {
+				select {
+				case reqFlit := <-taggedRequest:
+					downstreamRequest <- reqFlit
+					moreFlits = reqFlit.Eofc == 0
+				case <-grantQuery:
+					grantOwner <- portId
+				}
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
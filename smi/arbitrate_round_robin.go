@@ -0,0 +1,388 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// roundRobinGrant selects the next port to be granted access by a round robin
+// arbiter. The pending mask holds a bit for each port with a waiting transfer
+// request, where bit 0 corresponds to port 1. The search starts from the port
+// following the last granted port, so that the last granted port is only
+// selected again if no other port is waiting. Returns zero if no port is
+// waiting.
+//
+func roundRobinGrant(pendingMask uint8, lastGranted uint8, portCount uint8) uint8 {
+	portId := lastGranted
+	for i := uint8(0); i != portCount; i++ {
+		portId = portId%portCount + 1
+		if pendingMask&(uint8(1)<<(portId-1)) != 0 {
+			return portId
+		}
+	}
+	return 0
+}
+
+//
+// ArbitrateX2RoundRobin is a variant of ArbitrateX2 which uses round robin
+// arbitration to prevent upstream port starvation. The arbiter records the
+// last granted port and, when more than one port has a pending transfer
+// request, grants access to the next waiting port after it. Under sustained
+// traffic each port is therefore granted access in turn, so the number of
+// frames issued by any two saturated ports never differs by more than one.
+// When only one port has traffic it is granted access on every arbitration
+// cycle without waiting for the other ports.
+//
+func ArbitrateX2RoundRobin(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		pendingMask := uint8(0)
+		lastGranted := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// ports so that contention can be detected.
+			if pendingMask == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				case <-transferReqB:
+					pendingMask |= 0x02
+				}
+			}
+			if pendingMask&0x01 == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				default:
+				}
+			}
+			if pendingMask&0x02 == 0 {
+				select {
+				case <-transferReqB:
+					pendingMask |= 0x02
+				default:
+				}
+			}
+
+			// Select the next waiting port after the last granted port.
+			portId := roundRobinGrant(pendingMask, lastGranted, 2)
+			pendingMask &^= uint8(1) << (portId - 1)
+			lastGranted = portId
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
+
+//
+// ArbitrateX3RoundRobin is a variant of ArbitrateX3 which uses round robin
+// arbitration to prevent upstream port starvation, as described for
+// ArbitrateX2RoundRobin.
+//
+func ArbitrateX3RoundRobin(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+	go manageUpstreamPort(upstreamRequestC, upstreamResponseC,
+		taggedRequestC, taggedResponseC, transferReqC, uint8(3))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		pendingMask := uint8(0)
+		lastGranted := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// ports so that contention can be detected.
+			if pendingMask == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				case <-transferReqB:
+					pendingMask |= 0x02
+				case <-transferReqC:
+					pendingMask |= 0x04
+				}
+			}
+			if pendingMask&0x01 == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				default:
+				}
+			}
+			if pendingMask&0x02 == 0 {
+				select {
+				case <-transferReqB:
+					pendingMask |= 0x02
+				default:
+				}
+			}
+			if pendingMask&0x04 == 0 {
+				select {
+				case <-transferReqC:
+					pendingMask |= 0x04
+				default:
+				}
+			}
+
+			// Select the next waiting port after the last granted port.
+			portId := roundRobinGrant(pendingMask, lastGranted, 3)
+			pendingMask &^= uint8(1) << (portId - 1)
+			lastGranted = portId
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				case 2:
+					reqFlit = <-taggedRequestB
+				default:
+					reqFlit = <-taggedRequestC
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
+
+//
+// ArbitrateX4RoundRobin is a variant of ArbitrateX4 which uses round robin
+// arbitration to prevent upstream port starvation, as described for
+// ArbitrateX2RoundRobin.
+//
+func ArbitrateX4RoundRobin(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	upstreamRequestD <-chan Flit64,
+	upstreamResponseD chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	taggedRequestD := make(chan Flit64, 1)
+	taggedResponseD := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+	transferReqD := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+	go manageUpstreamPort(upstreamRequestC, upstreamResponseC,
+		taggedRequestC, taggedResponseC, transferReqC, uint8(3))
+	go manageUpstreamPort(upstreamRequestD, upstreamResponseD,
+		taggedRequestD, taggedResponseD, transferReqD, uint8(4))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		pendingMask := uint8(0)
+		lastGranted := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, then sample the other
+			// ports so that contention can be detected.
+			if pendingMask == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				case <-transferReqB:
+					pendingMask |= 0x02
+				case <-transferReqC:
+					pendingMask |= 0x04
+				case <-transferReqD:
+					pendingMask |= 0x08
+				}
+			}
+			if pendingMask&0x01 == 0 {
+				select {
+				case <-transferReqA:
+					pendingMask |= 0x01
+				default:
+				}
+			}
+			if pendingMask&0x02 == 0 {
+				select {
+				case <-transferReqB:
+					pendingMask |= 0x02
+				default:
+				}
+			}
+			if pendingMask&0x04 == 0 {
+				select {
+				case <-transferReqC:
+					pendingMask |= 0x04
+				default:
+				}
+			}
+			if pendingMask&0x08 == 0 {
+				select {
+				case <-transferReqD:
+					pendingMask |= 0x08
+				default:
+				}
+			}
+
+			// Select the next waiting port after the last granted port.
+			portId := roundRobinGrant(pendingMask, lastGranted, 4)
+			pendingMask &^= uint8(1) << (portId - 1)
+			lastGranted = portId
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				case 2:
+					reqFlit = <-taggedRequestB
+				case 3:
+					reqFlit = <-taggedRequestC
+				default:
+					reqFlit = <-taggedRequestD
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		case 4:
+			taggedResponseD <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,107 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRoundRobinGrant(t *testing.T) {
+	cases := []struct {
+		pendingMask uint8
+		lastGranted uint8
+		portCount   uint8
+		expect      uint8
+	}{
+		{0x00, 1, 2, 0},
+		{0x03, 1, 2, 2},
+		{0x03, 2, 2, 1},
+		{0x01, 1, 2, 1},
+		{0x0D, 1, 4, 3},
+		{0x0D, 4, 4, 1},
+		{0x05, 3, 3, 1},
+		{0x03, 0, 3, 1},
+	}
+	for _, c := range cases {
+		if portId := roundRobinGrant(c.pendingMask, c.lastGranted, c.portCount); portId != c.expect {
+			t.Errorf("mask 0x%02X last %d: granted %d, expected %d",
+				c.pendingMask, c.lastGranted, portId, c.expect)
+		}
+	}
+}
+
+func TestArbitrateX2RoundRobinSaturated(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64, 64)
+	go ArbitrateX2RoundRobin(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse)
+
+	// Both ports issue write requests continuously.
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			for {
+				sendFrame64(upstreamRequests[port], WriteReqFrames64(0, make([]byte, 16), nil, 0))
+			}
+		}()
+		go func() {
+			for {
+				receiveFrame64(upstreamResponses[port])
+			}
+		}()
+	}
+
+	// Act as the downstream endpoint, yielding before accepting each flit
+	// so that both ports have requests waiting at every grant.
+	var grantCounts [2]int
+	for frameCount := 0; frameCount != 200; frameCount++ {
+		frame := []Flit64{}
+		moreFlits := true
+		for moreFlits {
+			for i := 0; i != 20; i++ {
+				runtime.Gosched()
+			}
+			reqFlit := recvFlit(t, downstreamRequest)
+			frame = append(frame, reqFlit)
+			moreFlits = reqFlit.Eofc == 0
+		}
+		grantCounts[frame[0].Data[2]-1]++
+		if grantCounts[0]-grantCounts[1] > 1 || grantCounts[1]-grantCounts[0] > 1 {
+			t.Fatalf("unfair grant counts %v", grantCounts)
+		}
+		downstreamResponse <- Flit64{
+			Data: [8]uint8{SmiMemWriteResp, 0, frame[0].Data[2], frame[0].Data[3]}, Eofc: 4}
+	}
+}
+
+func TestArbitrateX2RoundRobinSinglePort(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 8)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX2RoundRobin(make(chan Flit64), make(chan Flit64),
+		upstreamRequestA, upstreamResponseA,
+		downstreamRequest, downstreamResponse)
+
+	// Port B is idle, so every grant goes to port A without waiting.
+	for i := 0; i != 8; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, uint8(i)))
+		reqHeader := recvFrame(t, downstreamRequest)[0]
+		if reqHeader.Data[2] != 2 {
+			t.Fatalf("unexpected port ID %d", reqHeader.Data[2])
+		}
+		sendFrame(t, downstreamResponse, []Flit64{{
+			Data: [8]uint8{SmiMemErrorResp, 0, 2, reqHeader.Data[3], SmiMemErrDecode}, Eofc: 5}})
+		if respFrame := recvFrame(t, upstreamResponseA); respTag(respFrame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", respFrame)
+		}
+	}
+}
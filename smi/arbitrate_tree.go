@@ -0,0 +1,53 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateTree8 is a goroutine for providing arbitration between eight pairs
+// of SMI request/response channels, using a two level tree of arbiters. The
+// first four upstream ports are arbitrated by one ArbitrateX4 instance and
+// the last four by another, with the two intermediate ports then being
+// arbitrated by ArbitrateX2. Each level of the tree carries out its own tag
+// substitution on bytes 2 and 3 of the frame header, saving the tag bytes
+// from the level above and restoring them on the corresponding response.
+// The port IDs used at each level therefore never clash, and response frames
+// are routed back through both levels to the originating upstream port.
+// The intermediate channels use the same single flit buffering as the
+// internal arbiter connections.
+//
+func ArbitrateTree8(
+	upstreamRequests [8]<-chan Flit64,
+	upstreamResponses [8]chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define intermediate channel connections.
+	branchRequestA := make(chan Flit64, 1)
+	branchResponseA := make(chan Flit64, 1)
+	branchRequestB := make(chan Flit64, 1)
+	branchResponseB := make(chan Flit64, 1)
+
+	// Run the leaf level arbiters.
+	go ArbitrateX4(
+		upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		upstreamRequests[2], upstreamResponses[2],
+		upstreamRequests[3], upstreamResponses[3],
+		branchRequestA, branchResponseA)
+	go ArbitrateX4(
+		upstreamRequests[4], upstreamResponses[4],
+		upstreamRequests[5], upstreamResponses[5],
+		upstreamRequests[6], upstreamResponses[6],
+		upstreamRequests[7], upstreamResponses[7],
+		branchRequestB, branchResponseB)
+
+	// Run the root level arbiter.
+	ArbitrateX2(
+		branchRequestA, branchResponseA,
+		branchRequestB, branchResponseB,
+		downstreamRequest, downstreamResponse)
+}
@@ -0,0 +1,52 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbitrateTree8MemoryModel(t *testing.T) {
+	var upstreamRequests [8]<-chan Flit64
+	var upstreamResponses [8]chan<- Flit64
+	leafRequests := make([]chan Flit64, 8)
+	leafResponses := make([]chan Flit64, 8)
+	for i := range leafRequests {
+		leafRequests[i] = make(chan Flit64)
+		leafResponses[i] = make(chan Flit64)
+		upstreamRequests[i] = leafRequests[i]
+		upstreamResponses[i] = leafResponses[i]
+	}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := make([]byte, 4096)
+	go ArbitrateTree8(upstreamRequests, upstreamResponses,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// All eight leaves run concurrently on distinct regions, so any
+	// misrouted response results in a tag or read data mismatch.
+	results := make(chan error, 8)
+	for leaf := range leafRequests {
+		leaf := leaf
+		go func() {
+			results <- memoryModelMaster(leafRequests[leaf], leafResponses[leaf],
+				uint64(leaf*512), uint8(leaf*30+1))
+		}()
+	}
+	for i := 0; i != 8; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for leaves")
+		}
+	}
+}
@@ -0,0 +1,176 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ArbitrateX2Weighted is a variant of ArbitrateX2 which uses weighted round
+// robin arbitration. When both upstream ports have waiting requests, each
+// port may issue up to its weight in consecutive frames before the other port
+// is granted access. A port with no competition is always granted access.
+// Under contention, a port with a weight of zero is never granted access
+// unless both weights are zero, in which case they are treated as one. The
+// initial weights are specified as parameters and may be changed at runtime
+// by sending a new pair of weights on the weight update channel, with index
+// 0 holding the weight for port A and index 1 holding the weight for port B.
+// A nil weight update channel may be used if the weights are fixed. Weight
+// updates are only applied by the arbitration logic between frames, so a
+// frame transfer which is in progress always completes using the existing
+// grant. The new weights take effect from the next arbitration decision,
+// which occurs once any frame transfer in progress has completed. The
+// maximum latency is therefore the time taken to transfer a single
+// SmiMemFrame64Size frame. Applying an update also restarts the count of
+// consecutive frames for the current port.
+//
+func ArbitrateX2Weighted(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	weightA uint8,
+	weightB uint8,
+	weightUpdate <-chan [2]uint8) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		isPendingA := false
+		isPendingB := false
+		currentPort := uint8(1)
+		grantCount := uint8(0)
+		for {
+
+			// Wait for at least one transfer request, applying any weight
+			// updates while idle.
+			for !isPendingA && !isPendingB {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				case <-transferReqB:
+					isPendingB = true
+				case newWeights := <-weightUpdate:
+					weightA = newWeights[0]
+					weightB = newWeights[1]
+					grantCount = 0
+				}
+			}
+
+			// Sample the other port so that contention can be detected and
+			// apply any pending weight update.
+			if !isPendingA {
+				select {
+				case <-transferReqA:
+					isPendingA = true
+				default:
+				}
+			}
+			if !isPendingB {
+				select {
+				case <-transferReqB:
+					isPendingB = true
+				default:
+				}
+			}
+			select {
+			case newWeights := <-weightUpdate:
+				weightA = newWeights[0]
+				weightB = newWeights[1]
+				grantCount = 0
+			default:
+			}
+
+			// Select the port to be granted. Under contention, a port with
+			// a weight of zero is skipped in favour of the other port.
+			effectiveWeightA := weightA
+			effectiveWeightB := weightB
+			if effectiveWeightA == 0 && effectiveWeightB == 0 {
+				effectiveWeightA = 1
+				effectiveWeightB = 1
+			}
+			currentWeight := effectiveWeightA
+			if currentPort == 2 {
+				currentWeight = effectiveWeightB
+			}
+			if !isPendingA || !isPendingB {
+				if isPendingA {
+					if currentPort != 1 {
+						grantCount = 0
+					}
+					currentPort = 1
+				} else {
+					if currentPort != 2 {
+						grantCount = 0
+					}
+					currentPort = 2
+				}
+			} else if grantCount >= currentWeight {
+				currentPort = 3 - currentPort
+				grantCount = 0
+				if (currentPort == 1 && effectiveWeightA == 0) ||
+					(currentPort == 2 && effectiveWeightB == 0) {
+					currentPort = 3 - currentPort
+				}
+			}
+			portId := currentPort
+			if grantCount != 255 {
+				grantCount++
+			}
+			if portId == 1 {
+				isPendingA = false
+			} else {
+				isPendingB = false
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,113 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"runtime"
+	"testing"
+)
+
+//
+// weightedDownstream acts as the downstream endpoint for the weighted
+// arbitration tests, yielding before accepting each flit so that all active
+// ports have requests waiting at every grant. It returns the port ID for
+// each of the specified number of frames in the order they were granted.
+//
+func weightedDownstream(
+	t *testing.T,
+	downstreamRequest <-chan Flit64,
+	downstreamResponse chan<- Flit64,
+	frameCount int) []uint8 {
+
+	grants := make([]uint8, frameCount)
+	for i := range grants {
+		frame := []Flit64{}
+		moreFlits := true
+		for moreFlits {
+			for j := 0; j != 20; j++ {
+				runtime.Gosched()
+			}
+			reqFlit := recvFlit(t, downstreamRequest)
+			frame = append(frame, reqFlit)
+			moreFlits = reqFlit.Eofc == 0
+		}
+		grants[i] = frame[0].Data[2]
+		downstreamResponse <- Flit64{
+			Data: [8]uint8{SmiMemWriteResp, 0, frame[0].Data[2], frame[0].Data[3]}, Eofc: 4}
+	}
+	return grants
+}
+
+func TestArbitrateX2WeightedRatio(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64, 64)
+	go ArbitrateX2Weighted(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse, 2, 1, nil)
+
+	// Both ports issue write requests continuously.
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			for {
+				sendFrame64(upstreamRequests[port], WriteReqFrames64(0, make([]byte, 16), nil, 0))
+			}
+		}()
+		go func() {
+			for {
+				receiveFrame64(upstreamResponses[port])
+			}
+		}()
+	}
+
+	// Allow for a short settling period before both ports are saturated,
+	// then check the long run frame ratio.
+	grants := weightedDownstream(t, downstreamRequest, downstreamResponse, 310)
+	var grantCounts [2]int
+	for _, portId := range grants[10:] {
+		grantCounts[portId-1]++
+	}
+	if grantCounts[0] < 190 || grantCounts[0] > 210 {
+		t.Fatalf("grant counts %v do not converge to 2:1", grantCounts)
+	}
+}
+
+func TestArbitrateX2WeightedZeroWeight(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64, 64)
+	go ArbitrateX2Weighted(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse, 1, 0, nil)
+
+	// Port A issues a fixed number of requests. Once it is active, port B
+	// issues a single request which is left waiting.
+	go func() {
+		for i := 0; i != 30; i++ {
+			sendFrame64(upstreamRequests[0], WriteReqFrames64(0, make([]byte, 16), nil, 0))
+		}
+	}()
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			for {
+				receiveFrame64(upstreamResponses[port])
+			}
+		}()
+	}
+
+	// Port B is only granted access once port A has gone idle.
+	weightedDownstream(t, downstreamRequest, downstreamResponse, 1)
+	go sendFrame64(upstreamRequests[1], WriteReqFrames64(0, make([]byte, 16), nil, 0))
+	grants := weightedDownstream(t, downstreamRequest, downstreamResponse, 30)
+	if grants[29] != 2 {
+		t.Fatalf("zero weight port granted under contention: %v", grants)
+	}
+}
@@ -0,0 +1,68 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestArbitrateX8Routing(t *testing.T) {
+	upstreamRequests := make([]chan Flit64, 8)
+	upstreamResponses := make([]chan Flit64, 8)
+	for i := range upstreamRequests {
+		upstreamRequests[i] = make(chan Flit64)
+		upstreamResponses[i] = make(chan Flit64, 8)
+	}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX8(
+		upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		upstreamRequests[2], upstreamResponses[2],
+		upstreamRequests[3], upstreamResponses[3],
+		upstreamRequests[4], upstreamResponses[4],
+		upstreamRequests[5], upstreamResponses[5],
+		upstreamRequests[6], upstreamResponses[6],
+		upstreamRequests[7], upstreamResponses[7],
+		downstreamRequest, downstreamResponse)
+
+	// Issue a read request on each port, using the port index as the tag.
+	for i := range upstreamRequests {
+		go sendFrame64(upstreamRequests[i], ReadReqFrames64(uint64(i)<<8, 8, uint8(0x10+i)))
+	}
+	reqHeaders := make([]Flit64, 8)
+	seenPorts := make(map[uint8]bool)
+	for i := range reqHeaders {
+		reqFrame := recvFrame(t, downstreamRequest)
+		reqHeaders[i] = reqFrame[0]
+		portId := reqFrame[0].Data[2]
+		if portId < 1 || portId > 8 || seenPorts[portId] {
+			t.Fatalf("unexpected port ID %d", portId)
+		}
+		seenPorts[portId] = true
+
+		// The address identifies the issuing port.
+		if reqFrame[0].Data[5] != portId-1 {
+			t.Fatalf("port ID %d used for request from port %d",
+				portId, reqFrame[0].Data[5]+1)
+		}
+	}
+
+	// Respond in reverse order, with the read data identifying the port.
+	for i := len(reqHeaders) - 1; i >= 0; i-- {
+		portId := reqHeaders[i].Data[2]
+		respBytes := []byte{SmiMemReadResp, 0, portId, reqHeaders[i].Data[3],
+			portId, 0, 0, 0, 0, 0, 0, 0}
+		sendFrame(t, downstreamResponse, packFrame64(respBytes))
+	}
+	for i := range upstreamResponses {
+		respFrame := recvFrame(t, upstreamResponses[i])
+		if respTag(respFrame[0]) != uint16(0x10+i)<<8 || respFrame[0].Data[4] != uint8(i+1) {
+			t.Fatalf("unexpected response on port %d: %v", i+1, respFrame)
+		}
+	}
+}
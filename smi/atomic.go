@@ -0,0 +1,78 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// Atomic compare and swap requests use the standard request header, with the
+// length field giving the operand size of 8 bytes. The header is followed by
+// the expected value and then the desired value, each of which is a 64-bit
+// little endian operand. If the current contents of the addressed memory
+// match the expected value they are replaced by the desired value. Either
+// way, the response carries the standard response header followed by a
+// single byte which is set to SmiMemCasSwapped if the swap took place, and
+// then the prior contents of the addressed memory as a 64-bit little endian
+// value. Failed accesses are answered with an error response frame.
+//
+const (
+	SmiMemCasOperandSize = 8
+	SmiMemCasSwapped     = uint8(0x01)
+)
+
+//
+// CasReqFrame64 builds the atomic compare and swap request frame for the
+// specified address and operands. The tag value is placed in byte 3 of the
+// frame header, with byte 2 being set to zero and the default options being
+// used. This is intended for use in simulation and host side test code.
+//
+func CasReqFrame64(casAddr uint64, expected uint64, desired uint64, tag uint8) []Flit64 {
+	frameBytes := reqHeaderBytes(SmiMemAtomicCasReq, DefaultOptions, 0, tag,
+		casAddr, SmiMemCasOperandSize)
+	for i := uint(0); i != 8; i++ {
+		frameBytes = append(frameBytes, uint8(expected>>(8*i)))
+	}
+	for i := uint(0); i != 8; i++ {
+		frameBytes = append(frameBytes, uint8(desired>>(8*i)))
+	}
+	return packFrame64(frameBytes)
+}
+
+//
+// ParseCasResp64 reads a single complete atomic compare and swap response
+// frame from the input channel, returning the prior memory contents, whether
+// the swap took place and the tag value from byte 3 of the frame header. The
+// entire frame is always consumed. An error is returned if the frame is an
+// error response or is not a valid compare and swap response. This is
+// intended for use in simulation and host side test code.
+//
+func ParseCasResp64(smiInput <-chan Flit64) (uint64, bool, uint8, error) {
+	frameBytes := unpackFrame64(receiveFrame64(smiInput))
+	if len(frameBytes) < smiMemRespHeaderSize {
+		return 0, false, 0, fmt.Errorf("frame ends after %d header bytes", len(frameBytes))
+	}
+	tag := frameBytes[3]
+	if frameBytes[0] == SmiMemErrorResp && len(frameBytes) > smiMemRespHeaderSize {
+		return 0, false, tag, fmt.Errorf("error response code 0x%02X",
+			frameBytes[smiMemRespHeaderSize])
+	}
+	if frameBytes[0] != SmiMemAtomicCasResp {
+		return 0, false, tag, fmt.Errorf("unexpected frame type 0x%02X", frameBytes[0])
+	}
+	if len(frameBytes) != smiMemRespHeaderSize+1+SmiMemCasOperandSize {
+		return 0, false, tag, fmt.Errorf("compare and swap response size %d is not valid",
+			len(frameBytes))
+	}
+	priorValue := uint64(0)
+	for i := uint(0); i != 8; i++ {
+		priorValue |= uint64(frameBytes[smiMemRespHeaderSize+1+i]) << (8 * i)
+	}
+	isSwapped := frameBytes[smiMemRespHeaderSize] == SmiMemCasSwapped
+	return priorValue, isSwapped, tag, nil
+}
@@ -0,0 +1,54 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestMemoryModelCas(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 64)
+	binary.LittleEndian.PutUint64(backing[16:], 100)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// A matching expected value swaps in the desired value.
+	go sendFrame64(smiRequest, CasReqFrame64(16, 100, 200, 1))
+	priorValue, isSwapped, tag, err := ParseCasResp64(smiResponse)
+	if err != nil || !isSwapped || priorValue != 100 || tag != 1 {
+		t.Fatalf("unexpected result %d %v %d %v", priorValue, isSwapped, tag, err)
+	}
+	if binary.LittleEndian.Uint64(backing[16:]) != 200 {
+		t.Fatal("desired value not written")
+	}
+
+	// A stale expected value leaves memory unchanged.
+	go sendFrame64(smiRequest, CasReqFrame64(16, 100, 300, 2))
+	priorValue, isSwapped, tag, err = ParseCasResp64(smiResponse)
+	if err != nil || isSwapped || priorValue != 200 || tag != 2 {
+		t.Fatalf("unexpected result %d %v %d %v", priorValue, isSwapped, tag, err)
+	}
+	if binary.LittleEndian.Uint64(backing[16:]) != 200 {
+		t.Fatal("memory modified by failed swap")
+	}
+}
+
+func TestLoopbackCas(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	go Loopback64(smiRequest, smiResponse)
+
+	go sendFrame64(smiRequest, CasReqFrame64(16, 100, 200, 3))
+	frame := recvFrame(t, smiResponse)
+	frameBytes := unpackFrame64(frame)
+	if frameBytes[0] != SmiMemErrorResp || respTag(frame[0]) != 3<<8 ||
+		frameBytes[smiMemRespHeaderSize] != SmiMemErrDecode {
+		t.Fatalf("unexpected response %v", frameBytes)
+	}
+}
@@ -0,0 +1,73 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// BankGatherX2 is a goroutine which reassembles the responses to a logical
+// read request which has been split across two interleaved memory banks.
+// The interleaving scheme assigns consecutive blocks of 2^interleaveShift
+// bytes to alternate banks, with bank A holding the even numbered blocks and
+// bank B holding the odd numbered blocks. The logical read request frames are
+// supplied in their original issue order on the read request channel, and are
+// used to determine the sequence of fragments which was issued to the banks,
+// with each fragment covering the part of the read that lies within a single
+// interleave block. The fragment responses are collected from the
+// corresponding bank response channels and their payload data is
+// concatenated to form a single read response frame, which carries the tag
+// of the logical read request and the combined status flags of all the
+// fragment responses. This requires each bank to return its fragment
+// responses in issue order.
+//
+func BankGatherX2(
+	readRequest <-chan Flit64,
+	bankResponseA <-chan Flit64,
+	bankResponseB <-chan Flit64,
+	smiResponse chan<- Flit64,
+	interleaveShift uint8) {
+
+	blockSize := uint64(1) << interleaveShift
+	for {
+		reqBytes := unpackFrame64(receiveFrame64(readRequest))
+		if len(reqBytes) < smiMemReqHeaderSize {
+			// Discard invalid frame.
+			continue
+		}
+		readAddr := frameBytesAddr(reqBytes)
+		readEnd := readAddr + uint64(frameBytesLength(reqBytes))
+		respBytes := []byte{SmiMemReadResp, 0, reqBytes[2], reqBytes[3]}
+
+		// Collect the fragment responses in order.
+		for readAddr != readEnd {
+			blockIndex := readAddr >> interleaveShift
+			fragmentEnd := (blockIndex + 1) * blockSize
+			if fragmentEnd > readEnd {
+				fragmentEnd = readEnd
+			}
+			var fragmentFrame []Flit64
+			if blockIndex&1 == 0 {
+				fragmentFrame = receiveFrame64(bankResponseA)
+			} else {
+				fragmentFrame = receiveFrame64(bankResponseB)
+			}
+			fragmentBytes := unpackFrame64(fragmentFrame)
+			if len(fragmentBytes) >= smiMemRespHeaderSize {
+				respBytes[1] |= fragmentBytes[1]
+				fragmentBytes = fragmentBytes[smiMemRespHeaderSize:]
+			} else {
+				respBytes[1] |= 0x02
+				fragmentBytes = nil
+			}
+
+			// Pad or truncate the fragment data to the expected length.
+			fragmentData := make([]byte, fragmentEnd-readAddr)
+			copy(fragmentData, fragmentBytes)
+			respBytes = append(respBytes, fragmentData...)
+			readAddr = fragmentEnd
+		}
+		sendFrame64(smiResponse, packFrame64(respBytes))
+	}
+}
@@ -0,0 +1,62 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// BitErrorInjector64 is a goroutine which forwards flits from its input to its
+// output, injecting single bit errors into the flit data at a configurable
+// rate in order to model a noisy link. The error probability is specified per
+// flit as a fraction of 2^32, so an error rate of 0x00010000 corrupts one flit
+// in 65536 on average and an error rate of zero disables error injection.
+// Each corrupted flit has a single randomly selected data bit inverted. The
+// end of frame markers are never corrupted, so the frame structure is
+// preserved. Random values are generated using a 32-bit xorshift generator
+// initialised from the specified seed, so a given seed always reproduces the
+// same error sequence. A zero seed is replaced by a fixed non-zero value.
+// The cumulative count of injected errors is sent on the error count channel
+// each time an error is injected. This is a non-blocking send, so
+// intermediate counts will be discarded if the error count channel is not
+// being serviced.
+//
+func BitErrorInjector64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	errorRate uint32,
+	randomSeed uint32,
+	errorCounts chan<- uint64) {
+
+	randomState := randomSeed
+	if randomState == 0 {
+		randomState = 0x2545F491
+	}
+	errorCount := uint64(0)
+
+	for {
+		frameFlit := <-smiInput
+
+		// Advance the random number generator.
+		randomState ^= randomState << 13
+		randomState ^= randomState >> 17
+		randomState ^= randomState << 5
+
+		// Corrupt the flit data, using the upper bits of the next random
+		// value to select the bit position.
+		if randomState < errorRate {
+			randomState ^= randomState << 13
+			randomState ^= randomState >> 17
+			randomState ^= randomState << 5
+			bitIndex := uint8(randomState >> 26)
+			frameFlit.Data[bitIndex>>3] ^= uint8(1) << (bitIndex & 7)
+			errorCount++
+			select {
+			case errorCounts <- errorCount:
+			default:
+			}
+		}
+		smiOutput <- frameFlit
+	}
+}
@@ -0,0 +1,276 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// burstTransaction holds the state of an upstream transaction which has been
+// issued downstream as one or more burst fragments.
+//
+type burstTransaction struct {
+	respType      uint8
+	respStatus    uint8
+	tagLower      uint8
+	tagUpper      uint8
+	isSplit       bool
+	fragmentCount int
+	fragmentSize  int
+	respData      []byte
+}
+
+//
+// LimitBurst64 is a goroutine which enforces a maximum burst size on the
+// requests issued by an upstream SMI master. It is inserted between the
+// master and the downstream port, which will usually be an arbitrated
+// upstream port. Read and write requests with a transfer length that exceeds
+// the maximum burst size are handled in one of two ways, depending on the
+// split bursts flag.
+//
+// When splitting is enabled, oversized requests are split into a sequence of
+// fragments which do not exceed the maximum burst size, with the address of
+// each fragment being incremented accordingly. Strobed write requests are
+// split along with their byte enables. The fragment responses are
+// reassembled into a single response frame for the upstream master, which
+// carries the concatenated read data and the combined status flags.
+//
+// When splitting is disabled, oversized request frames are sent on the burst
+// error channel and are not issued downstream. Flits are dropped if the burst
+// error channel is not ready, so it does not need to be serviced. An error
+// response is returned to the upstream master so that its tag is not leaked.
+// For reads this carries zero valued data of the requested length. Requests
+// which would need more than 256 fragments are always rejected.
+//
+// All request frames are issued downstream with local tag values, where byte
+// 2 holds the fragment index and byte 3 holds a local transaction ID, and the
+// original tag bytes are restored on the upstream response. A maximum burst
+// size of zero disables burst size checking.
+//
+func LimitBurst64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	maxBurst uint16,
+	splitBursts bool,
+	burstErrors chan<- Flit64) {
+
+	var transactionLock sync.Mutex
+	transactions := make(map[uint8]*burstTransaction)
+	localResponses := make(chan []Flit64, 1)
+
+	// Set up the local transaction IDs.
+	freeIds := make(chan uint8, 256)
+	for i := 0; i != 256; i++ {
+		freeIds <- uint8(i)
+	}
+
+	// Start goroutine for request fragmentation.
+	go func() {
+		for {
+			frame := receiveFrame64(upstreamRequest)
+			frameBytes := unpackFrame64(frame)
+			if len(frameBytes) < smiMemReqHeaderSize {
+				frameBytes = append(frameBytes, make([]byte, smiMemReqHeaderSize)...)
+			}
+			isWrite := frameBytes[0] == SmiMemWriteReq
+			reqAddr := frameBytesAddr(frameBytes)
+			reqLength := int(frameBytesLength(frameBytes))
+
+			// Extract the write data and byte enables. Truncated write data
+			// is padded with zeros and malformed strobed writes have all
+			// their byte enables cleared.
+			var reqEnables []bool
+			var reqData []byte
+			if isWrite {
+				reqStrobes, writeData, isValid := writePayload(frameBytes)
+				if !isValid && frameBytes[1]&MemOptByteStrobes != 0 {
+					reqStrobes = make([]byte, (reqLength+7)/8)
+				}
+				reqData = writeData
+				if len(reqData) < reqLength {
+					reqData = append(reqData, make([]byte, reqLength-len(reqData))...)
+				}
+				reqEnables = writeEnables(reqStrobes, reqLength)
+			}
+			isOversized := maxBurst != 0 && reqLength > int(maxBurst) &&
+				(frameBytes[0] == SmiMemReadReq || isWrite)
+			fragmentCount := 1
+			fragmentSize := reqLength
+			if isOversized {
+				fragmentSize = int(maxBurst)
+				fragmentCount = (reqLength + fragmentSize - 1) / fragmentSize
+			}
+
+			// Reject oversized requests if splitting is disabled or the
+			// fragment index would overflow.
+			if isOversized && (!splitBursts || fragmentCount > 256) {
+				for _, frameFlit := range frame {
+					select {
+					case burstErrors <- frameFlit:
+					default:
+					}
+				}
+				respBytes := []byte{
+					SmiMemWriteResp, 0x02, frameBytes[2], frameBytes[3]}
+				if !isWrite {
+					respBytes[0] = SmiMemReadResp
+					respBytes = append(respBytes, make([]byte, reqLength)...)
+				}
+				localResponses <- packFrame64(respBytes)
+				continue
+			}
+
+			// Record the transaction details.
+			transaction := &burstTransaction{
+				respType:      SmiMemReadResp,
+				tagLower:      frameBytes[2],
+				tagUpper:      frameBytes[3],
+				isSplit:       isOversized,
+				fragmentCount: fragmentCount,
+				fragmentSize:  fragmentSize}
+			if isWrite {
+				transaction.respType = SmiMemWriteResp
+			} else if isOversized {
+				transaction.respData = make([]byte, reqLength)
+			}
+			transactionId := <-freeIds
+			transactionLock.Lock()
+			transactions[transactionId] = transaction
+			transactionLock.Unlock()
+
+			// Forward requests which do not need splitting with local tags.
+			if !isOversized {
+				frame[0].Data[2] = 0
+				frame[0].Data[3] = transactionId
+				sendFrame64(downstreamRequest, frame)
+				continue
+			}
+
+			// Issue the request fragments.
+			for i := 0; i != fragmentCount; i++ {
+				fragmentOffset := i * fragmentSize
+				fragmentLength := reqLength - fragmentOffset
+				if fragmentLength > fragmentSize {
+					fragmentLength = fragmentSize
+				}
+				fragmentEnd := fragmentOffset + fragmentLength
+				var fragmentBytes []byte
+				if isWrite {
+					var fragmentEnables []bool
+					if reqEnables != nil {
+						fragmentEnables = reqEnables[fragmentOffset:fragmentEnd]
+					}
+					fragmentBytes = strobedWriteReqBytes(reqAddr+uint64(fragmentOffset),
+						frameBytes[1], uint8(i), transactionId,
+						fragmentEnables, reqData[fragmentOffset:fragmentEnd])
+				} else {
+					fragmentBytes = readReqBytes(reqAddr+uint64(fragmentOffset),
+						frameBytes[1], uint8(i), transactionId, uint16(fragmentLength))
+				}
+				sendFrame64(downstreamRequest, packFrame64(fragmentBytes))
+			}
+		}
+	}()
+
+	// Reassemble responses and restore the original tags.
+	for {
+		select {
+		case respFrame := <-localResponses:
+			sendFrame64(upstreamResponse, respFrame)
+
+		case headerFlit := <-downstreamResponse:
+			respFrame := []Flit64{headerFlit}
+			if headerFlit.Eofc == 0 {
+				respFrame = append(respFrame, receiveFrame64(downstreamResponse)...)
+			}
+			transactionId := headerFlit.Data[3]
+			transactionLock.Lock()
+			transaction, isValid := transactions[transactionId]
+			transactionLock.Unlock()
+			if !isValid {
+				// Discard invalid frame.
+				continue
+			}
+
+			// Forward unfragmented responses directly.
+			if !transaction.isSplit {
+				respFrame[0].Data[2] = transaction.tagLower
+				respFrame[0].Data[3] = transaction.tagUpper
+				transactionLock.Lock()
+				delete(transactions, transactionId)
+				transactionLock.Unlock()
+				freeIds <- transactionId
+				sendFrame64(upstreamResponse, respFrame)
+				continue
+			}
+
+			// Accumulate fragment responses.
+			respBytes := unpackFrame64(respFrame)
+			transaction.respStatus |= respBytes[1]
+			if transaction.respData != nil {
+				fragmentOffset := int(headerFlit.Data[2]) * transaction.fragmentSize
+				if fragmentOffset < len(transaction.respData) {
+					copy(transaction.respData[fragmentOffset:], respBytes[smiMemRespHeaderSize:])
+				}
+			}
+			transaction.fragmentCount--
+			if transaction.fragmentCount != 0 {
+				continue
+			}
+
+			// Send the reassembled response.
+			transactionLock.Lock()
+			delete(transactions, transactionId)
+			transactionLock.Unlock()
+			freeIds <- transactionId
+			respBytes = append([]byte{
+				transaction.respType,
+				transaction.respStatus,
+				transaction.tagLower,
+				transaction.tagUpper}, transaction.respData...)
+			sendFrame64(upstreamResponse, packFrame64(respBytes))
+		}
+	}
+}
+
+//
+// ArbitrateX2BurstLimited is a variant of ArbitrateX2 which enforces a
+// maximum burst size on each upstream port, as described for LimitBurst64.
+// Oversized requests are either split or rejected, depending on the split
+// bursts flag, with rejected request frames being sent on the burst error
+// channel. A maximum burst size of zero disables checking for that port.
+//
+func ArbitrateX2BurstLimited(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	maxBurstA uint16,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	maxBurstB uint16,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	splitBursts bool,
+	burstErrors chan<- Flit64) {
+
+	// Define local channel connections.
+	limitedRequestA := make(chan Flit64, 1)
+	limitedResponseA := make(chan Flit64, 1)
+	limitedRequestB := make(chan Flit64, 1)
+	limitedResponseB := make(chan Flit64, 1)
+
+	// Run the burst limiting routines.
+	go LimitBurst64(upstreamRequestA, upstreamResponseA,
+		limitedRequestA, limitedResponseA, maxBurstA, splitBursts, burstErrors)
+	go LimitBurst64(upstreamRequestB, upstreamResponseB,
+		limitedRequestB, limitedResponseB, maxBurstB, splitBursts, burstErrors)
+
+	ArbitrateX2(limitedRequestA, limitedResponseA, limitedRequestB,
+		limitedResponseB, downstreamRequest, downstreamResponse)
+}
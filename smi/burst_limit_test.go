@@ -0,0 +1,218 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLimitBurst64Split(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := filledBacking(512)
+	go LimitBurst64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, 64, true, make(chan Flit64))
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// The 200 byte write is split into four fragments, which are combined
+	// into a single response carrying the original tag.
+	writeData := make([]byte, 200)
+	for i := range writeData {
+		writeData[i] = uint8(i + 1)
+	}
+	go sendFrame64(upstreamRequest, WriteReqFrames64(10, writeData, nil, 0x21))
+	respFrame := recvFrame(t, upstreamResponse)
+	if ok, _ := ResponseStatus(respFrame[0]); !ok || len(respFrame) != 1 ||
+		respFrame[0].Data[0] != SmiMemWriteResp || respTag(respFrame[0]) != 0x2100 {
+		t.Fatalf("unexpected write response %v", respFrame)
+	}
+	if !bytes.Equal(backing[10:210], writeData) || backing[9] != 0xAA || backing[210] != 0xAA {
+		t.Fatalf("unexpected memory contents %v", backing[:211])
+	}
+
+	// The read data from each fragment is reassembled in order.
+	go sendFrame64(upstreamRequest, ReadReqFrames64(10, 200, 0x22))
+	respFrame = recvFrame(t, upstreamResponse)
+	respBytes := unpackFrame64(respFrame)
+	if ok, _ := ResponseStatus(respFrame[0]); !ok || respTag(respFrame[0]) != 0x2200 ||
+		!bytes.Equal(respBytes[smiMemRespHeaderSize:], writeData) {
+		t.Fatalf("unexpected read response %v", respBytes)
+	}
+
+	// Requests within the burst limit are forwarded unchanged.
+	go sendFrame64(upstreamRequest, ReadReqFrames64(500, 12, 0x23))
+	respFrame = recvFrame(t, upstreamResponse)
+	respBytes = unpackFrame64(respFrame)
+	if ok, _ := ResponseStatus(respFrame[0]); !ok || respTag(respFrame[0]) != 0x2300 ||
+		!bytes.Equal(respBytes[smiMemRespHeaderSize:], backing[500:]) {
+		t.Fatalf("unexpected read response %v", respBytes)
+	}
+}
+
+func TestLimitBurst64SplitStrobed(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := filledBacking(256)
+	go LimitBurst64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, 64, true, make(chan Flit64))
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// Each fragment of the 200 byte strobed write carries the byte enables
+	// for its own part of the write data.
+	writeData, writeStrobes, expected := strobedTestData(200)
+	go sendFrame64(upstreamRequest, WriteReqFrames64(0, writeData, writeStrobes, 0x24))
+	respFrame := recvFrame(t, upstreamResponse)
+	if ok, _ := ResponseStatus(respFrame[0]); !ok || respTag(respFrame[0]) != 0x2400 {
+		t.Fatalf("unexpected write response %v", respFrame)
+	}
+	if !bytes.Equal(backing[:200], expected) || backing[200] != 0xAA {
+		t.Fatalf("unexpected memory contents %v", backing[:201])
+	}
+}
+
+func TestLimitBurst64Reject(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := filledBacking(512)
+	go LimitBurst64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, 64, false, make(chan Flit64))
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// Oversized requests are rejected without stalling when the burst error
+	// channel is not serviced, and memory is left unchanged.
+	for i := 0; i != 4; i++ {
+		go sendFrame64(upstreamRequest, WriteReqFrames64(0, make([]byte, 100), nil, uint8(i)))
+		respFrame := recvFrame(t, upstreamResponse)
+		if ok, _ := ResponseStatus(respFrame[0]); ok || len(respFrame) != 1 ||
+			respFrame[0].Data[0] != SmiMemWriteResp || respTag(respFrame[0]) != uint16(i)<<8 {
+			t.Fatalf("write %d: unexpected response %v", i, respFrame)
+		}
+		go sendFrame64(upstreamRequest, ReadReqFrames64(0, 100, uint8(i)))
+		respFrame = recvFrame(t, upstreamResponse)
+		respBytes := unpackFrame64(respFrame)
+		if ok, _ := ResponseStatus(respFrame[0]); ok || respTag(respFrame[0]) != uint16(i)<<8 ||
+			!bytes.Equal(respBytes[smiMemRespHeaderSize:], make([]byte, 100)) {
+			t.Fatalf("read %d: unexpected response %v", i, respBytes)
+		}
+	}
+	if !bytes.Equal(backing, filledBacking(512)) {
+		t.Fatal("rejected write modified memory")
+	}
+
+	// Requests within the burst limit are still issued.
+	go sendFrame64(upstreamRequest, WriteReqFrames64(0, []byte{1, 2, 3, 4}, nil, 0x30))
+	if respFrame := recvFrame(t, upstreamResponse); respTag(respFrame[0]) != 0x3000 {
+		t.Fatalf("unexpected response %v", respFrame)
+	}
+	if !bytes.Equal(backing[:5], []byte{1, 2, 3, 4, 0xAA}) {
+		t.Fatalf("unexpected memory contents %v", backing[:5])
+	}
+}
+
+func TestLimitBurst64RejectErrors(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	burstErrors := make(chan Flit64, 64)
+	go LimitBurst64(upstreamRequest, upstreamResponse,
+		make(chan Flit64), make(chan Flit64), 64, false, burstErrors)
+
+	// A serviced burst error channel receives the rejected request frame.
+	reqFrame := WriteReqFrames64(0x40, make([]byte, 65), nil, 0x31)
+	go sendFrame64(upstreamRequest, reqFrame)
+	recvFrame(t, upstreamResponse)
+	for i, reqFlit := range reqFrame {
+		if errorFlit := recvFlit(t, burstErrors); errorFlit != reqFlit {
+			t.Fatalf("unexpected burst error flit %d %v", i, errorFlit)
+		}
+	}
+	expectIdle(t, burstErrors, 10*time.Millisecond)
+}
+
+func TestArbitrateX2BurstLimited(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64)
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := filledBacking(1024)
+	go ArbitrateX2BurstLimited(upstreamRequestA, upstreamResponseA, 32,
+		upstreamRequestB, upstreamResponseB, 0,
+		downstreamRequest, downstreamResponse, true, make(chan Flit64))
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// Port A splits its writes while port B is unlimited, with both ports
+	// active at the same time.
+	dataA := bytes.Repeat([]byte{0x11}, 100)
+	dataB := bytes.Repeat([]byte{0x22}, 200)
+	doneA := make(chan []Flit64)
+	go func() {
+		for i := 0; i != 8; i++ {
+			sendFrame64(upstreamRequestA, WriteReqFrames64(uint64(i*100), dataA, nil, uint8(i)))
+			doneA <- receiveFrame64(upstreamResponseA)
+		}
+	}()
+	for i := 0; i != 8; i++ {
+		go sendFrame64(upstreamRequestB, WriteReqFrames64(uint64(800), dataB, nil, uint8(0x40+i)))
+		if respFrame := recvFrame(t, upstreamResponseB); respTag(respFrame[0]) != uint16(0x40+i)<<8 {
+			t.Fatalf("port B write %d: unexpected response %v", i, respFrame)
+		}
+	}
+	for i := 0; i != 8; i++ {
+		select {
+		case respFrame := <-doneA:
+			if ok, _ := ResponseStatus(respFrame[0]); !ok || respTag(respFrame[0]) != uint16(i)<<8 {
+				t.Fatalf("port A write %d: unexpected response %v", i, respFrame)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("timed out waiting for port A write %d", i)
+		}
+	}
+	if !bytes.Equal(backing[:800], bytes.Repeat([]byte{0x11}, 800)) ||
+		!bytes.Equal(backing[800:1000], dataB) {
+		t.Fatal("unexpected memory contents")
+	}
+}
+
+func TestArbitrateX2BurstLimitedReject(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64)
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX2BurstLimited(upstreamRequestA, upstreamResponseA, 32,
+		upstreamRequestB, upstreamResponseB, 32,
+		downstreamRequest, downstreamResponse, false, make(chan Flit64))
+	go MemoryModel64(downstreamRequest, downstreamResponse, filledBacking(256))
+
+	// Both ports share the unserviced burst error channel and neither is
+	// blocked by oversized requests on the other.
+	for i := 0; i != 4; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 64, uint8(i)))
+		go sendFrame64(upstreamRequestB, ReadReqFrames64(0, 64, uint8(0x10+i)))
+		if respFrame := recvFrame(t, upstreamResponseA); respTag(respFrame[0]) != uint16(i)<<8 {
+			t.Fatalf("port A read %d: unexpected response %v", i, respFrame)
+		}
+		if respFrame := recvFrame(t, upstreamResponseB); respTag(respFrame[0]) != uint16(0x10+i)<<8 {
+			t.Fatalf("port B read %d: unexpected response %v", i, respFrame)
+		}
+	}
+	go sendFrame64(upstreamRequestB, ReadReqFrames64(0, 32, 0x20))
+	respFrame := recvFrame(t, upstreamResponseB)
+	if ok, _ := ResponseStatus(respFrame[0]); !ok || respTag(respFrame[0]) != 0x2000 {
+		t.Fatalf("unexpected response %v", respFrame)
+	}
+}
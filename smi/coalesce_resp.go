@@ -0,0 +1,109 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// CoalesceWriteResponses64 is a goroutine which may be inserted on an upstream
+// response channel to merge runs of consecutive write response frames into a
+// single aggregated write response. The aggregated response is a single flit
+// which carries the frame type, the combined status bits of all the merged
+// responses, the tag bytes from the last merged response and the number of
+// merged responses in Data[4], with an Eofc value of 5. A run of write
+// responses is terminated when a different frame type is received, when no
+// further response flits are immediately available or when the merged
+// response count would exceed 255. All other response frames are forwarded
+// unchanged.
+//
+// This changes the shape of the response stream, so it should only be used
+// by applications which do not need individual write acknowledgements and
+// which use WriteRespCount to decode the write responses. In particular, it
+// is not compatible with the WriteUInt* and WriteBurst* functions, which
+// expect one write response per write request. Since only the tag bytes of
+// the last merged response are kept, it should also not be used on a
+// response stream which is shared by several upstream ports, such as the
+// downstream side of an arbiter, as the tags identifying write responses
+// for the other ports would be lost.
+//
+func CoalesceWriteResponses64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	var headerFlit Flit64
+	hasHeaderFlit := false
+	for {
+		if !hasHeaderFlit {
+			headerFlit = <-smiInput
+		}
+		hasHeaderFlit = false
+
+		// Forward frames other than write responses unchanged.
+		if headerFlit.Data[0] != SmiMemWriteResp {
+			smiOutput <- headerFlit
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-smiInput
+				moreFlits = bodyFlit.Eofc == 0
+				smiOutput <- bodyFlit
+			}
+			continue
+		}
+
+		// Merge any immediately available write responses.
+		lastFlit := headerFlit
+		statusBits := uint8(0)
+		writeCount := uint8(0)
+		isWriteResp := true
+		for isWriteResp {
+			statusBits |= lastFlit.Data[1]
+			writeCount += WriteRespCount(lastFlit)
+			moreFlits := lastFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-smiInput
+				moreFlits = bodyFlit.Eofc == 0
+			}
+			isWriteResp = false
+			select {
+			case nextFlit := <-smiInput:
+				if nextFlit.Data[0] == SmiMemWriteResp &&
+					uint16(writeCount)+uint16(WriteRespCount(nextFlit)) <= 255 {
+					lastFlit = nextFlit
+					isWriteResp = true
+				} else {
+					headerFlit = nextFlit
+					hasHeaderFlit = true
+				}
+			default:
+			}
+		}
+
+		// Send the aggregated write response.
+		smiOutput <- Flit64{
+			Eofc: 5,
+			Data: [8]uint8{
+				uint8(SmiMemWriteResp),
+				statusBits,
+				lastFlit.Data[2],
+				lastFlit.Data[3],
+				writeCount,
+				uint8(0),
+				uint8(0),
+				uint8(0)}}
+	}
+}
+
+//
+// WriteRespCount returns the number of write requests that are acknowledged
+// by the specified write response header flit. This will be 1 for standard
+// write responses and the merged response count for aggregated write
+// responses generated by CoalesceWriteResponses64.
+//
+func WriteRespCount(headerFlit Flit64) uint8 {
+	if headerFlit.Eofc > 4 {
+		return headerFlit.Data[4]
+	}
+	return 1
+}
@@ -0,0 +1,79 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalesceWriteResponses64(t *testing.T) {
+	smiInput := make(chan Flit64, 64)
+	smiOutput := make(chan Flit64, 64)
+	writeResp := func(tag uint8, status uint8) []Flit64 {
+		return []Flit64{{Data: [8]uint8{SmiMemWriteResp, status, 0, tag}, Eofc: 4}}
+	}
+	readResp := packFrame64([]byte{SmiMemReadResp, 0, 0, 4, 1, 2, 3, 4, 5, 6, 7, 8})
+	errorResp := packFrame64(errorRespBytes(0, 5, SmiMemErrAddress))
+
+	// All the input frames are available before the stage starts, so each
+	// run of consecutive write responses is merged.
+	inputFrames := [][]Flit64{
+		writeResp(1, 0), writeResp(2, 0x02), writeResp(3, 0),
+		readResp, errorResp,
+		writeResp(6, 0), writeResp(7, 0),
+	}
+	for _, frame := range inputFrames {
+		sendFrame(t, smiInput, frame)
+	}
+	go CoalesceWriteResponses64(smiInput, smiOutput)
+
+	expectFrames := [][]Flit64{
+		{{Data: [8]uint8{SmiMemWriteResp, 0x02, 0, 3, 3}, Eofc: 5}},
+		readResp,
+		errorResp,
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 7, 2}, Eofc: 5}},
+	}
+	for i, frame := range expectFrames {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("output %d: unexpected frame %v", i, output)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	if count := WriteRespCount(expectFrames[0][0]); count != 3 {
+		t.Fatalf("unexpected merged count %d", count)
+	}
+	if count := WriteRespCount(writeResp(1, 0)[0]); count != 1 {
+		t.Fatalf("unexpected standard count %d", count)
+	}
+}
+
+func TestCoalesceWriteResponses64Separated(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 64)
+	go CoalesceWriteResponses64(smiInput, smiOutput)
+
+	// Write responses which are separated by a read response are not
+	// merged.
+	inputFrames := [][]Flit64{
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 1}, Eofc: 4}},
+		packFrame64([]byte{SmiMemReadResp, 0, 0, 2, 1, 2, 3, 4}),
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 3}, Eofc: 4}},
+	}
+	go func() {
+		for _, frame := range inputFrames {
+			sendFrame64(smiInput, frame)
+		}
+	}()
+	for i, tag := range []uint8{1, 2, 3} {
+		output := recvFrame(t, smiOutput)
+		if output[0].Data[3] != tag || (i != 1 && WriteRespCount(output[0]) != 1) {
+			t.Fatalf("output %d: unexpected frame %v", i, output)
+		}
+	}
+}
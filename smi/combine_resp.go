@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// CombineBurstResponses64 is a goroutine for use in simulation which merges
+// the read response fragments for a large read, as generated for the read
+// request frames built by ReadReqFrames64, into a single logical response
+// frame. Response frames do not carry the memory address, so fragments which
+// belong to the same request are identified by having the same tag bytes,
+// with the fragments being returned in address order. A run of fragments
+// ends with a fragment that carries less than SmiMemBurstSize bytes of read
+// data, or when a frame with different tag bytes or a frame which is not a
+// read response is received. The combined frame uses the header of the first
+// fragment, with the status flags of all the fragments being combined, and
+// carries the concatenated read data. It may therefore be longer than
+// SmiMemFrame64Size flits. Reads which are an exact multiple of
+// SmiMemBurstSize are only forwarded once the next frame has been received.
+// All other frames are forwarded unchanged.
+//
+func CombineBurstResponses64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	var combinedBytes []byte
+	for {
+		frame := receiveFrame64(smiInput)
+		frameBytes := unpackFrame64(frame)
+		isReadResp := len(frameBytes) >= smiMemRespHeaderSize &&
+			frameBytes[0] == SmiMemReadResp
+
+		// Flush the pending combined frame if this is not a matching fragment.
+		isMatching := isReadResp && combinedBytes != nil &&
+			frameBytes[2] == combinedBytes[2] && frameBytes[3] == combinedBytes[3]
+		if combinedBytes != nil && !isMatching {
+			sendRespBytes(smiOutput, combinedBytes)
+			combinedBytes = nil
+		}
+		if !isReadResp {
+			sendFrame64(smiOutput, frame)
+			putFrame64(frame)
+			putFrameBytes(frameBytes)
+			continue
+		}
+		putFrame64(frame)
+
+		// Add the fragment to the combined frame.
+		if combinedBytes == nil {
+			combinedBytes = append(getFrameBytes(), frameBytes[:smiMemRespHeaderSize]...)
+		} else {
+			combinedBytes[1] |= frameBytes[1]
+		}
+		readData := frameBytes[smiMemRespHeaderSize:]
+		combinedBytes = append(combinedBytes, readData...)
+		if len(readData) < SmiMemBurstSize {
+			sendRespBytes(smiOutput, combinedBytes)
+			combinedBytes = nil
+		}
+		putFrameBytes(frameBytes)
+	}
+}
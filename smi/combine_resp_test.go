@@ -0,0 +1,81 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCombineBurstResponses768(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 256)
+	go CombineBurstResponses64(smiInput, smiOutput)
+
+	readData := make([]byte, 768)
+	for i := range readData {
+		readData[i] = uint8(i * 11)
+	}
+	go func() {
+		for i := 0; i != 3; i++ {
+			sendFrame64(smiInput, packFrame64(append([]byte{SmiMemReadResp, 0, 1, 0x30},
+				readData[i*SmiMemBurstSize:(i+1)*SmiMemBurstSize]...)))
+		}
+	}()
+
+	// The combined frame is held until the next frame shows the run has
+	// ended.
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	writeResp := []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 1, 0x31}, Eofc: 4}}
+	go sendFrame64(smiInput, writeResp)
+	frame := recvFrame(t, smiOutput)
+	frameBytes := unpackFrame64(frame)
+	if !bytes.Equal(frameBytes[:smiMemRespHeaderSize], []byte{SmiMemReadResp, 0, 1, 0x30}) ||
+		!bytes.Equal(frameBytes[smiMemRespHeaderSize:], readData) {
+		t.Fatalf("unexpected combined frame header %v", frameBytes[:smiMemRespHeaderSize])
+	}
+	if len(frame) != (smiMemRespHeaderSize+768+7)/8 || frame[len(frame)-1].Eofc != 4 {
+		t.Fatalf("unexpected combined frame length %d", len(frame))
+	}
+	if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, writeResp) {
+		t.Fatalf("unexpected write response %v", output)
+	}
+}
+
+func TestCombineBurstResponsesFlush(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 256)
+	go CombineBurstResponses64(smiInput, smiOutput)
+
+	// A short final fragment ends the run immediately, with the status
+	// flags of all the fragments being combined.
+	go func() {
+		sendFrame64(smiInput, packFrame64(append([]byte{SmiMemReadResp, 0, 0, 1},
+			make([]byte, SmiMemBurstSize)...)))
+		sendFrame64(smiInput, packFrame64(append([]byte{SmiMemReadResp, 0x02, 0, 1},
+			make([]byte, 100)...)))
+	}()
+	frameBytes := unpackFrame64(recvFrame(t, smiOutput))
+	if len(frameBytes) != smiMemRespHeaderSize+SmiMemBurstSize+100 || frameBytes[1] != 0x02 {
+		t.Fatalf("unexpected combined frame size %d options 0x%02X", len(frameBytes), frameBytes[1])
+	}
+
+	// A change of tag flushes the pending run.
+	go func() {
+		sendFrame64(smiInput, packFrame64(append([]byte{SmiMemReadResp, 0, 0, 2},
+			make([]byte, SmiMemBurstSize)...)))
+		sendFrame64(smiInput, packFrame64([]byte{SmiMemReadResp, 0, 0, 3, 1, 2, 3, 4}))
+	}()
+	for i, expectLength := range []int{SmiMemBurstSize, 4} {
+		frameBytes := unpackFrame64(recvFrame(t, smiOutput))
+		if frameBytes[3] != uint8(2+i) || len(frameBytes) != smiMemRespHeaderSize+expectLength {
+			t.Fatalf("unexpected frame %d size %d", i, len(frameBytes))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// The CRC stages protect complete SMI frames on unreliable links using the
+// 16-bit CRC-CCITT code, with the generator polynomial x^16 + x^12 + x^5 + 1
+// (0x1021), an initial value of 0xFFFF and no final inversion. The CRC is
+// calculated over all the valid bytes of the frame in order, with the most
+// significant bit of each byte being processed first. It is appended to the
+// frame as two trailing bytes, least significant byte first, immediately
+// after the last valid frame byte. This is placed in the final flit if there
+// is space, otherwise an additional flit is added to the frame. Protected
+// frames may therefore be one flit longer than SmiMemFrame64Size. Since the
+// CRC also covers the header, AppendCrc64 and CheckCrc64 should be placed
+// either side of the unreliable link, with no tag substitution in between.
+//
+const smiCrcInit = uint16(0xFFFF)
+
+//
+// crcUpdate updates the running CRC value with a single frame byte.
+//
+func crcUpdate(crc uint16, value uint8) uint16 {
+	crc ^= uint16(value) << 8
+	for i := 0; i != 8; i++ {
+		if crc&0x8000 != 0 {
+			crc = (crc << 1) ^ 0x1021
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
+
+//
+// AppendCrc64 is a goroutine which forwards Flit64 based SMI frames from its
+// input to its output, appending the CRC to each frame as described above.
+//
+func AppendCrc64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	crc := smiCrcInit
+	for {
+		frameFlit := <-smiInput
+		validBytes := frameFlit.PayloadLen()
+		for i := 0; i != validBytes; i++ {
+			crc = crcUpdate(crc, frameFlit.Data[i])
+		}
+		if frameFlit.Eofc == 0 {
+			smiOutput <- frameFlit
+			continue
+		}
+
+		// Append the CRC bytes, adding an extra flit if required.
+		crcFlit := Flit64{}
+		switch {
+		case validBytes <= 6:
+			frameFlit.Data[validBytes] = uint8(crc)
+			frameFlit.Data[validBytes+1] = uint8(crc >> 8)
+			frameFlit.Eofc = uint8(validBytes + 2)
+		case validBytes == 7:
+			frameFlit.Data[7] = uint8(crc)
+			frameFlit.Eofc = 0
+			crcFlit.Data[0] = uint8(crc >> 8)
+			crcFlit.Eofc = 1
+		default:
+			frameFlit.Eofc = 0
+			crcFlit.Data[0] = uint8(crc)
+			crcFlit.Data[1] = uint8(crc >> 8)
+			crcFlit.Eofc = 2
+		}
+		smiOutput <- frameFlit
+		if crcFlit.Eofc != 0 {
+			smiOutput <- crcFlit
+		}
+		crc = smiCrcInit
+	}
+}
+
+//
+// CheckCrc64 is a goroutine for use in simulation which verifies the CRC of
+// each frame generated by AppendCrc64. Each frame is buffered until it is
+// complete and the CRC is then checked. Frames with a matching CRC are
+// forwarded to the output with the CRC bytes removed. Corrupted frames are
+// discarded and an error is reported on the error channel. This is a
+// non-blocking send, so errors will be discarded if the error channel is not
+// being serviced.
+//
+func CheckCrc64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	crcErrors chan<- error) {
+
+	for {
+		frameBytes := unpackFrame64(receiveFrame64(smiInput))
+		var crcErr error
+		if len(frameBytes) < 3 {
+			crcErr = fmt.Errorf("protected frame size %d is too short", len(frameBytes))
+		} else {
+			dataLength := len(frameBytes) - 2
+			crc := smiCrcInit
+			for _, frameByte := range frameBytes[:dataLength] {
+				crc = crcUpdate(crc, frameByte)
+			}
+			frameCrc := uint16(frameBytes[dataLength]) |
+				(uint16(frameBytes[dataLength+1]) << 8)
+			if crc == frameCrc {
+				sendFrame64(smiOutput, packFrame64(frameBytes[:dataLength]))
+				continue
+			}
+			crcErr = fmt.Errorf("frame type 0x%02X CRC 0x%04X does not match 0x%04X",
+				frameBytes[0], frameCrc, crc)
+		}
+		select {
+		case crcErrors <- crcErr:
+		default:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCrcUpdateCheckValue(t *testing.T) {
+	crc := smiCrcInit
+	for _, value := range []byte("123456789") {
+		crc = crcUpdate(crc, value)
+	}
+	if crc != 0x29B1 {
+		t.Fatalf("unexpected check value 0x%04X", crc)
+	}
+}
+
+func TestCrcRoundTrip(t *testing.T) {
+	smiInput := make(chan Flit64)
+	protected := make(chan Flit64, 64)
+	smiOutput := make(chan Flit64, 64)
+	crcErrors := make(chan error, 1)
+	go AppendCrc64(smiInput, protected)
+	go CheckCrc64(protected, smiOutput, crcErrors)
+
+	// The write lengths place the CRC in the final flit, split it across an
+	// additional flit and place it entirely in an additional flit.
+	for _, length := range []int{8, 9, 10} {
+		frame := WriteReqFrames64(0x40, make([]byte, length), nil, uint8(length))
+		go sendFrame64(smiInput, frame)
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("length %d: unexpected frame %v", length, output)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	select {
+	case err := <-crcErrors:
+		t.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
+
+func TestCrcCorruptedPayload(t *testing.T) {
+	smiInput := make(chan Flit64)
+	protected := make(chan Flit64, 64)
+	link := make(chan Flit64, 64)
+	smiOutput := make(chan Flit64, 64)
+	crcErrors := make(chan error, 1)
+	go AppendCrc64(smiInput, protected)
+	go CheckCrc64(link, smiOutput, crcErrors)
+
+	// Flip a payload byte on the link between the CRC stages.
+	go sendFrame64(smiInput, WriteReqFrames64(0x40, []byte{1, 2, 3, 4, 5, 6, 7, 8}, nil, 0))
+	frame := recvFrame(t, protected)
+	frame[2].Data[0] ^= 0x10
+	sendFrame(t, link, frame)
+	select {
+	case err := <-crcErrors:
+		if err == nil {
+			t.Fatal("nil CRC error")
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for CRC error")
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
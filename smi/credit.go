@@ -0,0 +1,106 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// CreditFlowControl64 is a goroutine which applies credit based flow control
+// to the requests issued by an upstream SMI master, bounding the number of
+// request frames which the master can have in flight independently of the
+// tag FIFO of the downstream arbiter port. Credits are added to the credit
+// pool by sending the number of new credits on the credit grant channel.
+// Each request frame consumes a single credit, and no further request frames
+// are accepted from the upstream master while the credit pool is empty. A
+// credit is returned to the pool each time a response frame is forwarded to
+// the upstream master. Each request frame is assembled in full before being
+// forwarded, so that a slow upstream master does not hold the downstream
+// port in the middle of a frame. Request frames which are longer than
+// SmiMemFrame64Size flits are truncated. The upstream master must not use
+// requests which do not generate a response, since their credits would never
+// be returned.
+//
+func CreditFlowControl64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	creditGrant <-chan uint8) {
+
+	// Set up the shared credit pool.
+	var creditLock sync.Mutex
+	creditCount := uint32(0)
+	creditReturned := make(chan bool, 1)
+
+	// Forward request frames once a credit is available.
+	go func() {
+		// TODO: The array size here should be set using the
+		// SmiMemFrame64Size constant once supported by the compiler.
+		var frameBuffer [34]Flit64
+		for {
+
+			// Wait for a credit to become available.
+			hasCredit := false
+			for !hasCredit {
+				creditLock.Lock()
+				if creditCount != 0 {
+					creditCount--
+					hasCredit = true
+				}
+				creditLock.Unlock()
+				if !hasCredit {
+					select {
+					case newCredits := <-creditGrant:
+						creditLock.Lock()
+						creditCount += uint32(newCredits)
+						creditLock.Unlock()
+					case <-creditReturned:
+					}
+				}
+			}
+
+			// Assemble the complete request frame.
+			frameLength := uint8(0)
+			moreFlits := true
+			for moreFlits {
+				reqFlit := <-upstreamRequest
+				moreFlits = reqFlit.Eofc == 0
+				if frameLength != 34 {
+					frameBuffer[frameLength] = reqFlit
+					frameLength++
+				}
+			}
+			if frameBuffer[frameLength-1].Eofc == 0 {
+				frameBuffer[frameLength-1].Eofc = 8
+			}
+
+			// Forward the request frame.
+			for i := uint8(0); i != frameLength; i++ {
+				downstreamRequest <- frameBuffer[i]
+			}
+		}
+	}()
+
+	// Forward response frames, returning a credit for each one.
+	for {
+		moreFlits := true
+		for moreFlits {
+			respFlit := <-downstreamResponse
+			upstreamResponse <- respFlit
+			moreFlits = respFlit.Eofc == 0
+		}
+		creditLock.Lock()
+		creditCount++
+		creditLock.Unlock()
+		select {
+		case creditReturned <- true:
+		default:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreditFlowControlLimit(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	creditGrant := make(chan uint8)
+	go CreditFlowControl64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, creditGrant)
+
+	// No frames are accepted before any credits are granted.
+	reqFrame := WriteReqFrames64(0, make([]byte, 20), nil, 1)
+	if tryOffer(upstreamRequest, reqFrame[0], 10*time.Millisecond) {
+		t.Fatal("request accepted without credit")
+	}
+
+	// Two credits allow two frames, after which the producer blocks.
+	creditGrant <- 2
+	for i := 0; i != 2; i++ {
+		sendFrame(t, upstreamRequest, reqFrame)
+		recvFrame(t, downstreamRequest)
+	}
+	if tryOffer(upstreamRequest, reqFrame[0], 10*time.Millisecond) {
+		t.Fatal("request accepted after credit limit")
+	}
+
+	// A response returns a credit, which releases a single frame.
+	sendFrame(t, downstreamResponse, []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 1}, Eofc: 4}})
+	recvFrame(t, upstreamResponse)
+	sendFrame(t, upstreamRequest, reqFrame)
+	recvFrame(t, downstreamRequest)
+	if tryOffer(upstreamRequest, reqFrame[0], 10*time.Millisecond) {
+		t.Fatal("request accepted after returned credit was used")
+	}
+
+	// Granting further credits releases the producer again.
+	creditGrant <- 1
+	sendFrame(t, upstreamRequest, reqFrame)
+	if frame := recvFrame(t, downstreamRequest); len(frame) != len(reqFrame) {
+		t.Fatalf("unexpected frame %v", frame)
+	}
+}
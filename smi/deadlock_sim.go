@@ -0,0 +1,234 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+// +build simulation
+
+package smi
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// The deadlock monitor settings are shared by all upstream ports, so that
+// deadlock detection can be enabled without changing the arbiter interfaces.
+//
+var (
+	deadlockLock      sync.Mutex
+	deadlockThreshold time.Duration
+	deadlockSignal    chan<- uint8
+)
+
+//
+// SetDeadlockMonitor enables deadlock detection for the upstream ports of
+// ArbitrateX2Monitored, which is only available in simulation builds.
+// If all SmiMemInFlightLimit tags for a port remain outstanding for longer
+// than the specified threshold, the port ID is sent on the deadlock signal
+// channel, indicating that the downstream port has probably stalled. This is
+// a non-blocking send, so signals will be discarded if the deadlock signal
+// channel is not being serviced. Setting a nil signal channel disables
+// deadlock detection for subsequent tag exhaustion events.
+//
+func SetDeadlockMonitor(threshold time.Duration, signal chan<- uint8) {
+	deadlockLock.Lock()
+	defer deadlockLock.Unlock()
+	deadlockThreshold = threshold
+	deadlockSignal = signal
+}
+
+//
+// Type tagMonitor tracks the number of outstanding tags for an upstream port,
+// starting the deadlock timer when the tag FIFO becomes empty and cancelling
+// it when a tag is returned.
+//
+type tagMonitor struct {
+	portId      uint8
+	lock        sync.Mutex
+	outstanding int
+	timer       *time.Timer
+}
+
+func newTagMonitor(portId uint8) *tagMonitor {
+	return &tagMonitor{portId: portId}
+}
+
+func (monitor *tagMonitor) tagAllocated() {
+	monitor.lock.Lock()
+	defer monitor.lock.Unlock()
+	monitor.outstanding++
+	if monitor.outstanding != SmiMemInFlightLimit {
+		return
+	}
+	deadlockLock.Lock()
+	threshold := deadlockThreshold
+	signal := deadlockSignal
+	deadlockLock.Unlock()
+	if signal != nil {
+		monitor.timer = time.AfterFunc(threshold, func() {
+			select {
+			case signal <- monitor.portId:
+			default:
+			}
+		})
+	}
+}
+
+func (monitor *tagMonitor) tagReleased() {
+	monitor.lock.Lock()
+	defer monitor.lock.Unlock()
+	if monitor.timer != nil {
+		monitor.timer.Stop()
+		monitor.timer = nil
+	}
+	monitor.outstanding--
+}
+
+//
+// manageUpstreamPortMonitored is a variant of manageUpstreamPort which also
+// tracks tag usage for deadlock detection, as described for
+// SetDeadlockMonitor. This keeps the simulation instrumentation out of the
+// standard port management used for hardware builds.
+//
+func manageUpstreamPortMonitored(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	var tagTableLower [SmiMemInFlightLimit]uint8
+	var tagTableUpper [SmiMemInFlightLimit]uint8
+	tagFifo := make(chan uint8, SmiMemInFlightLimit)
+	tagMonitor := newTagMonitor(portId)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != SmiMemInFlightLimit; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		for {
+
+			// Do tag replacement on header.
+			headerFlit := <-upstreamRequest
+			tagId := <-tagFifo
+			tagMonitor.tagAllocated()
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Carry out tag replacement on responses.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-taggedResponse
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		tagMonitor.tagReleased()
+		tagFifo <- tagId
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
+
+//
+// ArbitrateX2Monitored is a variant of ArbitrateX2 which is only available in
+// simulation builds. Each upstream port tracks its outstanding tags and
+// signals a probable deadlock as described for SetDeadlockMonitor.
+//
+func ArbitrateX2Monitored(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPortMonitored(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPortMonitored(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,49 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+// +build simulation
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadlockMonitorWithheldResponses(t *testing.T) {
+	deadlockSignal := make(chan uint8, 1)
+	SetDeadlockMonitor(20*time.Millisecond, deadlockSignal)
+	defer SetDeadlockMonitor(0, nil)
+
+	upstreamRequestA := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	go ArbitrateX2Monitored(upstreamRequestA, make(chan Flit64),
+		make(chan Flit64), make(chan Flit64),
+		downstreamRequest, make(chan Flit64))
+
+	// Three outstanding requests leave a free tag, so no signal is raised.
+	for i := 0; i != SmiMemInFlightLimit; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, uint8(i)))
+		recvFrame(t, downstreamRequest)
+		if i == SmiMemInFlightLimit-2 {
+			select {
+			case portId := <-deadlockSignal:
+				t.Fatalf("unexpected deadlock signal for port %d", portId)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	// Withholding the responses to all the tags raises the signal.
+	select {
+	case portId := <-deadlockSignal:
+		if portId != 1 {
+			t.Fatalf("deadlock signalled for port %d", portId)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for deadlock signal")
+	}
+}
@@ -0,0 +1,55 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// DemuxByTagRangeX2 is a goroutine which routes complete SMI response frames
+// from a shared response channel to one of two outputs, based on the 16-bit
+// tag in bytes 2 and 3 of the header flit. This supports topologies where the
+// masters share a response channel but each use a disjoint range of tag
+// values, rather than relying on the port ID substitution used by the
+// arbiters. Frames with a tag in the inclusive range tagLowA to tagHighA are
+// sent to output A and frames with a tag in the inclusive range tagLowB to
+// tagHighB are sent to output B. If the ranges overlap, output A takes
+// priority. Frames with a tag outside both ranges are sent to the error
+// output.
+//
+func DemuxByTagRangeX2(
+	smiResponse <-chan Flit64,
+	smiResponseA chan<- Flit64,
+	tagLowA uint16,
+	tagHighA uint16,
+	smiResponseB chan<- Flit64,
+	tagLowB uint16,
+	tagHighB uint16,
+	errorResponse chan<- Flit64) {
+
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-smiResponse
+		if isHeaderFlit {
+			tagId := uint16(respFlit.Data[2]) | (uint16(respFlit.Data[3]) << 8)
+			if tagId >= tagLowA && tagId <= tagHighA {
+				portId = 1
+			} else if tagId >= tagLowB && tagId <= tagHighB {
+				portId = 2
+			} else {
+				portId = 0
+			}
+		}
+		switch portId {
+		case 1:
+			smiResponseA <- respFlit
+		case 2:
+			smiResponseB <- respFlit
+		default:
+			errorResponse <- respFlit
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
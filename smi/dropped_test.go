@@ -0,0 +1,68 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArbitrateX4WithDropped(t *testing.T) {
+	upstreamRequests := make([]chan Flit64, 4)
+	upstreamResponses := make([]chan Flit64, 4)
+	for i := range upstreamRequests {
+		upstreamRequests[i] = make(chan Flit64)
+		upstreamResponses[i] = make(chan Flit64, 16)
+	}
+	downstreamRequest := make(chan Flit64, 16)
+	downstreamResponse := make(chan Flit64)
+	dropped := make(chan Flit64, 16)
+	go ArbitrateX4WithDropped(
+		upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		upstreamRequests[2], upstreamResponses[2],
+		upstreamRequests[3], upstreamResponses[3],
+		downstreamRequest, downstreamResponse, dropped)
+
+	// Both flits of a response with port ID 5 surface on the dropped
+	// channel and are not delivered upstream.
+	invalidResp := packFrame64([]byte{SmiMemReadResp, 0, 5, 0, 1, 2, 3, 4, 5, 6, 7, 8})
+	sendFrame(t, downstreamResponse, invalidResp)
+	droppedFlits := []Flit64{recvFlit(t, dropped), recvFlit(t, dropped)}
+	if !reflect.DeepEqual(droppedFlits, invalidResp) {
+		t.Fatalf("unexpected dropped flits %v", droppedFlits)
+	}
+	for port := range upstreamResponses {
+		if len(upstreamResponses[port]) != 0 {
+			t.Fatalf("invalid response delivered to port %d", port+1)
+		}
+	}
+
+	// Valid responses are still routed normally.
+	go sendFrame64(upstreamRequests[3], ReadReqFrames64(0, 8, 0x44))
+	reqHeader := recvFrame(t, downstreamRequest)[0]
+	sendFrame(t, downstreamResponse, packFrame64(
+		[]byte{SmiMemReadResp, 0, reqHeader.Data[2], reqHeader.Data[3], 1, 2, 3, 4, 5, 6, 7, 8}))
+	if _, tag, err := ParseReadResp64(upstreamResponses[3]); err != nil || tag != 0x44 {
+		t.Fatalf("unexpected response %d %v", tag, err)
+	}
+	expectIdle(t, dropped, 10*time.Millisecond)
+}
+
+func TestArbitrateX2NilDropped(t *testing.T) {
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX2(make(chan Flit64), make(chan Flit64),
+		make(chan Flit64), make(chan Flit64),
+		make(chan Flit64), downstreamResponse)
+
+	// Invalid flits are discarded without stalling the response path.
+	for i := 0; i != 4; i++ {
+		sendFrame(t, downstreamResponse, []Flit64{{
+			Data: [8]uint8{SmiMemWriteResp, 0, 7, uint8(i)}, Eofc: 4}})
+	}
+}
@@ -0,0 +1,166 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// The ECC stages protect the data bytes of each flit using a (72,64) single
+// error correcting, double error detecting (SECDED) Hamming code. The eight
+// ECC check bits for each flit are carried alongside the flit data in an
+// extended flit, so the protected path uses EccFlit64 channels in place of
+// Flit64 channels. EccEncode64 should be placed at the start of the protected
+// path, such as before a memory write or an unreliable link, and EccDecode64
+// should be placed at the end, such as after a memory read. Unprotected paths
+// continue to use plain Flit64 channels, so the ECC stages are optional and
+// may be omitted without any other changes.
+//
+// The data bits are mapped to the non power of two positions 3 to 71 of the
+// Hamming code word, the lower seven check bits hold the Hamming parity bits
+// and the most significant check bit holds the overall parity of the code
+// word. The end of frame marker is not protected.
+//
+
+//
+// Type EccFlit64 is an extended Flit64 which carries the ECC check bits for
+// its data bytes.
+//
+type EccFlit64 struct {
+	Data  [8]uint8
+	Check uint8
+	Eofc  uint8
+}
+
+//
+// eccSyndrome calculates the Hamming syndrome and parity of the flit data,
+// which is the exclusive OR of the code word positions of all the set data
+// bits.
+//
+func eccSyndrome(flitData [8]uint8) (uint8, uint8) {
+	syndrome := uint8(0)
+	parity := uint8(0)
+	codePos := uint8(3)
+	for i := uint8(0); i != 64; i++ {
+		if flitData[i>>3]&(uint8(1)<<(i&7)) != 0 {
+			syndrome ^= codePos
+			parity ^= 1
+		}
+		codePos++
+		if codePos&(codePos-1) == 0 {
+			codePos++
+		}
+	}
+	return syndrome, parity
+}
+
+//
+// eccParity calculates the parity of a byte.
+//
+func eccParity(value uint8) uint8 {
+	value ^= value >> 4
+	value ^= value >> 2
+	value ^= value >> 1
+	return value & 1
+}
+
+//
+// EccCheck64 calculates the SECDED check bits for the specified flit data.
+//
+func EccCheck64(flitData [8]uint8) uint8 {
+	syndrome, parity := eccSyndrome(flitData)
+	return syndrome | ((parity ^ eccParity(syndrome)) << 7)
+}
+
+//
+// EccCorrect64 checks the flit data against the SECDED check bits, correcting
+// a single bit error if one is present. The first boolean result indicates
+// that a single bit error was corrected and the second indicates that an
+// uncorrectable error was detected, in which case the flit data is returned
+// unchanged.
+//
+func EccCorrect64(flitData [8]uint8, checkBits uint8) ([8]uint8, bool, bool) {
+	syndrome, parity := eccSyndrome(flitData)
+	syndrome ^= checkBits & 0x7F
+	parity ^= eccParity(checkBits)
+
+	// No errors or a double bit error.
+	if parity == 0 {
+		return flitData, false, syndrome != 0
+	}
+
+	// Single bit errors in the check bits do not affect the data.
+	if syndrome&(syndrome-1) == 0 {
+		return flitData, true, false
+	}
+
+	// Locate and correct single bit errors in the data.
+	codePos := uint8(3)
+	for i := uint8(0); i != 64; i++ {
+		if codePos == syndrome {
+			flitData[i>>3] ^= uint8(1) << (i & 7)
+			return flitData, true, false
+		}
+		codePos++
+		if codePos&(codePos-1) == 0 {
+			codePos++
+		}
+	}
+	return flitData, false, true
+}
+
+//
+// EccEncode64 is a goroutine which converts a stream of Flit64 based SMI
+// frames to ECC protected flits by adding the SECDED check bits for each
+// flit.
+//
+func EccEncode64(
+	smiInput <-chan Flit64,
+	eccOutput chan<- EccFlit64) {
+
+	for {
+		inputFlit := <-smiInput
+		eccOutput <- EccFlit64{
+			Data:  inputFlit.Data,
+			Check: EccCheck64(inputFlit.Data),
+			Eofc:  inputFlit.Eofc}
+	}
+}
+
+//
+// EccDecode64 is a goroutine which converts a stream of ECC protected flits
+// back to Flit64 based SMI frames. Single bit errors are corrected and sent
+// on the corrected error channel, while uncorrectable errors are sent on the
+// uncorrectable error channel and the affected flits are forwarded
+// unchanged. The received ECC flits are reported in both cases, prior to
+// correction. These are non-blocking sends, so errors will be discarded if
+// the error channels are not being serviced.
+//
+func EccDecode64(
+	eccInput <-chan EccFlit64,
+	smiOutput chan<- Flit64,
+	correctedErrors chan<- EccFlit64,
+	uncorrectableErrors chan<- EccFlit64) {
+
+	for {
+		inputFlit := <-eccInput
+		flitData, isCorrected, isUncorrectable :=
+			EccCorrect64(inputFlit.Data, inputFlit.Check)
+		if isCorrected {
+			select {
+			case correctedErrors <- inputFlit:
+			default:
+			}
+		}
+		if isUncorrectable {
+			select {
+			case uncorrectableErrors <- inputFlit:
+			default:
+			}
+		}
+		smiOutput <- Flit64{
+			Data: flitData,
+			Eofc: inputFlit.Eofc}
+	}
+}
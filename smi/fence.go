@@ -0,0 +1,138 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// FenceWrites64 is a goroutine which provides a memory fence for an SMI master,
+// since SMI has no native fence operation. It is inserted between the master
+// and the downstream port. Request and response frames are normally forwarded
+// unchanged, while the number of outstanding write requests is tracked. When
+// a value is received on the fence request channel, no further request frames
+// are forwarded until the responses for all previously forwarded write
+// requests have been forwarded upstream. A fence complete signal is then sent
+// and normal forwarding resumes. Fence requests are only accepted between
+// request frames.
+//
+// This guarantees that any request forwarded after a fence observes all the
+// writes which were forwarded before it, including reads issued separately
+// from the writes. The latency cost is the time taken for all outstanding
+// writes to complete, during which the request path is stalled. Outstanding
+// reads are not waited for.
+//
+// Writes are completed by either a write response or an error response with
+// the same tag bytes, so each outstanding write must have a unique tag. Up to
+// SmiMemInFlightLimit writes may be outstanding, with any further write
+// requests being held until an earlier write completes. This matches the
+// number of in-flight transactions supported by an arbitrated port.
+//
+func FenceWrites64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	fenceReq <-chan bool,
+	fenceDone chan<- bool) {
+
+	fenceTarget := make(chan uint32, 1)
+	fenceClear := make(chan bool, 1)
+
+	// The tags of issued writes are passed to the response handler, which
+	// returns a write credit when each write completes.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	writeIssued := make(chan uint16, 4)
+	writeCredits := make(chan bool, 4)
+	for creditInit := 0; creditInit != 4; creditInit++ {
+		writeCredits <- true
+	}
+
+	// Start goroutine for forwarding requests, counting the issued writes.
+	go func() {
+		issueCount := uint32(0)
+		for {
+			var headerFlit Flit64
+			select {
+			case headerFlit = <-upstreamRequest:
+			case <-fenceReq:
+
+				// Wait for all outstanding writes to complete.
+				fenceTarget <- issueCount
+				<-fenceClear
+				fenceDone <- true
+				continue
+			}
+
+			// Forward the request frame.
+			if headerFlit.Data[0] == SmiMemWriteReq {
+				<-writeCredits
+				writeIssued <- uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+				issueCount++
+			}
+			downstreamRequest <- headerFlit
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				downstreamRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Forward responses, counting the completed writes and clearing the fence
+	// once the completed count reaches the issued count. The tags of issued
+	// writes are queued before the write requests are forwarded, so they are
+	// always available by the time the corresponding responses are received.
+	var issuedValid [4]bool
+	var issuedTags [4]uint16
+	completeCount := uint32(0)
+	fenceCount := uint32(0)
+	isFencing := false
+	for {
+		if isFencing && completeCount == fenceCount {
+			fenceClear <- true
+			isFencing = false
+		}
+		select {
+		case fenceCount = <-fenceTarget:
+			isFencing = true
+		case headerFlit := <-downstreamResponse:
+			moreIssued := true
+			for moreIssued {
+				select {
+				case issuedTag := <-writeIssued:
+					for i := 0; i != 4; i++ {
+						if !issuedValid[i] {
+							issuedValid[i] = true
+							issuedTags[i] = issuedTag
+							break
+						}
+					}
+				default:
+					moreIssued = false
+				}
+			}
+			upstreamResponse <- headerFlit
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-downstreamResponse
+				moreFlits = bodyFlit.Eofc == 0
+				upstreamResponse <- bodyFlit
+			}
+			if headerFlit.Data[0] == SmiMemWriteResp || headerFlit.Data[0] == SmiMemErrorResp {
+				respTag := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+				for i := 0; i != 4; i++ {
+					if issuedValid[i] && issuedTags[i] == respTag {
+						issuedValid[i] = false
+						writeCredits <- true
+						completeCount++
+						break
+					}
+				}
+			}
+		}
+	}
+}
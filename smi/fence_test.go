@@ -0,0 +1,37 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFenceWritesErrorCompletesWrite(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	fenceReq := make(chan bool)
+	fenceDone := make(chan bool)
+	go FenceWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, fenceReq, fenceDone)
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 256))
+
+	sendFrame(t, upstreamRequest, WriteReqFrames64(0, make([]byte, 8), nil, 1))
+	sendFrame(t, upstreamRequest, WriteReqFrames64(1024, make([]byte, 8), nil, 2))
+	sendFrame(t, upstreamRequest, ReadReqFrames64(1024, 8, 3))
+	fenceReq <- true
+	select {
+	case <-fenceDone:
+	case <-time.After(testTimeout):
+		t.Fatal("fence did not complete after a failed write")
+	}
+	for i := 0; i != 3; i++ {
+		recvFrame(t, upstreamResponse)
+	}
+}
@@ -0,0 +1,173 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Frame filtering stages inspect the payload of each frame and either forward
+// or drop the entire frame. The payload of write requests starts after the
+// 14 byte request header and any strobe mask, and the payload of read
+// responses starts after the 4 byte response header. All other frame types
+// have an empty payload. Since
+// the decision can only be made once the payload has been inspected, these
+// stages use store and forward buffering. This adds the full frame transfer
+// time to the latency of every frame, which is up to 34 flit cycles for a
+// maximum size burst, and prevents the stage from forwarding one frame while
+// the next is being received.
+//
+
+//
+// framePayload extracts the payload bytes from a serialized frame.
+//
+func framePayload(frameBytes []byte) []byte {
+	payloadOffset := len(frameBytes)
+	switch frameBytes[0] {
+	case SmiMemWriteReq:
+		payloadOffset = smiMemReqHeaderSize
+		if _, writeData, isValid := writePayload(frameBytes); isValid {
+			payloadOffset = len(frameBytes) - len(writeData)
+		}
+	case SmiMemReadResp:
+		payloadOffset = smiMemRespHeaderSize
+	}
+	if payloadOffset > len(frameBytes) {
+		payloadOffset = len(frameBytes)
+	}
+	return frameBytes[payloadOffset:]
+}
+
+//
+// FilterByPayload is a goroutine which buffers each Flit64 based SMI frame
+// from its input and applies the supplied predicate to the frame payload.
+// Frames for which the predicate returns true are forwarded to the output
+// and all other frames are dropped. This is intended for simulation, since
+// function values may not be supported by the FPGA compiler.
+// FilterByFirstPayloadByte64 provides a concrete example which is suitable
+// for synthesis.
+//
+func FilterByPayload(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	predicate func(payload []byte) bool) {
+
+	for {
+		frame := receiveFrame64(smiInput)
+		if predicate(framePayload(unpackFrame64(frame))) {
+			sendFrame64(smiOutput, frame)
+		}
+	}
+}
+
+//
+// FilterByFirstPayloadByte64 is a goroutine which buffers each Flit64 based
+// SMI frame from its input and only forwards frames where the first payload
+// byte matches the specified value. The first payload byte of a write
+// request with byte strobes is the first byte after the strobe mask. Frames
+// with an empty payload are dropped. Frames which exceed the size of a
+// maximum length burst request can not be buffered and are also dropped.
+//
+func FilterByFirstPayloadByte64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	matchValue uint8) {
+
+	// TODO: The array size here should be set using the SmiMemFrame64Size
+	// constant once supported by the compiler.
+	var frameBuffer [34]Flit64
+
+	for {
+		headerFlit := <-smiInput
+		frameBuffer[0] = headerFlit
+
+		// Select the location of the first payload byte.
+		payloadFlit := uint8(0xFF)
+		payloadByte := uint8(0)
+		switch headerFlit.Data[0] {
+		case SmiMemWriteReq:
+			payloadFlit = 1
+			payloadByte = 6
+		case SmiMemReadResp:
+			payloadFlit = 0
+			payloadByte = 4
+		}
+
+		// Buffer the remainder of the frame, checking the first payload byte.
+		isMatch := false
+		isOverflow := false
+		flitCount := uint8(0)
+		moreFlits := true
+		for moreFlits {
+			frameFlit := frameBuffer[0]
+			if flitCount != 0 {
+				frameFlit = <-smiInput
+			}
+			if flitCount < 34 /* SmiMemFrame64Size */ {
+				frameBuffer[flitCount] = frameFlit
+				flitCount++
+			} else {
+				isOverflow = true
+			}
+			if flitCount == 2 && headerFlit.Data[0] == SmiMemWriteReq &&
+				headerFlit.Data[1]&MemOptByteStrobes != 0 {
+
+				// Skip the strobe mask, using the length from the second
+				// header flit.
+				writeLength := uint16(frameFlit.Data[4]) | (uint16(frameFlit.Data[5]) << 8)
+				payloadOffset := 14 + (writeLength+7)/8
+				payloadFlit = uint8(payloadOffset / 8)
+				payloadByte = uint8(payloadOffset % 8)
+			}
+			if flitCount-1 == payloadFlit && !isOverflow &&
+				(frameFlit.Eofc == 0 || frameFlit.Eofc > payloadByte) {
+				isMatch = frameFlit.Data[payloadByte] == matchValue
+			}
+			moreFlits = frameFlit.Eofc == 0
+		}
+
+		// Forward matching frames.
+		if isMatch && !isOverflow {
+			for i := uint8(0); i != flitCount; i++ {
+				smiOutput <- frameBuffer[i]
+			}
+		}
+	}
+}
+
+//
+// FilterFrames64 is a goroutine which routes each Flit64 based SMI frame from
+// its input to either the pass output or the drop output, using the supplied
+// predicate. The predicate is evaluated once per frame on the header flit
+// only, so the decision can be made as soon as the header arrives and the
+// frame is streamed through without store and forward buffering. Frames for
+// which the predicate returns true are sent to the pass output and all other
+// frames are sent to the drop output, with whole frames always being routed
+// to the same output. This may be used to isolate traffic by frame type or
+// by tag, since the tag identifies the originating port after arbitration.
+// It is intended for simulation, since function values may not be supported
+// by the FPGA compiler.
+//
+func FilterFrames64(
+	smiInput <-chan Flit64,
+	passOutput chan<- Flit64,
+	dropOutput chan<- Flit64,
+	predicate func(headerFlit Flit64) bool) {
+
+	for {
+		headerFlit := <-smiInput
+		smiOutput := dropOutput
+		if predicate(headerFlit) {
+			smiOutput = passOutput
+		}
+
+		// Forward the remainder of the frame to the selected output.
+		frameFlit := headerFlit
+		smiOutput <- frameFlit
+		for frameFlit.Eofc == 0 {
+			frameFlit = <-smiInput
+			smiOutput <- frameFlit
+		}
+	}
+}
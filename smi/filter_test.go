@@ -0,0 +1,55 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterFrames64Partition(t *testing.T) {
+	smiInput := make(chan Flit64)
+	passOutput := make(chan Flit64, 64)
+	dropOutput := make(chan Flit64, 64)
+	predicateCalls := 0
+	go FilterFrames64(smiInput, passOutput, dropOutput, func(headerFlit Flit64) bool {
+		predicateCalls++
+		return headerFlit.Data[0] == SmiMemWriteReq
+	})
+
+	frames := [][]Flit64{
+		WriteReqFrames64(0x00, make([]byte, 40), nil, 0),
+		ReadReqFrames64(0x40, 64, 1),
+		ReadReqFrames64(0x80, 8, 2),
+		WriteReqFrames64(0xC0, []byte{1, 2, 3}, nil, 3),
+		WriteReqFrames64(0x100, make([]byte, 100), make([]byte, 13), 4),
+		ReadReqFrames64(0x200, 16, 5),
+	}
+	go func() {
+		for _, frame := range frames {
+			sendFrame64(smiInput, frame)
+		}
+	}()
+
+	// Writes are passed and reads are dropped, with each output preserving
+	// the input frame order.
+	for _, frame := range frames {
+		smiOutput := dropOutput
+		if frame[0].Data[0] == SmiMemWriteReq {
+			smiOutput = passOutput
+		}
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("unexpected frame %v", output)
+		}
+	}
+	expectIdle(t, passOutput, 10*time.Millisecond)
+	expectIdle(t, dropOutput, 10*time.Millisecond)
+	if predicateCalls != len(frames) {
+		t.Fatalf("predicate called %d times for %d frames", predicateCalls, len(frames))
+	}
+}
@@ -0,0 +1,76 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//
+// testFrame128 builds a Flit128 frame with the specified number of bytes,
+// with each byte holding its offset in the frame.
+//
+func testFrame128(frameLength int) []Flit128 {
+	frame := make([]Flit128, (frameLength+15)/16)
+	for i := 0; i != frameLength; i++ {
+		frame[i/16].Data[i%16] = uint8(i)
+	}
+	frame[len(frame)-1].Eofc = uint8((frameLength-1)%16 + 1)
+	return frame
+}
+
+func TestForwardAssembleFrame128(t *testing.T) {
+	type frameFunc func(
+		req <-chan bool,
+		smiInput <-chan Flit128,
+		smiOutput chan<- Flit128,
+		done chan<- bool)
+	stages := []struct {
+		name  string
+		stage frameFunc
+	}{
+		{"ForwardFrame128", ForwardFrame128},
+		{"AssembleFrame128", AssembleFrame128},
+	}
+	for _, s := range stages {
+		req := make(chan bool)
+		smiInput := make(chan Flit128)
+		smiOutput := make(chan Flit128)
+		done := make(chan bool)
+		go s.stage(req, smiInput, smiOutput, done)
+
+		// The 37 byte frame has a partial final beat and the 272 byte
+		// frame is a maximum size burst with its header.
+		for _, frameLength := range []int{16, 37, 272} {
+			frame := testFrame128(frameLength)
+			req <- true
+			go func() {
+				for _, inputFlit := range frame {
+					smiInput <- inputFlit
+				}
+			}()
+			output := []Flit128{}
+			moreFlits := true
+			for moreFlits {
+				select {
+				case outputFlit := <-smiOutput:
+					output = append(output, outputFlit)
+					moreFlits = outputFlit.Eofc == 0
+				case <-time.After(testTimeout):
+					t.Fatalf("%s: timed out for length %d", s.name, frameLength)
+				}
+			}
+			if !reflect.DeepEqual(output, frame) {
+				t.Fatalf("%s: unexpected frame %v, expected %v", s.name, output, frame)
+			}
+			<-done
+		}
+		req <- false
+	}
+}
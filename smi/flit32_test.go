@@ -0,0 +1,135 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//
+// testFrame32 builds a Flit32 frame with the specified number of bytes, with
+// each byte holding its offset in the frame.
+//
+func testFrame32(frameLength int) []Flit32 {
+	frame := make([]Flit32, (frameLength+3)/4)
+	for i := 0; i != frameLength; i++ {
+		frame[i/4].Data[i%4] = uint8(i)
+	}
+	frame[len(frame)-1].Eofc = uint8((frameLength-1)%4 + 1)
+	return frame
+}
+
+func TestForwardAssembleFrame32(t *testing.T) {
+	type frameFunc func(
+		req <-chan bool,
+		smiInput <-chan Flit32,
+		smiOutput chan<- Flit32,
+		done chan<- bool)
+	stages := []struct {
+		name  string
+		stage frameFunc
+	}{
+		{"ForwardFrame32", ForwardFrame32},
+		{"AssembleFrame32", AssembleFrame32},
+	}
+	for _, s := range stages {
+		req := make(chan bool)
+		smiInput := make(chan Flit32)
+		smiOutput := make(chan Flit32)
+		done := make(chan bool)
+		go s.stage(req, smiInput, smiOutput, done)
+
+		// The 13 byte frame has a partial final beat and the 270 byte
+		// frame is a maximum size write burst with its header.
+		for _, frameLength := range []int{4, 13, 270} {
+			frame := testFrame32(frameLength)
+			req <- true
+			go func() {
+				for _, inputFlit := range frame {
+					smiInput <- inputFlit
+				}
+			}()
+			output := []Flit32{}
+			moreFlits := true
+			for moreFlits {
+				select {
+				case outputFlit := <-smiOutput:
+					output = append(output, outputFlit)
+					moreFlits = outputFlit.Eofc == 0
+				case <-time.After(testTimeout):
+					t.Fatalf("%s: timed out for length %d", s.name, frameLength)
+				}
+			}
+			if !reflect.DeepEqual(output, frame) {
+				t.Fatalf("%s: unexpected frame %v, expected %v", s.name, output, frame)
+			}
+			<-done
+		}
+		req <- false
+	}
+}
+
+func TestGearbox64To32RoundTrip(t *testing.T) {
+	smiInput := make(chan Flit64)
+	narrowStream := make(chan Flit32, 128)
+	smiOutput := make(chan Flit64, 64)
+	go Gearbox64To32(smiInput, narrowStream)
+	go Gearbox32To64(narrowStream, smiOutput)
+
+	// Every partial final flit length is covered, with the bytes after the
+	// end of the frame set to zero.
+	for _, flitCount := range []int{1, 2, 3, 34} {
+		for lastEofc := 1; lastEofc <= 8; lastEofc++ {
+			frameLength := (flitCount-1)*8 + lastEofc
+			frame := make([]Flit64, flitCount)
+			for i := 0; i != frameLength; i++ {
+				frame[i/8].Data[i%8] = uint8(i + 1)
+			}
+			frame[flitCount-1].Eofc = uint8(lastEofc)
+			go sendFrame64(smiInput, frame)
+			if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+				t.Fatalf("%d flits, eofc %d: unexpected frame %v",
+					flitCount, lastEofc, output)
+			}
+		}
+	}
+}
+
+func TestGearbox64To32Layout(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit32, 16)
+	go Gearbox64To32(smiInput, smiOutput)
+
+	// The 23 byte write request frame maps to six narrow flits, with the
+	// tag bytes in the first flit and a three byte final flit.
+	frame := WriteReqFrames64(0x1000, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, 0x5A)
+	go sendFrame64(smiInput, frame)
+	output := []Flit32{}
+	moreFlits := true
+	for moreFlits {
+		select {
+		case narrowFlit := <-smiOutput:
+			output = append(output, narrowFlit)
+			moreFlits = narrowFlit.Eofc == 0
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for narrow flit")
+		}
+	}
+	frameBytes := unpackFrame64(frame)
+	if len(output) != 6 || output[5].Eofc != 3 || output[0].Data[3] != 0x5A {
+		t.Fatalf("unexpected narrow frame %v", output)
+	}
+	for i, narrowFlit := range output {
+		for j := 0; j != 4 && i*4+j < len(frameBytes); j++ {
+			if narrowFlit.Data[j] != frameBytes[i*4+j] {
+				t.Fatalf("unexpected byte %d in narrow flit %d", j, i)
+			}
+		}
+	}
+}
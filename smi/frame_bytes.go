@@ -0,0 +1,281 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Specify the number of header bytes which precede the payload data in
+// memory access request and response frames. The unexported names are used
+// internally.
+//
+const (
+	SmiMemReqHeaderSize  = 14
+	SmiMemRespHeaderSize = 4
+	smiMemReqHeaderSize  = SmiMemReqHeaderSize
+	smiMemRespHeaderSize = SmiMemRespHeaderSize
+)
+
+//
+// receiveFrame64 reads a complete frame from the input channel, returning
+// the received flits. This is intended for use in simulation components.
+// The flit buffer is taken from the frame pool.
+//
+func receiveFrame64(smiInput <-chan Flit64) []Flit64 {
+	frame := getFrame64()
+	moreFlits := true
+	for moreFlits {
+		inputFlit := <-smiInput
+		frame = append(frame, inputFlit)
+		moreFlits = inputFlit.Eofc == 0
+	}
+	return frame
+}
+
+//
+// AssembleFrame64WithLen reads a complete Flit64 based SMI frame from the
+// input channel, returning the received flits together with the total number
+// of valid bytes in the frame. The byte count includes the frame header and
+// is made up of 8 bytes for each flit prior to the last, plus the number of
+// valid bytes given by the Eofc field of the last flit. This is intended for
+// use in simulation and host side test code.
+//
+func AssembleFrame64WithLen(smiInput <-chan Flit64) ([]Flit64, int) {
+	frame := receiveFrame64(smiInput)
+	frameLength := 8*(len(frame)-1) + frame[len(frame)-1].PayloadLen()
+	return frame, frameLength
+}
+
+//
+// sendFrame64 writes all the flits in a frame to the output channel.
+//
+func sendFrame64(smiOutput chan<- Flit64, frame []Flit64) {
+	for _, outputFlit := range frame {
+		smiOutput <- outputFlit
+	}
+}
+
+//
+// unpackFrame64 extracts the valid bytes from the flits in a frame, using the
+// Eofc field of the final flit to determine the number of valid bytes it
+// contains. The byte buffer is taken from the frame bytes pool.
+//
+func unpackFrame64(frame []Flit64) []byte {
+	frameBytes := getFrameBytes()
+	for _, frameFlit := range frame {
+		frameBytes = append(frameBytes, frameFlit.Data[:frameFlit.PayloadLen()]...)
+	}
+	return frameBytes
+}
+
+//
+// packFrame64 packs a sequence of frame bytes into flits, setting the Eofc
+// field of the final flit to the number of valid bytes it contains. The flit
+// buffer is taken from the frame pool.
+//
+func packFrame64(frameBytes []byte) []Flit64 {
+	flitCount := (len(frameBytes) + 7) / 8
+	frame := getFrame64()
+	for i := 0; i != flitCount; i++ {
+		var frameFlit Flit64
+		copy(frameFlit.Data[:], frameBytes[i*8:])
+		frame = append(frame, frameFlit)
+	}
+	if flitCount != 0 {
+		frame[flitCount-1].Eofc = uint8(len(frameBytes) - (flitCount-1)*8)
+	}
+	return frame
+}
+
+//
+// UnpackFrame64 extracts the valid bytes from the flits in a frame, using the
+// Eofc field of the final flit to determine the number of valid bytes it
+// contains. The returned byte slice is owned by the caller. This is intended
+// for use in simulation and host side test code.
+//
+func UnpackFrame64(frame []Flit64) []byte {
+	return unpackFrame64(frame)
+}
+
+//
+// PackFrame64 packs a sequence of frame bytes into flits, setting the Eofc
+// field of the final flit to the number of valid bytes it contains. The
+// returned flit slice is owned by the caller. This is intended for use in
+// simulation and host side test code.
+//
+func PackFrame64(frameBytes []byte) []Flit64 {
+	return packFrame64(frameBytes)
+}
+
+//
+// headerFlits copies the header bytes from the unpacked bytes of a memory
+// access request frame into a pair of header flits, for use with the header
+// accessor functions.
+//
+func headerFlits(frameBytes []byte) [2]Flit64 {
+	var header [2]Flit64
+	copy(header[0].Data[:], frameBytes[:8])
+	copy(header[1].Data[:], frameBytes[8:smiMemReqHeaderSize])
+	return header
+}
+
+//
+// frameBytesAddr extracts the 64-bit memory address from the unpacked bytes
+// of a memory access request frame.
+//
+func frameBytesAddr(frameBytes []byte) uint64 {
+	return ReadAddr(headerFlits(frameBytes))
+}
+
+//
+// frameBytesLength extracts the 16-bit transfer length from the unpacked
+// bytes of a memory access request frame.
+//
+func frameBytesLength(frameBytes []byte) uint16 {
+	return ReadLength(headerFlits(frameBytes))
+}
+
+//
+// reqHeaderBytes assembles the unpacked header bytes of a memory access
+// request frame.
+//
+func reqHeaderBytes(
+	reqType uint8,
+	reqOptions uint8,
+	tagLower uint8,
+	tagUpper uint8,
+	reqAddr uint64,
+	reqLength uint16) []byte {
+
+	var header [2]Flit64
+	header[0].Data[0] = reqType
+	header[0].Data[1] = reqOptions
+	header[0].Data[2] = tagLower
+	header[0].Data[3] = tagUpper
+	WriteAddr(&header, reqAddr)
+	WriteLength(&header, reqLength)
+	frameBytes := make([]byte, smiMemReqHeaderSize)
+	copy(frameBytes, header[0].Data[:])
+	copy(frameBytes[8:], header[1].Data[:])
+	return frameBytes
+}
+
+//
+// ReqHeaderBytes assembles the unpacked header bytes of a memory access
+// request frame, with the specified frame type, options, tag bytes, address
+// and transfer length. This is intended for use in simulation and host side
+// test code.
+//
+func ReqHeaderBytes(
+	reqType uint8,
+	reqOptions uint8,
+	tagLower uint8,
+	tagUpper uint8,
+	reqAddr uint64,
+	reqLength uint16) []byte {
+
+	return reqHeaderBytes(reqType, reqOptions, tagLower, tagUpper, reqAddr, reqLength)
+}
+
+//
+// writeReqBytes assembles the unpacked bytes of a write request frame.
+//
+func writeReqBytes(
+	writeAddr uint64,
+	writeOptions uint8,
+	tagLower uint8,
+	tagUpper uint8,
+	writeData []byte) []byte {
+
+	frameBytes := reqHeaderBytes(SmiMemWriteReq, writeOptions,
+		tagLower, tagUpper, writeAddr, uint16(len(writeData)))
+	return append(frameBytes, writeData...)
+}
+
+//
+// readReqBytes assembles the unpacked bytes of a read request frame.
+//
+func readReqBytes(
+	readAddr uint64,
+	readOptions uint8,
+	tagLower uint8,
+	tagUpper uint8,
+	readLength uint16) []byte {
+
+	return reqHeaderBytes(SmiMemReadReq, readOptions,
+		tagLower, tagUpper, readAddr, readLength)
+}
+
+//
+// writePayload separates the unpacked bytes of a write request frame into
+// the strobe mask and the write data, using the layout described for
+// SmiMemStrobedBurstSize. The strobe mask is nil if the byte strobe option is
+// not set, in which case the write data is the entire payload. The boolean
+// result is false if the frame is too short to contain the request header or
+// if the strobe mask and write data for a write with byte strobes do not
+// match the length field.
+//
+func writePayload(frameBytes []byte) ([]byte, []byte, bool) {
+	if len(frameBytes) < smiMemReqHeaderSize {
+		return nil, nil, false
+	}
+	payload := frameBytes[smiMemReqHeaderSize:]
+	if frameBytes[1]&MemOptByteStrobes == 0 {
+		return nil, payload, true
+	}
+	writeLength := int(frameBytesLength(frameBytes))
+	strobeCount := (writeLength + 7) / 8
+	if len(payload) != strobeCount+writeLength {
+		return nil, nil, false
+	}
+	return payload[:strobeCount], payload[strobeCount:], true
+}
+
+//
+// strobedWriteReqBytes assembles the unpacked bytes of a write request frame
+// which carries a strobe mask. Each element of the byte enable slice enables
+// the corresponding write data byte, and the byte strobe option is set in
+// the supplied options. If the byte enable slice is nil, the byte strobe
+// option is cleared and a plain write request is assembled instead.
+//
+func strobedWriteReqBytes(
+	writeAddr uint64,
+	writeOptions uint8,
+	tagLower uint8,
+	tagUpper uint8,
+	writeEnables []bool,
+	writeData []byte) []byte {
+
+	if writeEnables == nil {
+		return writeReqBytes(writeAddr, writeOptions&^MemOptByteStrobes,
+			tagLower, tagUpper, writeData)
+	}
+	frameBytes := reqHeaderBytes(SmiMemWriteReq, writeOptions|MemOptByteStrobes,
+		tagLower, tagUpper, writeAddr, uint16(len(writeData)))
+	strobes := make([]byte, (len(writeData)+7)/8)
+	for i, isEnabled := range writeEnables {
+		if isEnabled {
+			strobes[i/8] |= 1 << uint(i%8)
+		}
+	}
+	frameBytes = append(frameBytes, strobes...)
+	return append(frameBytes, writeData...)
+}
+
+//
+// writeEnables expands a strobe mask into a slice of per-byte write enables
+// for the specified number of write data bytes. A nil strobe mask gives a
+// nil slice, indicating that all bytes are enabled.
+//
+func writeEnables(strobes []byte, writeLength int) []bool {
+	if strobes == nil {
+		return nil
+	}
+	enables := make([]bool, writeLength)
+	for i := range enables {
+		enables[i] = strobes[i/8]&(1<<uint(i%8)) != 0
+	}
+	return enables
+}
@@ -0,0 +1,39 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssembleFrame64WithLen(t *testing.T) {
+	smiInput := make(chan Flit64)
+
+	// The lengths cover every final flit Eofc value, single flit frames and
+	// a maximum size write burst with its header.
+	frameLengths := []int{1, 4, 8, 9, 12, 15, 16, 21, 270}
+	for _, frameLength := range frameLengths {
+		frameBytes := make([]byte, frameLength)
+		for i := range frameBytes {
+			frameBytes[i] = uint8(i)
+		}
+		frame := packFrame64(frameBytes)
+		go sendFrame64(smiInput, frame)
+		output, outputLength := AssembleFrame64WithLen(smiInput)
+		if outputLength != frameLength || !reflect.DeepEqual(output, frame) {
+			t.Fatalf("length %d: reported length %d for frame %v",
+				frameLength, outputLength, output)
+		}
+	}
+
+	// The reported length for a request frame covers its header.
+	go sendFrame64(smiInput, WriteReqFrames64(0, make([]byte, 100), nil, 0))
+	if _, outputLength := AssembleFrame64WithLen(smiInput); outputLength != smiMemReqHeaderSize+100 {
+		t.Fatalf("unexpected write request length %d", outputLength)
+	}
+}
@@ -0,0 +1,89 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// The host side simulation helpers draw their per-frame flit and byte
+// buffers from a pair of shared pools, which avoids allocating new buffers
+// for every frame in long running simulations. Buffers obtained from the
+// pools are owned by the caller in the same way as any other slice, and are
+// only returned to the pools by code which knows that no other references
+// to them remain. Once a buffer has been returned it must not be accessed
+// again, since it may be handed out for use by another frame at any time.
+// Buffers which are never returned are simply garbage collected, so using
+// the pools does not alter any functional results. Each pool is a bounded
+// free list implemented as a buffered channel, rather than a sync.Pool,
+// since storing slices in a sync.Pool allocates on every release. Buffers
+// which are released when the free list is full are garbage collected.
+//
+var framePool = make(chan []Flit64, 16)
+var frameBytesPool = make(chan []byte, 16)
+
+//
+// getFrame64 obtains an empty flit buffer from the frame pool, with capacity
+// for at least one maximum size frame.
+//
+func getFrame64() []Flit64 {
+	select {
+	case frame := <-framePool:
+		return frame[:0]
+	default:
+		return make([]Flit64, 0, SmiMemFrame64Size)
+	}
+}
+
+//
+// putFrame64 returns a flit buffer to the frame pool. The buffer must not be
+// accessed after it has been returned. Buffers which are too small to hold a
+// maximum size frame are left for garbage collection.
+//
+func putFrame64(frame []Flit64) {
+	if cap(frame) >= SmiMemFrame64Size {
+		select {
+		case framePool <- frame:
+		default:
+		}
+	}
+}
+
+//
+// getFrameBytes obtains an empty byte buffer from the frame bytes pool, with
+// capacity for at least one maximum size frame.
+//
+func getFrameBytes() []byte {
+	select {
+	case frameBytes := <-frameBytesPool:
+		return frameBytes[:0]
+	default:
+		return make([]byte, 0, SmiMemFrame64Size*8)
+	}
+}
+
+//
+// putFrameBytes returns a byte buffer to the frame bytes pool. The buffer
+// must not be accessed after it has been returned. Buffers which are too
+// small to hold a maximum size frame are left for garbage collection.
+//
+func putFrameBytes(frameBytes []byte) {
+	if cap(frameBytes) >= SmiMemFrame64Size*8 {
+		select {
+		case frameBytesPool <- frameBytes:
+		default:
+		}
+	}
+}
+
+//
+// ReleaseReadData returns the buffer holding the read data from
+// ParseReadResp64 to the shared buffer pool once the caller has finished
+// with it. The read data slice, and any slices derived from it, must not be
+// accessed after it has been released. Releasing the read data is optional,
+// since buffers which are not released are garbage collected as normal.
+//
+func ReleaseReadData(readData []byte) {
+	putFrameBytes(readData)
+}
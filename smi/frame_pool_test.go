@@ -0,0 +1,125 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFramePoolReleasedReadData(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 4096)
+	for i := range backing {
+		backing[i] = uint8(i * 13)
+	}
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// Released buffers are reused for later reads without corrupting the
+	// read data which is still held by the caller.
+	held := [][]byte{}
+	for i := 0; i != 32; i++ {
+		readAddr := uint64(i * 100)
+		sendFrame64(smiRequest, ReadReqFrames64(readAddr, 100, uint8(i)))
+		readData, tag, err := ParseReadResp64(smiResponse)
+		if err != nil || tag != uint8(i) || !bytes.Equal(readData, backing[readAddr:readAddr+100]) {
+			t.Fatalf("read %d: unexpected response %v %d %v", i, readData, tag, err)
+		}
+		if i%2 == 0 {
+			ReleaseReadData(readData)
+		} else {
+			held = append(held, readData)
+		}
+	}
+	for i, readData := range held {
+		readAddr := uint64((2*i + 1) * 100)
+		if !bytes.Equal(readData, backing[readAddr:readAddr+100]) {
+			t.Fatalf("held read data %d was overwritten", i)
+		}
+	}
+}
+
+//
+// benchmarkMemoryModelRead issues maximum size reads to the memory model,
+// optionally releasing the read data after each read.
+//
+func benchmarkMemoryModelRead(b *testing.B, release bool) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	go MemoryModel64(smiRequest, smiResponse, make([]byte, 4096))
+	reqFrame := ReadReqFrames64(0, SmiMemBurstSize, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sendFrame64(smiRequest, reqFrame)
+		readData, _, err := ParseReadResp64(smiResponse)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if release {
+			ReleaseReadData(readData)
+		}
+	}
+}
+
+func BenchmarkMemoryModelReadUnreleased(b *testing.B) {
+	benchmarkMemoryModelRead(b, false)
+}
+
+func BenchmarkMemoryModelReadReleased(b *testing.B) {
+	benchmarkMemoryModelRead(b, true)
+}
+
+//
+// frameBufferSink holds the most recent buffers in the frame buffer
+// benchmarks, so that the allocations can not be optimised away.
+//
+var frameBufferSink struct {
+	frame      []Flit64
+	frameBytes []byte
+}
+
+//
+// benchmarkFrameBuffers obtains and fills a maximum size flit buffer and byte
+// buffer for each iteration, either allocating them directly with make as
+// the simulation helpers did before pooling or drawing them from the pools.
+//
+func benchmarkFrameBuffers(b *testing.B, pooled bool) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var frame []Flit64
+		var frameBytes []byte
+		if pooled {
+			frame = getFrame64()
+			frameBytes = getFrameBytes()
+		} else {
+			frame = make([]Flit64, 0, SmiMemFrame64Size)
+			frameBytes = make([]byte, 0, SmiMemFrame64Size*8)
+		}
+		for j := 0; j != SmiMemFrame64Size; j++ {
+			frame = append(frame, Flit64{Data: [8]uint8{uint8(j)}})
+			frameBytes = append(frameBytes, uint8(j))
+		}
+		frameBufferSink.frame = frame
+		frameBufferSink.frameBytes = frameBytes
+		if pooled {
+			putFrame64(frame)
+			putFrameBytes(frameBytes)
+		}
+	}
+}
+
+func BenchmarkFrameBuffersMake(b *testing.B) {
+	benchmarkFrameBuffers(b, false)
+}
+
+func BenchmarkFrameBuffersPooled(b *testing.B) {
+	benchmarkFrameBuffers(b, true)
+}
@@ -0,0 +1,88 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"time"
+)
+
+//
+// Type Stage64 specifies the common form of a Flit64 based processing stage
+// with a single input and a single output channel. Stages which have
+// additional ports, such as error reporting channels, can be adapted to this
+// form using a closure.
+//
+type Stage64 func(smiInput <-chan Flit64, smiOutput chan<- Flit64)
+
+//
+// FlitsFromFuzz converts an arbitrary byte slice, as supplied by go-fuzz or a
+// testing.F fuzz target, into a sequence of flits. Each group of nine input
+// bytes is mapped to a flit, with the first eight bytes being used as the
+// flit data and the ninth byte being reduced modulo 9 to give a valid Eofc
+// value. Any trailing partial group is zero padded. Since the flit data and
+// end of frame markers are unconstrained, this will generate truncated
+// frames, unknown frame types and oversized frames as well as valid ones.
+//
+func FlitsFromFuzz(fuzzData []byte) []Flit64 {
+	flitCount := (len(fuzzData) + 8) / 9
+	flits := make([]Flit64, flitCount)
+	for i := range fuzzData {
+		flitIndex := i / 9
+		byteIndex := i % 9
+		if byteIndex == 8 {
+			flits[flitIndex].Eofc = fuzzData[i] % 9
+		} else {
+			flits[flitIndex].Data[byteIndex] = fuzzData[i]
+		}
+	}
+	return flits
+}
+
+//
+// FuzzStage64 runs a new instance of the specified stage and feeds it with
+// the sequence of flits generated from the fuzz data. All the flits emitted
+// on the stage output are collected and returned. The boolean result
+// indicates whether the stage accepted all of the input flits without
+// stalling for longer than the specified timeout, which is also used as the
+// idle period for detecting the end of the stage output. A false result
+// indicates a probable deadlock. Panics in the stage are not recovered, so
+// that they are reported directly by the fuzzer. Since stage goroutines run
+// indefinitely, each call leaks the stage instance it creates.
+//
+func FuzzStage64(
+	fuzzData []byte,
+	stage Stage64,
+	timeout time.Duration) ([]Flit64, bool) {
+
+	inputFlits := FlitsFromFuzz(fuzzData)
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64)
+	go stage(smiInput, smiOutput)
+
+	// Feed the input flits to the stage while collecting its output.
+	var outputFlits []Flit64
+	for inputIndex := 0; inputIndex != len(inputFlits); {
+		select {
+		case smiInput <- inputFlits[inputIndex]:
+			inputIndex++
+		case outputFlit := <-smiOutput:
+			outputFlits = append(outputFlits, outputFlit)
+		case <-time.After(timeout):
+			return outputFlits, false
+		}
+	}
+
+	// Collect any remaining output until the stage goes idle.
+	for {
+		select {
+		case outputFlit := <-smiOutput:
+			outputFlits = append(outputFlits, outputFlit)
+		case <-time.After(timeout):
+			return outputFlits, true
+		}
+	}
+}
@@ -0,0 +1,101 @@
+//go:build go1.18
+// +build go1.18
+
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//
+// The fuzz targets use the native fuzzing support added in Go 1.18, so they
+// are excluded from builds using earlier toolchains.
+//
+
+//
+// addFuzzSeeds adds a set of valid and malformed frames as fuzz seed inputs.
+//
+func addFuzzSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(append(unpackFlits(ReadReqFrames64(0x100, 8, 1)), 0xFF))
+	f.Add(unpackFlits(WriteReqFrames64(0x100, make([]byte, 40), nil, 2)))
+	f.Add(unpackFlits(packFrame64([]byte{SmiMemReadResp, 0, 0, 3, 1, 2, 3, 4})))
+}
+
+//
+// completeFrameBytes returns the unpacked bytes of each complete frame in a
+// sequence of flits, ignoring any trailing partial frame.
+//
+func completeFrameBytes(flits []Flit64) [][]byte {
+	frames := [][]byte{}
+	frameStart := 0
+	for i, frameFlit := range flits {
+		if frameFlit.Eofc != 0 {
+			frames = append(frames, append([]byte{}, unpackFrame64(flits[frameStart:i+1])...))
+			frameStart = i + 1
+		}
+	}
+	return frames
+}
+
+func FuzzValidateFrames64(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, fuzzData []byte) {
+		outputFlits, ok := FuzzStage64(fuzzData, func(smiInput <-chan Flit64, smiOutput chan<- Flit64) {
+			ValidateFrames64(smiInput, smiOutput, nil)
+		}, 5*time.Millisecond)
+		if !ok {
+			t.Fatal("ValidateFrames64 stalled")
+		}
+
+		// Only complete well formed frames are forwarded.
+		frame := []Flit64{}
+		for _, outputFlit := range outputFlits {
+			frame = append(frame, outputFlit)
+			if outputFlit.Eofc != 0 {
+				if isValid, reason := WellFormed(frame); !isValid {
+					t.Fatalf("malformed frame forwarded: %s", reason)
+				}
+				frame = frame[:0]
+			}
+		}
+		if len(frame) != 0 {
+			t.Fatalf("incomplete frame forwarded %v", frame)
+		}
+	})
+}
+
+func FuzzCheckCrc64(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, fuzzData []byte) {
+		_, ok := FuzzStage64(fuzzData, func(smiInput <-chan Flit64, smiOutput chan<- Flit64) {
+			CheckCrc64(smiInput, smiOutput, nil)
+		}, 5*time.Millisecond)
+		if !ok {
+			t.Fatal("CheckCrc64 stalled")
+		}
+
+		// Frames which pass through AppendCrc64 are always accepted.
+		protected, ok := FuzzStage64(fuzzData, AppendCrc64, 5*time.Millisecond)
+		if !ok {
+			t.Fatal("AppendCrc64 stalled")
+		}
+		checked, ok := FuzzStage64(unpackFlits(protected), func(smiInput <-chan Flit64, smiOutput chan<- Flit64) {
+			CheckCrc64(smiInput, smiOutput, nil)
+		}, 5*time.Millisecond)
+		if !ok {
+			t.Fatal("CheckCrc64 stalled on protected frames")
+		}
+		if !reflect.DeepEqual(completeFrameBytes(checked), completeFrameBytes(FlitsFromFuzz(fuzzData))) {
+			t.Fatal("protected frames altered or rejected")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFlitsFromFuzz(t *testing.T) {
+	fuzzData := []byte{1, 2, 3, 4, 5, 6, 7, 8, 12, 9, 10}
+	expected := []Flit64{
+		{Data: [8]uint8{1, 2, 3, 4, 5, 6, 7, 8}, Eofc: 3},
+		{Data: [8]uint8{9, 10}, Eofc: 0},
+	}
+	if flits := FlitsFromFuzz(fuzzData); !reflect.DeepEqual(flits, expected) {
+		t.Fatalf("unexpected flits %v", flits)
+	}
+	if flits := FlitsFromFuzz(nil); len(flits) != 0 {
+		t.Fatalf("unexpected flits %v", flits)
+	}
+}
+
+func TestFuzzStageForward(t *testing.T) {
+	forward := func(smiInput <-chan Flit64, smiOutput chan<- Flit64) {
+		for {
+			smiOutput <- <-smiInput
+		}
+	}
+	fuzzData := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 8}
+	outputFlits, ok := FuzzStage64(fuzzData, forward, 10*time.Millisecond)
+	if !ok || !reflect.DeepEqual(outputFlits, FlitsFromFuzz(fuzzData)) {
+		t.Fatalf("unexpected output %v", outputFlits)
+	}
+
+	// A stage which never reads its input is reported as stalled.
+	stalled := func(smiInput <-chan Flit64, smiOutput chan<- Flit64) {}
+	if _, ok := FuzzStage64(fuzzData, stalled, 10*time.Millisecond); ok {
+		t.Fatal("stalled stage not detected")
+	}
+}
+
+//
+// unpackFlits converts flits back to the fuzz input format used by
+// FlitsFromFuzz, so that valid frames can be used as seed inputs.
+//
+func unpackFlits(flits []Flit64) []byte {
+	fuzzData := []byte{}
+	for _, inputFlit := range flits {
+		fuzzData = append(append(fuzzData, inputFlit.Data[:]...), inputFlit.Eofc)
+	}
+	return fuzzData
+}
@@ -0,0 +1,68 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// GaugeOutstanding64 is a goroutine which forwards SMI request and response
+// frames unchanged, tracking the number of transactions which are currently
+// in flight. It is inserted between an SMI master and the upstream port of
+// an arbiter, so that the master can adapt its request rate to the current
+// transaction depth. The count is incremented when a request header flit is
+// received, before it is forwarded, and decremented when a response header
+// flit is received. The new count is sent on the gauge channel after every
+// change. This is a non-blocking send, so intermediate values will be
+// discarded if the gauge channel is not being serviced, but the count itself
+// is never lost. Unlike StatsTap64, this reports the instantaneous depth
+// rather than the time taken by each transaction.
+//
+func GaugeOutstanding64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	gauge chan<- int) {
+
+	var countLock sync.Mutex
+	outstandingCount := 0
+	updateCount := func(countDelta int) {
+		countLock.Lock()
+		outstandingCount += countDelta
+		select {
+		case gauge <- outstandingCount:
+		default:
+		}
+		countLock.Unlock()
+	}
+
+	// Start goroutine for counting issued requests.
+	go func() {
+		isHeaderFlit := true
+		for {
+			reqFlit := <-upstreamRequest
+			if isHeaderFlit {
+				updateCount(1)
+			}
+			downstreamRequest <- reqFlit
+			isHeaderFlit = reqFlit.Eofc != 0
+		}
+	}()
+
+	// Count returned responses.
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			updateCount(-1)
+		}
+		upstreamResponse <- respFlit
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,59 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGaugeOutstanding64(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	gauge := make(chan int, 16)
+	go GaugeOutstanding64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, gauge)
+
+	expectGauge := func(expectCount int) {
+		select {
+		case count := <-gauge:
+			if count != expectCount {
+				t.Fatalf("gauge reported %d, expected %d", count, expectCount)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for gauge")
+		}
+	}
+
+	// Issue three multi-flit requests without responses.
+	for i := 0; i != 3; i++ {
+		go sendFrame64(upstreamRequest, WriteReqFrames64(0, make([]byte, 20), nil, uint8(i)))
+		recvFrame(t, downstreamRequest)
+		expectGauge(i + 1)
+	}
+	select {
+	case count := <-gauge:
+		t.Fatalf("unexpected gauge update %d", count)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// The gauge drops as each response returns, including multi-flit read
+	// responses.
+	sendFrame(t, downstreamResponse, packFrame64([]byte{SmiMemReadResp, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}))
+	expectGauge(2)
+	for i := 1; i != 3; i++ {
+		sendFrame(t, downstreamResponse, []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 0, uint8(i)}, Eofc: 4}})
+		expectGauge(2 - i)
+	}
+	for i := 0; i != 3; i++ {
+		if respFrame := recvFrame(t, upstreamResponse); respFrame[0].Data[3] != uint8(i) {
+			t.Fatalf("unexpected response %v", respFrame)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Gearbox64To128 is a goroutine which converts a stream of Flit64 based SMI
+// frames to Flit128 based SMI frames. Each pair of consecutive input flits in
+// a frame is packed into a single output flit, with the first input flit
+// occupying bytes 0 to 7 and the second input flit occupying bytes 8 to 15.
+// The frame header bytes therefore retain their original byte positions, so
+// the tag bytes 2 and 3 can still be used for arbitration downstream. Frames
+// with an odd number of input flits are completed using a final output flit
+// which only contains the data from the last input flit.
+//
+func Gearbox64To128(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit128) {
+
+	for {
+		var outputFlit Flit128
+		lowerFlit := <-smiInput
+		copy(outputFlit.Data[0:8], lowerFlit.Data[:])
+		outputFlit.Eofc = lowerFlit.Eofc
+
+		// Pack the second input flit if the frame continues.
+		if lowerFlit.Eofc == 0 {
+			upperFlit := <-smiInput
+			copy(outputFlit.Data[8:16], upperFlit.Data[:])
+			if upperFlit.Eofc != 0 {
+				outputFlit.Eofc = upperFlit.Eofc + 8
+			}
+		}
+		smiOutput <- outputFlit
+	}
+}
+
+//
+// Gearbox128To64 is a goroutine which converts a stream of Flit128 based SMI
+// frames to Flit64 based SMI frames. This is the inverse of Gearbox64To128,
+// with each input flit being split into two output flits. The upper output
+// flit is omitted for the last flit in a frame if it contains no valid data.
+//
+func Gearbox128To64(
+	smiInput <-chan Flit128,
+	smiOutput chan<- Flit64) {
+
+	for {
+		inputFlit := <-smiInput
+		var lowerFlit Flit64
+		copy(lowerFlit.Data[:], inputFlit.Data[0:8])
+
+		// Output a single flit if all the valid data is in the lower half.
+		if inputFlit.Eofc != 0 && inputFlit.Eofc <= 8 {
+			lowerFlit.Eofc = inputFlit.Eofc
+			smiOutput <- lowerFlit
+		} else {
+			var upperFlit Flit64
+			copy(upperFlit.Data[:], inputFlit.Data[8:16])
+			if inputFlit.Eofc != 0 {
+				upperFlit.Eofc = inputFlit.Eofc - 8
+			}
+			smiOutput <- lowerFlit
+			smiOutput <- upperFlit
+		}
+	}
+}
+
+//
+// Gearbox32To64 is a goroutine which converts a stream of Flit32 based SMI
+// frames to Flit64 based SMI frames. Each pair of consecutive input flits in
+// a frame is packed into a single output flit, with the first input flit
+// occupying bytes 0 to 3 and the second input flit occupying bytes 4 to 7.
+// The frame header bytes therefore retain their original byte positions, so
+// the tag bytes 2 and 3 can still be used for arbitration downstream. Frames
+// with an odd number of input flits are completed using a final output flit
+// which only contains the data from the last input flit.
+//
+func Gearbox32To64(
+	smiInput <-chan Flit32,
+	smiOutput chan<- Flit64) {
+
+	for {
+		var outputFlit Flit64
+		lowerFlit := <-smiInput
+		copy(outputFlit.Data[0:4], lowerFlit.Data[:])
+		outputFlit.Eofc = lowerFlit.Eofc
+
+		// Pack the second input flit if the frame continues.
+		if lowerFlit.Eofc == 0 {
+			upperFlit := <-smiInput
+			copy(outputFlit.Data[4:8], upperFlit.Data[:])
+			if upperFlit.Eofc != 0 {
+				outputFlit.Eofc = upperFlit.Eofc + 4
+			}
+		}
+		smiOutput <- outputFlit
+	}
+}
+
+//
+// Gearbox64To32 is a goroutine which converts a stream of Flit64 based SMI
+// frames to Flit32 based SMI frames. This is the inverse of Gearbox32To64,
+// with each input flit being split into two output flits. The upper output
+// flit is omitted for the last flit in a frame if it contains no valid data.
+//
+func Gearbox64To32(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit32) {
+
+	for {
+		inputFlit := <-smiInput
+		var lowerFlit Flit32
+		copy(lowerFlit.Data[:], inputFlit.Data[0:4])
+
+		// Output a single flit if all the valid data is in the lower half.
+		if inputFlit.Eofc != 0 && inputFlit.Eofc <= 4 {
+			lowerFlit.Eofc = inputFlit.Eofc
+			smiOutput <- lowerFlit
+		} else {
+			var upperFlit Flit32
+			copy(upperFlit.Data[:], inputFlit.Data[4:8])
+			if inputFlit.Eofc != 0 {
+				upperFlit.Eofc = inputFlit.Eofc - 4
+			}
+			smiOutput <- lowerFlit
+			smiOutput <- upperFlit
+		}
+	}
+}
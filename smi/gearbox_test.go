@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGearbox64To128RoundTrip(t *testing.T) {
+	smiInput := make(chan Flit64)
+	wideStream := make(chan Flit128, 32)
+	smiOutput := make(chan Flit64, 64)
+	go Gearbox64To128(smiInput, wideStream)
+	go Gearbox128To64(wideStream, smiOutput)
+
+	for _, flitCount := range []int{1, 2, 3, 34} {
+		for _, lastEofc := range []uint8{8, 3} {
+			frame := make([]Flit64, flitCount)
+			for i := range frame {
+				for j := range frame[i].Data {
+					frame[i].Data[j] = uint8(i*8 + j)
+				}
+			}
+			frame[flitCount-1].Eofc = lastEofc
+			go sendFrame64(smiInput, frame)
+			if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+				t.Fatalf("%d flits, eofc %d: unexpected frame %v",
+					flitCount, lastEofc, output)
+			}
+		}
+	}
+}
+
+func TestGearbox64To128Layout(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit128, 4)
+	go Gearbox64To128(smiInput, smiOutput)
+
+	// The tag bytes keep their positions and odd length frames end with a
+	// half filled beat.
+	frame := WriteReqFrames64(0x1000, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, 0x5A)
+	go sendFrame64(smiInput, frame)
+	for i := 0; i != 2; i++ {
+		wideFlit := <-smiOutput
+		var lower, upper [8]uint8
+		copy(lower[:], wideFlit.Data[0:8])
+		copy(upper[:], wideFlit.Data[8:16])
+		switch i {
+		case 0:
+			if lower != frame[0].Data || upper != frame[1].Data || wideFlit.Eofc != 0 ||
+				wideFlit.Data[3] != 0x5A {
+				t.Fatalf("unexpected first beat %v", wideFlit)
+			}
+		default:
+			if lower != frame[2].Data || wideFlit.Eofc != frame[2].Eofc {
+				t.Fatalf("unexpected final beat %v", wideFlit)
+			}
+		}
+	}
+}
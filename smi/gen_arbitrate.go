@@ -0,0 +1,90 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+// +build ignore
+
+//
+// Gen_arbitrate generates the wide SMI arbiters from the arbitrate.go.tmpl
+// template and the fixed depth SMI arbiters from the arbitrate_depth.go.tmpl
+// template. It is run using 'go generate' from the smi package directory.
+//
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"text/template"
+)
+
+//
+// Specify the range of arbiter widths to be generated. The narrower arbiters
+// ArbitrateX2 to ArbitrateX4 are maintained by hand in protocol.go.
+//
+const (
+	minPortCount = 5
+	maxPortCount = 8
+)
+
+//
+// Specify the in-flight transaction limits for which fixed depth arbiters are
+// generated. The default limit is given by SmiMemInFlightLimit.
+//
+var inFlightDepths = []int{1, 2, 8, 16}
+
+var portCountNames = map[int]string{
+	5: "five",
+	6: "six",
+	7: "seven",
+	8: "eight"}
+
+type arbiterPort struct {
+	Letter string
+	Id     int
+	IsLast bool
+}
+
+type arbiter struct {
+	Count int
+	Name  string
+	Ports []arbiterPort
+}
+
+func generate(templateFile string, outputFile string, templateData interface{}) {
+	tmpl, err := template.ParseFiles(templateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, templateData)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(outputFile, buf.Bytes(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	arbiters := []arbiter{}
+	for portCount := minPortCount; portCount <= maxPortCount; portCount++ {
+		ports := make([]arbiterPort, portCount)
+		for i := range ports {
+			ports[i] = arbiterPort{
+				Letter: string(rune('A' + i)),
+				Id:     i + 1,
+				IsLast: i == portCount-1}
+		}
+		arbiters = append(arbiters, arbiter{
+			Count: portCount,
+			Name:  portCountNames[portCount],
+			Ports: ports})
+	}
+	generate("arbitrate.go.tmpl", "arbitrate_wide.go", arbiters)
+	generate("arbitrate_depth.go.tmpl", "arbitrate_depth.go", inFlightDepths)
+}
@@ -0,0 +1,194 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Specify the address granularity used for write hazard detection as an
+// integer number of bytes. Requests may start at any address and may span
+// several blocks, so each request is checked against every block it
+// overlaps.
+//
+const SmiMemHazardBlockSize = SmiMemBurstSize
+
+//
+// OrderWrites64 is a goroutine which detects write-after-write hazards on a
+// single SMI request/response channel pair. It tracks the range of address
+// blocks covered by each outstanding write request, and any new write request
+// which overlaps an address block that already has a write outstanding is
+// held until the response for the earlier write has been received. Read
+// requests which overlap an outstanding write are held in the same way, so
+// read-after-write hazards are also avoided. Writes to unrelated address
+// blocks may be outstanding concurrently, up to the in-flight limit.
+//
+// Hazards are detected at the granularity of SmiMemHazardBlockSize aligned
+// address blocks, so accesses to different bytes in the same block will also
+// be serialized. Request frames are forwarded in order, so a held write will
+// also hold back any subsequent requests from the same upstream port. Write
+// response frames are matched to their requests using the tag bytes, so each
+// outstanding write must have a unique tag. Error response frames which
+// match the tag of an outstanding write also complete that write. Read
+// requests are not tracked once they have been forwarded.
+//
+func OrderWrites64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Completed write tags are passed back to the request handler.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	writeDone := make(chan uint16, 4)
+	writeIssued := make(chan uint16, 4)
+
+	// Start goroutine for request hazard detection.
+	go func() {
+		var writeValid [4]bool
+		var writeTags [4]uint16
+		var writeFirstBlocks [4]uint64
+		var writeLastBlocks [4]uint64
+
+		for {
+			headerFlit1 := <-upstreamRequest
+			headerFlit2 := headerFlit1
+			if headerFlit1.Eofc == 0 {
+				headerFlit2 = <-upstreamRequest
+			}
+
+			// Check read and write requests for address hazards, waiting for
+			// write completions until there are no hazards and, for writes,
+			// a free table entry. The block range is inclusive, with zero
+			// length requests being treated as accessing a single byte.
+			isWrite := headerFlit1.Data[0] == SmiMemWriteReq
+			if isWrite || headerFlit1.Data[0] == SmiMemReadReq {
+				reqTag := uint16(headerFlit1.Data[2]) | (uint16(headerFlit1.Data[3]) << 8)
+				reqAddr := frameAddr(headerFlit1, headerFlit2)
+				reqLength := uint64(ReadLength([2]Flit64{headerFlit1, headerFlit2}))
+				if reqLength == 0 {
+					reqLength = 1
+				}
+				firstBlock := reqAddr / SmiMemHazardBlockSize
+				lastBlock := (reqAddr + reqLength - 1) / SmiMemHazardBlockSize
+				freeEntry := 0
+				isBlocked := true
+				for isBlocked {
+
+					// Retire all pending write completions.
+					moreDone := true
+					for moreDone {
+						select {
+						case doneTag := <-writeDone:
+							for i := 0; i != 4; i++ {
+								if writeValid[i] && writeTags[i] == doneTag {
+									writeValid[i] = false
+								}
+							}
+						default:
+							moreDone = false
+						}
+					}
+
+					// Check for hazards and locate a free table entry.
+					isHazard := false
+					freeEntry = -1
+					for i := 0; i != 4; i++ {
+						if writeValid[i] {
+							isHazard = isHazard || (writeFirstBlocks[i] <= lastBlock &&
+								firstBlock <= writeLastBlocks[i])
+						} else {
+							freeEntry = i
+						}
+					}
+					isBlocked = isHazard || (isWrite && freeEntry < 0)
+
+					// Wait for the next write completion if blocked.
+					if isBlocked {
+						doneTag := <-writeDone
+						for i := 0; i != 4; i++ {
+							if writeValid[i] && writeTags[i] == doneTag {
+								writeValid[i] = false
+							}
+						}
+					}
+				}
+				if isWrite {
+					writeValid[freeEntry] = true
+					writeTags[freeEntry] = reqTag
+					writeFirstBlocks[freeEntry] = firstBlock
+					writeLastBlocks[freeEntry] = lastBlock
+					writeIssued <- reqTag
+				}
+			}
+
+			// Forward the request frame.
+			downstreamRequest <- headerFlit1
+			moreFlits := headerFlit1.Eofc == 0
+			if moreFlits {
+				downstreamRequest <- headerFlit2
+				moreFlits = headerFlit2.Eofc == 0
+			}
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				downstreamRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Forward responses, notifying the request handler of write completions.
+	// The tags of the issued writes are queued before the write requests are
+	// forwarded, so they are always available by the time the corresponding
+	// responses are received. This allows error responses for writes to be
+	// distinguished from error responses for reads. There are never more
+	// writes outstanding than there are table entries.
+	var issuedValid [4]bool
+	var issuedTags [4]uint16
+	for {
+		headerFlit := <-downstreamResponse
+		moreIssued := true
+		for moreIssued {
+			select {
+			case issuedTag := <-writeIssued:
+				for i := 0; i != 4; i++ {
+					if !issuedValid[i] {
+						issuedValid[i] = true
+						issuedTags[i] = issuedTag
+						break
+					}
+				}
+			default:
+				moreIssued = false
+			}
+		}
+		upstreamResponse <- headerFlit
+		if headerFlit.Data[0] == SmiMemWriteResp || headerFlit.Data[0] == SmiMemErrorResp {
+			respTag := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+			for i := 0; i != 4; i++ {
+				if issuedValid[i] && issuedTags[i] == respTag {
+					issuedValid[i] = false
+					writeDone <- respTag
+					break
+				}
+			}
+		}
+
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-downstreamResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
+
+//
+// frameAddr extracts the 64-bit memory address from the first two flits of
+// an SMI memory access request frame.
+//
+func frameAddr(headerFlit1 Flit64, headerFlit2 Flit64) uint64 {
+	return ReadAddr([2]Flit64{headerFlit1, headerFlit2})
+}
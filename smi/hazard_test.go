@@ -0,0 +1,95 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderWritesErrorCompletesWrite(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go OrderWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 256))
+
+	// Fill the write table with failing writes to the same block, which
+	// are serialized, then check that a later write still completes.
+	go func() {
+		for i := 0; i != 6; i++ {
+			sendFrame64(upstreamRequest, WriteReqFrames64(250, make([]byte, 16), nil, uint8(i)))
+		}
+		sendFrame64(upstreamRequest, WriteReqFrames64(0, make([]byte, 16), nil, 6))
+	}()
+	for i := 0; i != 7; i++ {
+		frame := recvFrame(t, upstreamResponse)
+		wantType := uint8(SmiMemErrorResp)
+		if i == 6 {
+			wantType = SmiMemWriteResp
+		}
+		if frame[0].Data[0] != wantType || respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("response %d: %v", i, frame)
+		}
+	}
+}
+
+func TestOrderWritesReadErrorsIgnored(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go OrderWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 256))
+
+	// Read errors must not be mistaken for write completions, and must not
+	// stall the response path.
+	go func() {
+		for i := 0; i != 10; i++ {
+			sendFrame64(upstreamRequest, ReadReqFrames64(1024, 8, uint8(i)))
+		}
+		sendFrame64(upstreamRequest, WriteReqFrames64(0, make([]byte, 8), nil, 10))
+	}()
+	for i := 0; i != 11; i++ {
+		recvFrame(t, upstreamResponse)
+	}
+}
+
+func TestOrderWritesBlockSpan(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	go OrderWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+
+	// Each write at address 250 spans blocks 0 and 1. A read which does not
+	// overlap it is forwarded immediately, while a read or write which only
+	// touches block 1 is held until the write response has been received.
+	heldFrames := [][]Flit64{
+		ReadReqFrames64(SmiMemHazardBlockSize, 8, 3),
+		WriteReqFrames64(SmiMemHazardBlockSize+8, make([]byte, 8), nil, 4),
+	}
+	for i, heldFrame := range heldFrames {
+		go sendFrame64(upstreamRequest, WriteReqFrames64(250, make([]byte, 16), nil, 1))
+		recvFrame(t, downstreamRequest)
+		go sendFrame64(upstreamRequest, ReadReqFrames64(512, 8, 2))
+		if frame := recvFrame(t, downstreamRequest); frame[0].Data[3] != 2 {
+			t.Fatalf("unexpected request %v", frame)
+		}
+		go sendFrame64(upstreamRequest, heldFrame)
+		expectIdle(t, downstreamRequest, 10*time.Millisecond)
+		sendFrame(t, downstreamResponse, []Flit64{{
+			Data: [8]uint8{SmiMemWriteResp, 0, 0, 1}, Eofc: 4}})
+		if frame := recvFrame(t, downstreamRequest); frame[0].Data[3] != uint8(3+i) {
+			t.Fatalf("unexpected request %v", frame)
+		}
+	}
+}
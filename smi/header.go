@@ -0,0 +1,61 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// The 14 byte header of an SMI memory access request frame is carried in the
+// first two flits of the frame. The canonical byte order for the multi-byte
+// header fields is little endian throughout, so that the first byte of each
+// field holds its least significant bits. The 64-bit address occupies frame
+// bytes 4 to 11, which are Data[4] to Data[7] of the first header flit
+// followed by Data[0] to Data[3] of the second header flit. The 16-bit
+// transfer length occupies frame bytes 12 and 13, which are Data[4] and
+// Data[5] of the second header flit. The header accessor functions below
+// should be used wherever these fields are read or written, so that the same
+// layout is used everywhere.
+//
+
+//
+// ReadAddr extracts the 64-bit memory address from the first two flits of a
+// memory access request frame.
+//
+func ReadAddr(header [2]Flit64) uint64 {
+	addr := uint64(0)
+	for i := uint(0); i != 4; i++ {
+		addr |= uint64(header[0].Data[4+i]) << (8 * i)
+		addr |= uint64(header[1].Data[i]) << (8 * (i + 4))
+	}
+	return addr
+}
+
+//
+// WriteAddr sets the 64-bit memory address in the first two flits of a
+// memory access request frame.
+//
+func WriteAddr(header *[2]Flit64, addr uint64) {
+	for i := uint(0); i != 4; i++ {
+		header[0].Data[4+i] = uint8(addr >> (8 * i))
+		header[1].Data[i] = uint8(addr >> (8 * (i + 4)))
+	}
+}
+
+//
+// ReadLength extracts the 16-bit transfer length from the first two flits of
+// a memory access request frame.
+//
+func ReadLength(header [2]Flit64) uint16 {
+	return uint16(header[1].Data[4]) | (uint16(header[1].Data[5]) << 8)
+}
+
+//
+// WriteLength sets the 16-bit transfer length in the first two flits of a
+// memory access request frame.
+//
+func WriteLength(header *[2]Flit64, length uint16) {
+	header[1].Data[4] = uint8(length)
+	header[1].Data[5] = uint8(length >> 8)
+}
@@ -0,0 +1,228 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Header checks provide a lightweight integrity check for the header flit of
+// an SMI frame. A corrupted header is more damaging than corrupted payload
+// data, since the frame type and tag bytes are used for steering frames
+// through the arbiters. The upper four bits of the options/status byte
+// (Data[1]) are reserved and are used to carry a 4-bit check value. This is
+// the XOR of all the nibbles in the frame type byte, the lower half of the
+// options/status byte and the two tag bytes, together with a non-zero seed
+// value so that an all-zero header flit is always rejected.
+//
+const headerCheckSeed = uint8(0x05)
+
+//
+// HeaderCheck calculates the 4-bit header check value for the specified
+// header flit. The current contents of the check value bits are ignored.
+//
+func HeaderCheck(headerFlit Flit64) uint8 {
+	checkByte := headerFlit.Data[0] ^ (headerFlit.Data[1] & 0x0F) ^
+		headerFlit.Data[2] ^ headerFlit.Data[3]
+	return ((checkByte >> 4) ^ checkByte ^ headerCheckSeed) & 0x0F
+}
+
+//
+// SetHeaderCheck returns a copy of the specified header flit with the header
+// check value bits updated to match the current header contents.
+//
+func SetHeaderCheck(headerFlit Flit64) Flit64 {
+	headerFlit.Data[1] = (headerFlit.Data[1] & 0x0F) | (HeaderCheck(headerFlit) << 4)
+	return headerFlit
+}
+
+//
+// HeaderCheckOk verifies that the header check value bits in the specified
+// header flit match the current header contents.
+//
+func HeaderCheckOk(headerFlit Flit64) bool {
+	return (headerFlit.Data[1] >> 4) == HeaderCheck(headerFlit)
+}
+
+//
+// manageCheckedUpstreamPort is a variant of manageUpstreamPort which updates
+// the header check value after carrying out tag substitution on the request
+// and response header flits.
+//
+func manageCheckedUpstreamPort(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		for {
+
+			// Do tag replacement on header.
+			headerFlit := <-upstreamRequest
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- SetHeaderCheck(headerFlit)
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Carry out tag replacement on responses.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-taggedResponse
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		tagFifo <- tagId
+		upstreamResponse <- SetHeaderCheck(headerFlit)
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
+
+//
+// ArbitrateX2HeaderChecked is a variant of ArbitrateX2 which protects the
+// frame header flits using the header check value. Request headers have the
+// check value regenerated after tag substitution. Response headers from the
+// downstream port are verified before the tag bytes are used for steering,
+// and any response frame with a failed header check is diverted in its
+// entirety to the error response channel instead of being misrouted. This is
+// a non-blocking send, so diverted flits will be discarded if the error
+// response channel is not being serviced. If the port ID and local tag bytes
+// of a failed header are still plausible, an error response frame is sent
+// to the corresponding upstream port in place of the diverted frame, so
+// that the local tag is released and the upstream master is notified of the
+// failure. Where the corruption has affected the tag bytes themselves, this
+// may release the wrong tag, and failed headers with an invalid port ID or
+// local tag can not be recovered. Response headers have the check value
+// regenerated after tag restoration.
+//
+func ArbitrateX2HeaderChecked(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	errorResponse chan<- Flit64) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageCheckedUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageCheckedUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses, using port ID zero to indicate that the
+	// current frame has a failed header check.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			if HeaderCheckOk(respFlit) {
+				portId = respFlit.Data[2]
+			} else {
+				portId = 0
+
+				// Release the local tag using an error response if the
+				// port ID and local tag are plausible.
+				errorFlit := SetHeaderCheck(Flit64{
+					Data: [8]uint8{SmiMemErrorResp, 0x02, respFlit.Data[2],
+						respFlit.Data[3], SmiMemErrUnspecified},
+					Eofc: 5})
+				if respFlit.Data[3] < 4 {
+					switch respFlit.Data[2] {
+					case 1:
+						taggedResponseA <- errorFlit
+					case 2:
+						taggedResponseB <- errorFlit
+					}
+				}
+			}
+		}
+		switch portId {
+		case 0:
+			select {
+			case errorResponse <- respFlit:
+			default:
+			}
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
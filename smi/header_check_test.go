@@ -0,0 +1,104 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHeaderCheck(t *testing.T) {
+	headerFlit := SetHeaderCheck(Flit64{Data: [8]uint8{SmiMemReadResp, 0x02, 0x34, 0x12}, Eofc: 4})
+	if !HeaderCheckOk(headerFlit) || headerFlit.Data[1]&0x0F != 0x02 {
+		t.Fatalf("unexpected checked header %v", headerFlit)
+	}
+	for i := 0; i != 4; i++ {
+		corruptFlit := headerFlit
+		corruptFlit.Data[i] ^= 0x01
+		if HeaderCheckOk(corruptFlit) {
+			t.Fatalf("corrupted byte %d not detected", i)
+		}
+	}
+	if HeaderCheckOk(Flit64{}) {
+		t.Fatal("all-zero header accepted")
+	}
+}
+
+func TestArbitrateX2HeaderCheckedRecovery(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	errorResponse := make(chan Flit64, 64)
+	go ArbitrateX2HeaderChecked(upstreamRequestA, upstreamResponseA,
+		make(chan Flit64), make(chan Flit64),
+		downstreamRequest, downstreamResponse, errorResponse)
+
+	// More corrupted responses are returned than there are local tags. Each
+	// is diverted and the upstream master receives an error response.
+	for i := 0; i != 2*SmiMemInFlightLimit; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, uint8(0x10+i)))
+		reqHeader := recvFrame(t, downstreamRequest)[0]
+		if !HeaderCheckOk(reqHeader) {
+			t.Fatalf("request header check failed %v", reqHeader)
+		}
+		respFrame := packFrame64([]byte{SmiMemReadResp, 0, reqHeader.Data[2], reqHeader.Data[3],
+			1, 2, 3, 4, 5, 6, 7, 8})
+		respFrame[0] = SetHeaderCheck(respFrame[0])
+		respFrame[0].Data[1] ^= 0x10
+		sendFrame(t, downstreamResponse, respFrame)
+		if diverted := recvFrame(t, errorResponse); !reflect.DeepEqual(diverted, respFrame) {
+			t.Fatalf("unexpected diverted frame %v", diverted)
+		}
+		errorFrame := recvFrame(t, upstreamResponseA)
+		if ok, errorCode := ResponseStatus(errorFrame[0]); ok || len(errorFrame) != 1 ||
+			errorCode != SmiMemErrUnspecified || respTag(errorFrame[0]) != uint16(0x10+i)<<8 ||
+			!HeaderCheckOk(errorFrame[0]) {
+			t.Fatalf("unexpected error response %v", errorFrame)
+		}
+	}
+
+	// Valid responses are still delivered.
+	go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, 0x55))
+	reqHeader := recvFrame(t, downstreamRequest)[0]
+	respFrame := packFrame64([]byte{SmiMemReadResp, 0, reqHeader.Data[2], reqHeader.Data[3],
+		1, 2, 3, 4, 5, 6, 7, 8})
+	respFrame[0] = SetHeaderCheck(respFrame[0])
+	sendFrame(t, downstreamResponse, respFrame)
+	outputFrame := recvFrame(t, upstreamResponseA)
+	if !HeaderCheckOk(outputFrame[0]) || respTag(outputFrame[0]) != 0x5500 ||
+		!reflect.DeepEqual(unpackFrame64(outputFrame)[smiMemRespHeaderSize:],
+			[]byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("unexpected response %v", outputFrame)
+	}
+	expectIdle(t, errorResponse, 10*time.Millisecond)
+}
+
+func TestArbitrateX2HeaderCheckedUnservicedErrors(t *testing.T) {
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX2HeaderChecked(make(chan Flit64), make(chan Flit64),
+		upstreamRequestB, upstreamResponseB,
+		downstreamRequest, downstreamResponse, make(chan Flit64))
+
+	// Corrupted frames with an invalid port ID are discarded without
+	// stalling the response path when the error channel is not serviced.
+	for i := 0; i != 4; i++ {
+		sendFrame(t, downstreamResponse, packFrame64([]byte{SmiMemReadResp, 0, 7, 0,
+			1, 2, 3, 4, 5, 6, 7, 8}))
+	}
+	go sendFrame64(upstreamRequestB, ReadReqFrames64(0, 8, 0x66))
+	reqHeader := recvFrame(t, downstreamRequest)[0]
+	sendFrame(t, downstreamResponse, []Flit64{SetHeaderCheck(Flit64{
+		Data: [8]uint8{SmiMemWriteResp, 0, reqHeader.Data[2], reqHeader.Data[3]}, Eofc: 4})})
+	if respFrame := recvFrame(t, upstreamResponseB); respTag(respFrame[0]) != 0x6600 {
+		t.Fatalf("unexpected response %v", respFrame)
+	}
+}
@@ -0,0 +1,238 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//
+// headerOnlyFrames returns a single flit read request frame followed by a
+// two flit write request frame, both using the specified tag.
+//
+func headerOnlyFrames(tag uint8) ([]Flit64, []Flit64) {
+	readFrame := ReadReqFrames64(0x100, 8, tag)
+	if len(readFrame) != 2 {
+		panic("unexpected read request frame size")
+	}
+	headerFlit := readFrame[0]
+	headerFlit.Eofc = 8
+	return []Flit64{headerFlit}, WriteReqFrames64(0x100, []byte{1, 2}, nil, tag)
+}
+
+//
+// checkHeaderOnlyFrame forwards a header only frame and a two flit frame
+// through a frame forwarding function, checking that exactly the right
+// flits are consumed and produced for each frame.
+//
+func checkHeaderOnlyFrame(t *testing.T, forward func(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	forwardDone chan<- bool)) {
+
+	t.Helper()
+	shortFrame, longFrame := headerOnlyFrames(1)
+	forwardReq := make(chan bool)
+	smiInput := make(chan Flit64, 8)
+	smiOutput := make(chan Flit64, 8)
+	forwardDone := make(chan bool)
+	go forward(forwardReq, smiInput, smiOutput, forwardDone)
+
+	for _, inputFlit := range append(shortFrame, longFrame...) {
+		smiInput <- inputFlit
+	}
+	forwardReq <- true
+	<-forwardDone
+	if frame := recvFrame(t, smiOutput); !reflect.DeepEqual(frame, shortFrame) {
+		t.Fatalf("unexpected short frame %v", frame)
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	if len(smiInput) != len(longFrame) {
+		t.Fatalf("%d flits left on input, expected %d", len(smiInput), len(longFrame))
+	}
+	forwardReq <- true
+	<-forwardDone
+	if frame := recvFrame(t, smiOutput); !reflect.DeepEqual(frame, longFrame) {
+		t.Fatalf("unexpected long frame %v", frame)
+	}
+}
+
+func TestForwardFrameHeaderOnly(t *testing.T) {
+	checkHeaderOnlyFrame(t, ForwardFrame64)
+}
+
+func TestAssembleFrameHeaderOnly(t *testing.T) {
+	checkHeaderOnlyFrame(t, AssembleFrame64)
+}
+
+func TestManageUpstreamPortHeaderOnly(t *testing.T) {
+	upstreamRequest := make(chan Flit64, 8)
+	upstreamResponse := make(chan Flit64, 8)
+	taggedRequest := make(chan Flit64, 8)
+	taggedResponse := make(chan Flit64, 8)
+	transferReq := make(chan uint8, 8)
+	go manageUpstreamPort(upstreamRequest, upstreamResponse,
+		taggedRequest, taggedResponse, transferReq, 3)
+
+	// Only the header only request frame is forwarded until the next frame
+	// is sent.
+	shortFrame, longFrame := headerOnlyFrames(7)
+	sendFrame(t, upstreamRequest, shortFrame)
+	tagged := recvFrame(t, taggedRequest)
+	if len(tagged) != 1 || tagged[0].Data[2] != 3 || <-transferReq != 3 {
+		t.Fatalf("unexpected tagged frame %v", tagged)
+	}
+	expectIdle(t, taggedRequest, 10*time.Millisecond)
+	sendFrame(t, upstreamRequest, longFrame)
+	if frame := recvFrame(t, taggedRequest); len(frame) != len(longFrame) {
+		t.Fatalf("unexpected tagged frame %v", frame)
+	}
+
+	// A header only response frame is followed by a two flit response.
+	taggedResponse <- Flit64{
+		Data: [8]uint8{SmiMemWriteResp, 0, 3, tagged[0].Data[3]}, Eofc: 4}
+	if frame := recvFrame(t, upstreamResponse); len(frame) != 1 || respTag(frame[0]) != 7<<8 {
+		t.Fatalf("unexpected response frame %v", frame)
+	}
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+	sendFrame(t, taggedResponse, []Flit64{
+		{Data: [8]uint8{SmiMemReadResp, 0, 3, tagged[0].Data[3], 1, 2, 3, 4}},
+		{Data: [8]uint8{5}, Eofc: 1}})
+	if frame := recvFrame(t, upstreamResponse); len(frame) != 2 || frame[1].Data[0] != 5 {
+		t.Fatalf("unexpected response frame %v", frame)
+	}
+}
+
+//
+// checkArbiterHeaderOnly sends a header only request frame and a two flit
+// request frame from each of the upstream ports through an arbiter, acting
+// as the downstream endpoint and answering each request with a header only
+// response frame.
+//
+func checkArbiterHeaderOnly(t *testing.T, portCount int, arbitrate func(
+	upstreamRequests []chan Flit64,
+	upstreamResponses []chan Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64)) {
+
+	t.Helper()
+	upstreamRequests := make([]chan Flit64, portCount)
+	upstreamResponses := make([]chan Flit64, portCount)
+	for i := range upstreamRequests {
+		upstreamRequests[i] = make(chan Flit64, 8)
+		upstreamResponses[i] = make(chan Flit64, 8)
+	}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go arbitrate(upstreamRequests, upstreamResponses,
+		downstreamRequest, downstreamResponse)
+
+	for i := range upstreamRequests {
+		shortFrame, longFrame := headerOnlyFrames(uint8(i))
+		sendFrame(t, upstreamRequests[i], shortFrame)
+		sendFrame(t, upstreamRequests[i], longFrame)
+		for _, expectedLen := range []int{1, len(longFrame)} {
+			reqFrame := recvFrame(t, downstreamRequest)
+			if len(reqFrame) != expectedLen {
+				t.Fatalf("port %d: unexpected request frame %v", i, reqFrame)
+			}
+			sendFrame(t, downstreamResponse, []Flit64{{
+				Data: [8]uint8{SmiMemWriteResp, 0, reqFrame[0].Data[2], reqFrame[0].Data[3]},
+				Eofc: 4}})
+			respFrame := recvFrame(t, upstreamResponses[i])
+			if len(respFrame) != 1 || respTag(respFrame[0]) != uint16(i)<<8 {
+				t.Fatalf("port %d: unexpected response frame %v", i, respFrame)
+			}
+		}
+		expectIdle(t, downstreamRequest, 10*time.Millisecond)
+		expectIdle(t, upstreamResponses[i], 10*time.Millisecond)
+	}
+}
+
+func TestArbitrateX2HeaderOnly(t *testing.T) {
+	checkArbiterHeaderOnly(t, 2, func(
+		upReq []chan Flit64, upResp []chan Flit64,
+		downReq chan<- Flit64, downResp <-chan Flit64) {
+		ArbitrateX2(upReq[0], upResp[0], upReq[1], upResp[1], downReq, downResp)
+	})
+}
+
+func TestArbitrateX3HeaderOnly(t *testing.T) {
+	checkArbiterHeaderOnly(t, 3, func(
+		upReq []chan Flit64, upResp []chan Flit64,
+		downReq chan<- Flit64, downResp <-chan Flit64) {
+		ArbitrateX3(upReq[0], upResp[0], upReq[1], upResp[1],
+			upReq[2], upResp[2], downReq, downResp)
+	})
+}
+
+func TestArbitrateX4HeaderOnly(t *testing.T) {
+	checkArbiterHeaderOnly(t, 4, func(
+		upReq []chan Flit64, upResp []chan Flit64,
+		downReq chan<- Flit64, downResp <-chan Flit64) {
+		ArbitrateX4(upReq[0], upResp[0], upReq[1], upResp[1],
+			upReq[2], upResp[2], upReq[3], upResp[3], downReq, downResp)
+	})
+}
+
+func TestArbitrateX2VariantsHeaderOnly(t *testing.T) {
+	variants := map[string]func(
+		upstreamRequestA <-chan Flit64,
+		upstreamResponseA chan<- Flit64,
+		upstreamRequestB <-chan Flit64,
+		upstreamResponseB chan<- Flit64,
+		downstreamRequest chan<- Flit64,
+		downstreamResponse <-chan Flit64){
+		"RoundRobin":     ArbitrateX2RoundRobin,
+		"CutThrough":     ArbitrateX2CutThrough,
+		"Decoupled":      ArbitrateX2Decoupled,
+		"ByteFair":       ArbitrateX2ByteFair,
+		"LatencyStamped": ArbitrateX2LatencyStamped,
+		"Depth1":         ArbitrateX2Depth1,
+	}
+	for name, arbitrateX2 := range variants {
+		arbitrateX2 := arbitrateX2
+		t.Run(name, func(t *testing.T) {
+			checkArbiterHeaderOnly(t, 2, func(
+				upReq []chan Flit64, upResp []chan Flit64,
+				downReq chan<- Flit64, downResp <-chan Flit64) {
+				arbitrateX2(upReq[0], upResp[0], upReq[1], upResp[1], downReq, downResp)
+			})
+		})
+	}
+}
+
+func TestArbitrateX4VariantsHeaderOnly(t *testing.T) {
+	variants := map[string]func(
+		upstreamRequestA <-chan Flit64,
+		upstreamResponseA chan<- Flit64,
+		upstreamRequestB <-chan Flit64,
+		upstreamResponseB chan<- Flit64,
+		upstreamRequestC <-chan Flit64,
+		upstreamResponseC chan<- Flit64,
+		upstreamRequestD <-chan Flit64,
+		upstreamResponseD chan<- Flit64,
+		downstreamRequest chan<- Flit64,
+		downstreamResponse <-chan Flit64){
+		"RoundRobin": ArbitrateX4RoundRobin,
+		"Priority":   ArbitrateX4Priority,
+	}
+	for name, arbitrateX4 := range variants {
+		arbitrateX4 := arbitrateX4
+		t.Run(name, func(t *testing.T) {
+			checkArbiterHeaderOnly(t, 4, func(
+				upReq []chan Flit64, upResp []chan Flit64,
+				downReq chan<- Flit64, downResp <-chan Flit64) {
+				arbitrateX4(upReq[0], upResp[0], upReq[1], upResp[1],
+					upReq[2], upResp[2], upReq[3], upResp[3], downReq, downResp)
+			})
+		})
+	}
+}
@@ -0,0 +1,47 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestHeaderAccessorLayout(t *testing.T) {
+	var header [2]Flit64
+	header[0].Data = [8]uint8{SmiMemReadReq, 0x01, 0x02, 0x03, 0xEE, 0xEE, 0xEE, 0xEE}
+	header[1].Data = [8]uint8{0xEE, 0xEE, 0xEE, 0xEE, 0xEE, 0xEE, 0x06, 0x07}
+	WriteAddr(&header, 0x8877665544332211)
+	WriteLength(&header, 0xBBAA)
+
+	// The fields are little endian and other header bytes are untouched.
+	expect := [2]Flit64{
+		{Data: [8]uint8{SmiMemReadReq, 0x01, 0x02, 0x03, 0x11, 0x22, 0x33, 0x44}},
+		{Data: [8]uint8{0x55, 0x66, 0x77, 0x88, 0xAA, 0xBB, 0x06, 0x07}},
+	}
+	if header != expect {
+		t.Fatalf("unexpected header layout %v", header)
+	}
+	if addr := ReadAddr(header); addr != 0x8877665544332211 {
+		t.Fatalf("unexpected address 0x%X", addr)
+	}
+	if length := ReadLength(header); length != 0xBBAA {
+		t.Fatalf("unexpected length 0x%X", length)
+	}
+}
+
+func TestHeaderAccessorsMatchBuilders(t *testing.T) {
+	flits := ReadReqFrames64(0x0123456789ABCD00, 200, 1)
+	header := [2]Flit64{flits[0], flits[1]}
+	if ReadAddr(header) != 0x0123456789ABCD00 || ReadLength(header) != 200 {
+		t.Fatalf("unexpected header fields %v", header)
+	}
+	frameBytes := unpackFrame64(flits)
+	if frameBytesAddr(frameBytes) != ReadAddr(header) ||
+		frameBytesLength(frameBytes) != ReadLength(header) {
+		t.Fatal("mismatched frame byte accessors")
+	}
+}
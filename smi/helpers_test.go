@@ -0,0 +1,97 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// testTimeout bounds the time which tests wait for any single frame or
+// signal, so that a deadlocked component fails the test instead of hanging.
+//
+const testTimeout = 2 * time.Second
+
+//
+// recvFlit receives a single flit from the channel, failing the test if it
+// is not available before the timeout.
+//
+func recvFlit(t *testing.T, smiInput <-chan Flit64) Flit64 {
+	t.Helper()
+	select {
+	case inputFlit := <-smiInput:
+		return inputFlit
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for flit")
+		return Flit64{}
+	}
+}
+
+//
+// recvFrame receives a complete frame from the channel, failing the test if
+// any flit is not available before the timeout.
+//
+func recvFrame(t *testing.T, smiInput <-chan Flit64) []Flit64 {
+	t.Helper()
+	frame := []Flit64{}
+	moreFlits := true
+	for moreFlits {
+		inputFlit := recvFlit(t, smiInput)
+		frame = append(frame, inputFlit)
+		moreFlits = inputFlit.Eofc == 0
+	}
+	return frame
+}
+
+//
+// sendFrame sends a complete frame to the channel, failing the test if any
+// flit is not accepted before the timeout.
+//
+func sendFrame(t *testing.T, smiOutput chan<- Flit64, frame []Flit64) {
+	t.Helper()
+	for _, outputFlit := range frame {
+		select {
+		case smiOutput <- outputFlit:
+		case <-time.After(testTimeout):
+			t.Fatal("timed out sending flit")
+		}
+	}
+}
+
+//
+// tryOffer attempts to send a flit on the channel, returning false if it is
+// not accepted within the specified interval.
+//
+func tryOffer(smiOutput chan<- Flit64, outputFlit Flit64, interval time.Duration) bool {
+	select {
+	case smiOutput <- outputFlit:
+		return true
+	case <-time.After(interval):
+		return false
+	}
+}
+
+//
+// expectIdle fails the test if a flit is received from the channel within
+// the specified interval.
+//
+func expectIdle(t *testing.T, smiInput <-chan Flit64, interval time.Duration) {
+	t.Helper()
+	select {
+	case inputFlit := <-smiInput:
+		t.Fatalf("unexpected flit %v", inputFlit)
+	case <-time.After(interval):
+	}
+}
+
+//
+// respTag extracts the 16-bit tag from a response header flit.
+//
+func respTag(headerFlit Flit64) uint16 {
+	return uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+}
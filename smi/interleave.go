@@ -0,0 +1,89 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// InterleaveBursts2 is a goroutine which stripes the request frames from a
+// single upstream port across two downstream ports, so that a single master
+// can use the combined bandwidth of two memory banks. The memory address
+// space is divided into stripes of the specified size, with even numbered
+// stripes being mapped to downstream port A and odd numbered stripes being
+// mapped to downstream port B. Each request frame is sent to the port which
+// owns the stripe containing its start address, so sequential bursts which
+// are aligned to the stripe size alternate between the two ports. Request
+// frames are not split, so bursts should not cross stripe boundaries. The
+// downstream port which handles each request is recorded in a FIFO, so that
+// the response frames can be collected from the correct downstream port and
+// returned upstream in the original request order. Up to
+// SmiMemInFlightLimit requests may be outstanding at any time. A stripe size
+// of zero sends all requests to downstream port A.
+//
+func InterleaveBursts2(
+	upstreamRequest <-chan Flit64,
+	downstreamRequestA chan<- Flit64,
+	downstreamRequestB chan<- Flit64,
+	downstreamResponseA <-chan Flit64,
+	downstreamResponseB <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	stripeBytes uint64) {
+
+	// The response routine holds the routing of the oldest outstanding
+	// request, so the FIFO only needs to hold the remainder.
+	// TODO: The channel size here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	routeFifo := make(chan uint8, 3)
+
+	// Route request frames. The address is split across the first two flits,
+	// so both are received before the routing decision is made.
+	go func() {
+		for {
+			headerFlit := <-upstreamRequest
+			var addrFlit Flit64
+			if headerFlit.Eofc == 0 {
+				addrFlit = <-upstreamRequest
+			}
+			reqAddr := ReadAddr([2]Flit64{headerFlit, addrFlit})
+			portId := uint8(1)
+			downstreamRequest := downstreamRequestA
+			if stripeBytes != 0 && (reqAddr/stripeBytes)%2 != 0 {
+				portId = 2
+				downstreamRequest = downstreamRequestB
+			}
+			routeFifo <- portId
+
+			// Copy the frame to the selected downstream port.
+			downstreamRequest <- headerFlit
+			moreFlits := headerFlit.Eofc == 0
+			if moreFlits {
+				downstreamRequest <- addrFlit
+				moreFlits = addrFlit.Eofc == 0
+			}
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				downstreamRequest <- bodyFlit
+				moreFlits = bodyFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Return response frames in request order.
+	for {
+		portId := <-routeFifo
+		var respFlit Flit64
+		moreFlits := true
+		for moreFlits {
+			switch portId {
+			case 1:
+				respFlit = <-downstreamResponseA
+			default:
+				respFlit = <-downstreamResponseB
+			}
+			upstreamResponse <- respFlit
+			moreFlits = respFlit.Eofc == 0
+		}
+	}
+}
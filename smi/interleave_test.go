@@ -0,0 +1,76 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestInterleaveBursts2(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 256)
+	downstreamRequests := []chan Flit64{make(chan Flit64, 64), make(chan Flit64, 64)}
+	downstreamResponses := []chan Flit64{make(chan Flit64, 128), make(chan Flit64, 128)}
+	go InterleaveBursts2(upstreamRequest,
+		downstreamRequests[0], downstreamRequests[1],
+		downstreamResponses[0], downstreamResponses[1],
+		upstreamResponse, SmiMemBurstSize)
+
+	// Four sequential bursts alternate between the downstream ports.
+	go sendFrame64(upstreamRequest, ReadReqFrames64(0x1000, 4*SmiMemBurstSize, 1))
+	for i := 0; i != 4; i++ {
+		frame := recvFrame(t, downstreamRequests[i%2])
+		if ReadAddr([2]Flit64{frame[0], frame[1]}) != 0x1000+uint64(i*SmiMemBurstSize) {
+			t.Fatalf("unexpected request %d %v", i, frame)
+		}
+	}
+	expectIdle(t, downstreamRequests[0], 10*time.Millisecond)
+	expectIdle(t, downstreamRequests[1], 10*time.Millisecond)
+
+	// Complete the port B bursts first, with each burst holding its index.
+	respData := make([][]byte, 4)
+	for _, i := range []int{1, 3, 0, 2} {
+		respData[i] = bytes.Repeat([]byte{uint8(i)}, SmiMemBurstSize)
+		respBytes := append([]byte{SmiMemReadResp, 0, 0, 1}, respData[i]...)
+		sendFrame(t, downstreamResponses[i%2], packFrame64(respBytes))
+		if i == 3 {
+			expectIdle(t, upstreamResponse, 10*time.Millisecond)
+		}
+	}
+	for i := range respData {
+		readData, _, err := ParseReadResp64(upstreamResponse)
+		if err != nil || !bytes.Equal(readData, respData[i]) {
+			t.Fatalf("unexpected response %d %v", i, err)
+		}
+	}
+}
+
+func TestInterleaveBursts2InFlightLimit(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	downstreamRequestA := make(chan Flit64, 64)
+	downstreamResponseA := make(chan Flit64, 16)
+	go InterleaveBursts2(upstreamRequest, downstreamRequestA, make(chan Flit64, 64),
+		downstreamResponseA, make(chan Flit64), make(chan Flit64, 16), 0)
+
+	// The fifth outstanding request is stalled until a response returns.
+	go func() {
+		for i := 0; i != SmiMemInFlightLimit+1; i++ {
+			sendFrame64(upstreamRequest, ReadReqFrames64(0, 8, uint8(i)))
+		}
+	}()
+	for i := 0; i != SmiMemInFlightLimit; i++ {
+		recvFrame(t, downstreamRequestA)
+	}
+	expectIdle(t, downstreamRequestA, 10*time.Millisecond)
+	sendFrame(t, downstreamResponseA, []Flit64{{
+		Data: [8]uint8{SmiMemErrorResp, 0, 0, 0, SmiMemErrDecode}, Eofc: 5}})
+	if frame := recvFrame(t, downstreamRequestA); frame[0].Data[3] != SmiMemInFlightLimit {
+		t.Fatalf("unexpected request %v", frame)
+	}
+}
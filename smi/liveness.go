@@ -0,0 +1,126 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Specify the dedicated tag value used by liveness probe requests. This is
+// carried in bytes 2 and 3 of the probe request header and allows probe
+// transactions to be distinguished from application traffic.
+//
+const SmiLivenessProbeTag = uint16(0xFFFF)
+
+//
+// LivenessProbe64 is a goroutine which periodically checks that an SMI
+// memory access path is alive. It should be connected to a dedicated
+// upstream port of the memory arbitrator. Each time a probe trigger is
+// received, a single 64-bit read of the specified known-good address is
+// issued using the dedicated probe tag. The probe is healthy if the response
+// has the expected frame type, tag and status and returns the expected data
+// value within the specified number of ticks on the tick channel. The result
+// of each probe is sent on the status channel, with true indicating a healthy
+// memory access path. If the response does not arrive in time, an unhealthy
+// status is reported immediately and no further probes are issued until the
+// late response has been received and discarded. Probe triggers continue to
+// be serviced while the late response is outstanding, with an unhealthy
+// status being reported for each one, so a dead memory access path is
+// reported on every trigger. Triggers received while a probe is waiting for
+// its response before the timeout has expired are merged with that probe.
+//
+func LivenessProbe64(
+	probeTrigger <-chan struct{},
+	tick <-chan struct{},
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64,
+	probeAddr uintptr,
+	probeData uint64,
+	timeoutTicks uint32,
+	probeStatus chan<- bool) {
+
+	// Set up the probe request flits.
+	reqFlit1 := Flit64{
+		Eofc: 0,
+		Data: [8]uint8{
+			uint8(SmiMemReadReq),
+			DefaultOptions,
+			uint8(SmiLivenessProbeTag & 0xFF),
+			uint8(SmiLivenessProbeTag >> 8),
+			uint8(probeAddr) & 0xF8,
+			uint8(probeAddr >> 8),
+			uint8(probeAddr >> 16),
+			uint8(probeAddr >> 24)}}
+
+	reqFlit2 := Flit64{
+		Eofc: 6,
+		Data: [8]uint8{
+			uint8(probeAddr >> 32),
+			uint8(probeAddr >> 40),
+			uint8(probeAddr >> 48),
+			uint8(probeAddr >> 56),
+			uint8(8),
+			uint8(0),
+			uint8(0),
+			uint8(0)}}
+
+	for {
+		<-probeTrigger
+		smiRequest <- reqFlit1
+		smiRequest <- reqFlit2
+
+		// Wait for the response, reporting a timeout if required.
+		waitTicks := uint32(0)
+		isTimedOut := false
+		isWaiting := true
+		probeOk := false
+		for isWaiting {
+			select {
+			case respFlit := <-smiResponse:
+				probeOk = respFlit.Data[0] == SmiMemReadResp &&
+					(respFlit.Data[1]&0x02) == uint8(0x00) &&
+					respFlit.Data[2] == uint8(SmiLivenessProbeTag&0xFF) &&
+					respFlit.Data[3] == uint8(SmiLivenessProbeTag>>8)
+				respData := [8]uint8{
+					respFlit.Data[4],
+					respFlit.Data[5],
+					respFlit.Data[6],
+					respFlit.Data[7]}
+
+				// Collect the remaining read data from the response.
+				byteCount := 4
+				moreFlits := respFlit.Eofc == 0
+				for moreFlits {
+					respFlit = <-smiResponse
+					for i := 0; i != 8; i++ {
+						if byteCount != 8 {
+							respData[byteCount] = respFlit.Data[i]
+							byteCount++
+						}
+					}
+					moreFlits = respFlit.Eofc == 0
+				}
+				for i := uint(0); i != 8; i++ {
+					probeOk = probeOk && respData[i] == uint8(probeData>>(8*i))
+				}
+				isWaiting = false
+
+			case <-tick:
+				waitTicks++
+				if !isTimedOut && waitTicks >= timeoutTicks {
+					probeStatus <- false
+					isTimedOut = true
+				}
+
+			case <-probeTrigger:
+				if isTimedOut {
+					probeStatus <- false
+				}
+			}
+		}
+		if !isTimedOut {
+			probeStatus <- probeOk
+		}
+	}
+}
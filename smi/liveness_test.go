@@ -0,0 +1,96 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// probeResp builds a read response frame for a liveness probe carrying the
+// specified 64-bit data value.
+//
+func probeResp(readData uint64) []Flit64 {
+	respBytes := []byte{SmiMemReadResp, 0,
+		uint8(SmiLivenessProbeTag & 0xFF), uint8(SmiLivenessProbeTag >> 8)}
+	for i := uint(0); i != 8; i++ {
+		respBytes = append(respBytes, uint8(readData>>(8*i)))
+	}
+	return packFrame64(respBytes)
+}
+
+func TestLivenessProbe64Timeout(t *testing.T) {
+	probeTrigger := make(chan struct{})
+	tick := make(chan struct{})
+	smiRequest := make(chan Flit64, 16)
+	smiResponse := make(chan Flit64)
+	probeStatus := make(chan bool, 16)
+	go LivenessProbe64(probeTrigger, tick, smiRequest, smiResponse,
+		0x1000, 0x0123456789ABCDEF, 3, probeStatus)
+
+	expectStatus := func(expectOk bool) {
+		t.Helper()
+		select {
+		case probeOk := <-probeStatus:
+			if probeOk != expectOk {
+				t.Fatalf("probe status %v, expected %v", probeOk, expectOk)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for probe status")
+		}
+	}
+
+	trigger := func() {
+		t.Helper()
+		select {
+		case probeTrigger <- struct{}{}:
+		case <-time.After(testTimeout):
+			t.Fatal("probe trigger not serviced")
+		}
+	}
+
+	// A healthy probe.
+	trigger()
+	reqFrame := recvFrame(t, smiRequest)
+	header := [2]Flit64{reqFrame[0], reqFrame[1]}
+	if ReadAddr(header) != 0x1000 || ReadLength(header) != 8 {
+		t.Fatalf("unexpected probe request %v", reqFrame)
+	}
+	sendFrame(t, smiResponse, probeResp(0x0123456789ABCDEF))
+	expectStatus(true)
+
+	// A probe which times out is reported as unhealthy, and every later
+	// trigger is also reported as unhealthy without issuing a new probe.
+	trigger()
+	recvFrame(t, smiRequest)
+	for i := 0; i != 3; i++ {
+		tick <- struct{}{}
+	}
+	expectStatus(false)
+	for i := 0; i != 3; i++ {
+		trigger()
+		expectStatus(false)
+		tick <- struct{}{}
+	}
+	expectIdle(t, smiRequest, 10*time.Millisecond)
+
+	// The late response is discarded and probing resumes, with incorrect
+	// read data being reported as unhealthy.
+	sendFrame(t, smiResponse, probeResp(0x0123456789ABCDEF))
+	trigger()
+	recvFrame(t, smiRequest)
+	sendFrame(t, smiResponse, probeResp(0))
+	expectStatus(false)
+	trigger()
+	recvFrame(t, smiRequest)
+	sendFrame(t, smiResponse, probeResp(0x0123456789ABCDEF))
+	expectStatus(true)
+	if len(probeStatus) != 0 {
+		t.Fatalf("%d unexpected status reports", len(probeStatus))
+	}
+}
@@ -0,0 +1,133 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+//
+// Specify the number of bytes used to encode each flit in the serialized
+// frame format. Each flit is encoded as its eight data bytes followed by its
+// Eofc byte, which matches the flit layout used by FlitsFromFuzz. All eight
+// data bytes are included even for the final flit, so that frames survive a
+// round trip exactly, including any unused trailing bytes.
+//
+const SmiFlit64WireSize = 9
+
+//
+// MarshalBytes serializes a single flit to its SmiFlit64WireSize byte wire
+// format, consisting of the eight data bytes followed by the Eofc byte.
+//
+func (f Flit64) MarshalBytes() []byte {
+	flitBytes := make([]byte, SmiFlit64WireSize)
+	copy(flitBytes, f.Data[:])
+	flitBytes[8] = f.Eofc
+	return flitBytes
+}
+
+//
+// UnmarshalFlit64 deserializes a single flit from the wire format generated
+// by MarshalBytes. An error is returned if the byte slice is not exactly
+// SmiFlit64WireSize bytes long or if the Eofc value is out of range.
+//
+func UnmarshalFlit64(flitBytes []byte) (Flit64, error) {
+	var f Flit64
+	if len(flitBytes) != SmiFlit64WireSize {
+		return f, fmt.Errorf("flit size %d is not %d bytes",
+			len(flitBytes), SmiFlit64WireSize)
+	}
+	copy(f.Data[:], flitBytes)
+	f.Eofc = flitBytes[8]
+	if f.Eofc > 8 {
+		return f, fmt.Errorf("invalid end of frame marker %d", f.Eofc)
+	}
+	return f, nil
+}
+
+//
+// FramesFromBytes reads a stream of serialized flits from the specified
+// reader until the end of the stream, as generated by concatenating the
+// outputs of MarshalFrame for a sequence of frames. The flits for all the
+// frames are returned as a single sequence, with the Eofc field marking the
+// last flit of each frame. An error is returned if the stream is not a whole
+// number of flits, contains an invalid flit or ends part way through a
+// frame.
+//
+func FramesFromBytes(r io.Reader) ([]Flit64, error) {
+	streamBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(streamBytes)%SmiFlit64WireSize != 0 {
+		return nil, fmt.Errorf("stream size %d is not a multiple of %d bytes",
+			len(streamBytes), SmiFlit64WireSize)
+	}
+	flits := make([]Flit64, 0, len(streamBytes)/SmiFlit64WireSize)
+	for i := 0; i != len(streamBytes); i += SmiFlit64WireSize {
+		f, err := UnmarshalFlit64(streamBytes[i : i+SmiFlit64WireSize])
+		if err != nil {
+			return nil, fmt.Errorf("flit %d: %v", len(flits)+1, err)
+		}
+		flits = append(flits, f)
+	}
+	if len(flits) != 0 && flits[len(flits)-1].Eofc == 0 {
+		return nil, fmt.Errorf("truncated frame at end of stream")
+	}
+	return flits, nil
+}
+
+//
+// MarshalFrame serializes a Flit64 based SMI frame to a byte slice for
+// logging or later replay. The frame is not checked for validity during
+// serialization, but only frames with a single end of frame marker on the
+// final flit can be deserialized by UnmarshalFrame.
+//
+func MarshalFrame(frame []Flit64) []byte {
+	frameBytes := make([]byte, 0, len(frame)*SmiFlit64WireSize)
+	for _, frameFlit := range frame {
+		frameBytes = append(frameBytes, frameFlit.MarshalBytes()...)
+	}
+	return frameBytes
+}
+
+//
+// UnmarshalFrame deserializes a byte slice generated by MarshalFrame back to
+// the original Flit64 based SMI frame. An error is returned if the byte slice
+// length is not a whole number of flits, if an Eofc value is out of range or
+// if the end of frame marker is missing or does not occur on the final flit.
+//
+func UnmarshalFrame(frameBytes []byte) ([]Flit64, error) {
+	if len(frameBytes) == 0 {
+		return nil, fmt.Errorf("empty frame")
+	}
+	if len(frameBytes)%SmiFlit64WireSize != 0 {
+		return nil, fmt.Errorf("frame size %d is not a multiple of %d bytes",
+			len(frameBytes), SmiFlit64WireSize)
+	}
+	flitCount := len(frameBytes) / SmiFlit64WireSize
+	frame := make([]Flit64, flitCount)
+	for i := range frame {
+		flitBytes := frameBytes[i*SmiFlit64WireSize : (i+1)*SmiFlit64WireSize]
+		copy(frame[i].Data[:], flitBytes)
+		frame[i].Eofc = flitBytes[8]
+		if frame[i].Eofc > 8 {
+			return nil, fmt.Errorf("invalid end of frame marker %d on flit %d",
+				frame[i].Eofc, i+1)
+		}
+		if frame[i].Eofc != 0 && i != flitCount-1 {
+			return nil, fmt.Errorf("end of frame marker on flit %d of %d",
+				i+1, flitCount)
+		}
+	}
+	if frame[flitCount-1].Eofc == 0 {
+		return nil, fmt.Errorf("missing end of frame marker")
+	}
+	return frame, nil
+}
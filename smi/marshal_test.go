@@ -0,0 +1,98 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalFrameRoundTrip(t *testing.T) {
+	frame := WriteReqFrames64(0x12345678, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, 3)
+	frameBytes := MarshalFrame(frame)
+	if len(frameBytes) != len(frame)*SmiFlit64WireSize {
+		t.Fatalf("unexpected serialized size %d", len(frameBytes))
+	}
+	if frameBytes[SmiFlit64WireSize-1] != 0 ||
+		frameBytes[len(frameBytes)-1] != frame[len(frame)-1].Eofc {
+		t.Fatalf("unexpected end of frame markers in %v", frameBytes)
+	}
+	decoded, err := UnmarshalFrame(frameBytes)
+	if err != nil || !reflect.DeepEqual(decoded, frame) {
+		t.Fatalf("round trip failed: %v %v", decoded, err)
+	}
+}
+
+func TestUnmarshalFrameErrors(t *testing.T) {
+	frameBytes := MarshalFrame(WriteReqFrames64(0, []byte{1, 2, 3}, nil, 0))
+	earlyEnd := append([]byte{}, frameBytes...)
+	earlyEnd[SmiFlit64WireSize-1] = 8
+	badEofc := append([]byte{}, frameBytes...)
+	badEofc[len(badEofc)-1] = 9
+	noEnd := append([]byte{}, frameBytes...)
+	noEnd[len(noEnd)-1] = 0
+	for name, invalid := range map[string][]byte{
+		"empty":     {},
+		"truncated": frameBytes[:len(frameBytes)-1],
+		"early end": earlyEnd,
+		"bad eofc":  badEofc,
+		"no end":    noEnd,
+	} {
+		if _, err := UnmarshalFrame(invalid); err == nil {
+			t.Errorf("%s: no error reported", name)
+		}
+	}
+}
+
+func TestMarshalFlitRoundTrip(t *testing.T) {
+	frame := WriteReqFrames64(0x0102030405060708, []byte{9, 10, 11, 12, 13}, nil, 0x77)
+	for i, f := range frame {
+		flitBytes := f.MarshalBytes()
+		if len(flitBytes) != SmiFlit64WireSize || !bytes.Equal(flitBytes[:8], f.Data[:]) ||
+			flitBytes[8] != f.Eofc {
+			t.Fatalf("unexpected serialized flit %d %v", i, flitBytes)
+		}
+		decoded, err := UnmarshalFlit64(flitBytes)
+		if err != nil || decoded != f {
+			t.Fatalf("round trip failed for flit %d: %v %v", i, decoded, err)
+		}
+	}
+	for name, invalid := range map[string][]byte{
+		"short":    make([]byte, SmiFlit64WireSize-1),
+		"long":     make([]byte, SmiFlit64WireSize+1),
+		"bad eofc": {0, 0, 0, 0, 0, 0, 0, 0, 9},
+	} {
+		if _, err := UnmarshalFlit64(invalid); err == nil {
+			t.Errorf("%s: no error reported", name)
+		}
+	}
+}
+
+func TestFramesFromBytes(t *testing.T) {
+	flits := append(ReadReqFrames64(0x100, 600, 1),
+		WriteReqFrames64(0x200, []byte{1, 2, 3}, nil, 2)...)
+	stream := []byte{}
+	for _, f := range flits {
+		stream = append(stream, f.MarshalBytes()...)
+	}
+	decoded, err := FramesFromBytes(bytes.NewReader(stream))
+	if err != nil || !reflect.DeepEqual(decoded, flits) {
+		t.Fatalf("round trip failed: %v %v", decoded, err)
+	}
+	if decoded, err := FramesFromBytes(bytes.NewReader(nil)); err != nil || len(decoded) != 0 {
+		t.Fatalf("unexpected result for empty stream: %v %v", decoded, err)
+	}
+	for name, invalid := range map[string][]byte{
+		"partial flit":    stream[:len(stream)-1],
+		"truncated frame": stream[:len(stream)-SmiFlit64WireSize],
+	} {
+		if _, err := FramesFromBytes(bytes.NewReader(invalid)); err == nil {
+			t.Errorf("%s: no error reported", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// Type MemResult holds the result of a single memory client operation. The
+// read data is only present for read operations.
+//
+type MemResult struct {
+	Ok   bool
+	Data []byte
+}
+
+//
+// Type MemClient provides a combined read and write client for a pair of SMI
+// request and response channels, which tracks all outstanding operations.
+// Each operation is allocated a tag from a shared pool of SmiMemInFlightLimit
+// tags, with new operations blocking until a tag becomes available. The
+// result of each operation is delivered on its own result channel once the
+// response has been received, so operations may complete out of order. The
+// memory client methods may be called from multiple goroutines. This is
+// intended for use in simulation and host side test code.
+//
+type MemClient struct {
+	smiRequest   chan<- Flit64
+	requestLock  sync.Mutex
+	pendingLock  sync.Mutex
+	tagFifo      chan uint8
+	pendingReads map[uint8]bool
+	pendingOps   map[uint8]chan MemResult
+}
+
+//
+// NewMemClient creates a new memory client using the specified request and
+// response channels, starting the goroutine which processes the responses.
+//
+func NewMemClient(
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64) *MemClient {
+
+	client := &MemClient{
+		smiRequest:   smiRequest,
+		tagFifo:      make(chan uint8, SmiMemInFlightLimit),
+		pendingReads: make(map[uint8]bool),
+		pendingOps:   make(map[uint8]chan MemResult)}
+	for tagInit := uint8(0); tagInit != SmiMemInFlightLimit; tagInit++ {
+		client.tagFifo <- tagInit
+	}
+	go client.processResponses(smiResponse)
+	return client
+}
+
+//
+// issue allocates a tag for a new operation, records it as outstanding and
+// sends the request frame assembled by the supplied function.
+//
+func (client *MemClient) issue(isRead bool, reqBytes func(tagId uint8) []byte) <-chan MemResult {
+	result := make(chan MemResult, 1)
+	tagId := <-client.tagFifo
+	client.pendingLock.Lock()
+	client.pendingReads[tagId] = isRead
+	client.pendingOps[tagId] = result
+	client.pendingLock.Unlock()
+	client.requestLock.Lock()
+	sendFrame64(client.smiRequest, packFrame64(reqBytes(tagId)))
+	client.requestLock.Unlock()
+	return result
+}
+
+//
+// Read issues a read of the specified number of bytes from the specified
+// address, returning the channel on which the result will be delivered.
+//
+func (client *MemClient) Read(
+	readAddr uint64,
+	readLength uint16,
+	readOptions uint8) <-chan MemResult {
+
+	return client.issue(true, func(tagId uint8) []byte {
+		return readReqBytes(readAddr, readOptions, tagId, 0, readLength)
+	})
+}
+
+//
+// Write issues a write of the specified data to the specified address,
+// returning the channel on which the result will be delivered.
+//
+func (client *MemClient) Write(
+	writeAddr uint64,
+	writeData []byte,
+	writeOptions uint8) <-chan MemResult {
+
+	return client.issue(false, func(tagId uint8) []byte {
+		return writeReqBytes(writeAddr, writeOptions, tagId, 0, writeData)
+	})
+}
+
+//
+// Outstanding returns the number of operations which have been issued but
+// have not yet completed.
+//
+func (client *MemClient) Outstanding() int {
+	client.pendingLock.Lock()
+	defer client.pendingLock.Unlock()
+	return len(client.pendingOps)
+}
+
+//
+// processResponses matches response frames to outstanding operations using
+// the tag, delivering the results and returning the tags to the pool.
+// Response frames with unknown tags are discarded.
+//
+func (client *MemClient) processResponses(smiResponse <-chan Flit64) {
+	for {
+		respFrame := receiveFrame64(smiResponse)
+		respBytes := unpackFrame64(respFrame)
+		putFrame64(respFrame)
+		if len(respBytes) < smiMemRespHeaderSize || respBytes[3] != 0 {
+			continue
+		}
+		tagId := respBytes[2]
+		client.pendingLock.Lock()
+		result, isPending := client.pendingOps[tagId]
+		isRead := client.pendingReads[tagId]
+		delete(client.pendingOps, tagId)
+		delete(client.pendingReads, tagId)
+		client.pendingLock.Unlock()
+		if !isPending {
+			continue
+		}
+		memResult := MemResult{Ok: (respBytes[1] & 0x02) == uint8(0x00)}
+		if isRead {
+			memResult.Data = respBytes[smiMemRespHeaderSize:]
+		}
+		result <- memResult
+		client.tagFifo <- tagId
+	}
+}
@@ -0,0 +1,170 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+)
+
+//
+// MemoryModel64 is a goroutine which acts as an SMI memory endpoint for use
+// in end to end simulation, using the specified byte slice as the backing
+// memory. Write request frames are applied to the backing memory and
+// acknowledged with a write response frame. Read request frames are answered
+// with a read response frame carrying the requested bytes. The tag bytes of
+// each request are copied to its response, so the model may be used behind
+// any of the arbiters. Write requests may use the byte strobe mask layout
+// described for SmiMemStrobedBurstSize, in which case only the enabled bytes
+// are written. Requests which access memory outside the backing slice are
+// not applied and are answered with an error response frame carrying the
+// SmiMemErrAddress error code. Atomic compare and swap requests are carried
+// out as described for SmiMemCasOperandSize. Malformed write requests and
+// unsupported request types are answered with the SmiMemErrDecode error
+// code. Request frames which are too short to contain the tag bytes are
+// discarded.
+//
+func MemoryModel64(
+	smiRequest <-chan Flit64,
+	smiResponse chan<- Flit64,
+	backing []byte) {
+
+	for {
+		reqFrame := receiveFrame64(smiRequest)
+		reqBytes := unpackFrame64(reqFrame)
+		putFrame64(reqFrame)
+		if len(reqBytes) < smiMemRespHeaderSize {
+			// Discard invalid frame.
+			putFrameBytes(reqBytes)
+			continue
+		}
+		if len(reqBytes) < smiMemReqHeaderSize {
+			respBytes := errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrDecode)
+			sendRespBytes(smiResponse, respBytes)
+			putFrameBytes(reqBytes)
+			continue
+		}
+		reqAddr := frameBytesAddr(reqBytes)
+		reqLength := uint64(frameBytesLength(reqBytes))
+		isInRange := reqAddr <= uint64(len(backing)) &&
+			reqLength <= uint64(len(backing))-reqAddr
+
+		switch reqBytes[0] {
+		case SmiMemWriteReq:
+			respBytes := append(getFrameBytes(), SmiMemWriteResp, 0, reqBytes[2], reqBytes[3])
+			writeData := reqBytes[smiMemReqHeaderSize:]
+			var writeStrobes []byte
+			if reqBytes[1]&MemOptByteStrobes != 0 {
+				strobeCount := (reqLength + 7) / 8
+				if uint64(len(writeData)) >= strobeCount {
+					writeStrobes = writeData[:strobeCount]
+					writeData = writeData[strobeCount:]
+				} else {
+					writeData = nil
+				}
+			}
+			if !isInRange {
+				respBytes = errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrAddress)
+			} else if uint64(len(writeData)) < reqLength {
+				respBytes = errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrDecode)
+			} else if writeStrobes == nil {
+				copy(backing[reqAddr:], writeData[:reqLength])
+			} else {
+				for i := uint64(0); i != reqLength; i++ {
+					if writeStrobes[i/8]&(1<<(i%8)) != 0 {
+						backing[reqAddr+i] = writeData[i]
+					}
+				}
+			}
+			sendRespBytes(smiResponse, respBytes)
+
+		case SmiMemReadReq:
+			respBytes := append(getFrameBytes(), SmiMemReadResp, 0, reqBytes[2], reqBytes[3])
+			if isInRange {
+				respBytes = append(respBytes, backing[reqAddr:reqAddr+reqLength]...)
+			} else {
+				respBytes = errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrAddress)
+			}
+			sendRespBytes(smiResponse, respBytes)
+
+		case SmiMemAtomicCasReq:
+			respBytes := []byte{SmiMemAtomicCasResp, 0, reqBytes[2], reqBytes[3], 0}
+			casOperands := reqBytes[smiMemReqHeaderSize:]
+			if !isInRange {
+				respBytes = errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrAddress)
+			} else if reqLength != SmiMemCasOperandSize ||
+				len(casOperands) != 2*SmiMemCasOperandSize {
+				respBytes = errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrDecode)
+			} else {
+				priorData := backing[reqAddr : reqAddr+SmiMemCasOperandSize]
+				respBytes = append(respBytes, priorData...)
+				if bytes.Equal(priorData, casOperands[:SmiMemCasOperandSize]) {
+					respBytes[smiMemRespHeaderSize] = SmiMemCasSwapped
+					copy(priorData, casOperands[SmiMemCasOperandSize:])
+				}
+			}
+			sendRespBytes(smiResponse, respBytes)
+
+		default:
+			respBytes := errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrDecode)
+			sendRespBytes(smiResponse, respBytes)
+		}
+		putFrameBytes(reqBytes)
+	}
+}
+
+//
+// sendRespBytes packs the unpacked bytes of a response frame into flits and
+// writes them to the output channel, returning both buffers to the pools.
+//
+func sendRespBytes(smiResponse chan<- Flit64, respBytes []byte) {
+	respFrame := packFrame64(respBytes)
+	sendFrame64(smiResponse, respFrame)
+	putFrame64(respFrame)
+	putFrameBytes(respBytes)
+}
+
+//
+// Loopback64 is a goroutine which acts as a trivial SMI memory endpoint for
+// smoke testing. Each request frame is answered with a structurally valid
+// response frame, without accessing any memory. Read requests are answered
+// with zero valued data of the requested length and write requests are
+// acknowledged with a write response. The tag bytes of each request are
+// copied to its response, so the endpoint may be used behind any of the
+// arbiters. Atomic compare and swap requests can not be carried out without
+// memory, so they are answered with the SmiMemErrDecode error code in the
+// same way as for an unsupported request to MemoryModel64. Invalid and
+// unsupported request frames are discarded.
+//
+func Loopback64(
+	smiRequest <-chan Flit64,
+	smiResponse chan<- Flit64) {
+
+	for {
+		reqBytes := unpackFrame64(receiveFrame64(smiRequest))
+		if len(reqBytes) < smiMemReqHeaderSize {
+			// Discard invalid frame.
+			continue
+		}
+		switch reqBytes[0] {
+		case SmiMemWriteReq:
+			respBytes := []byte{SmiMemWriteResp, 0, reqBytes[2], reqBytes[3]}
+			sendFrame64(smiResponse, packFrame64(respBytes))
+
+		case SmiMemReadReq:
+			respBytes := []byte{SmiMemReadResp, 0, reqBytes[2], reqBytes[3]}
+			readData := make([]byte, frameBytesLength(reqBytes))
+			sendFrame64(smiResponse, packFrame64(append(respBytes, readData...)))
+
+		case SmiMemAtomicCasReq:
+			respBytes := errorRespBytes(reqBytes[2], reqBytes[3], SmiMemErrDecode)
+			sendFrame64(smiResponse, packFrame64(respBytes))
+
+		default:
+			// Discard unsupported frame.
+		}
+	}
+}
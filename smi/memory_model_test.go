@@ -0,0 +1,129 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//
+// memoryModelMaster writes a pattern to a region of memory using a sequence
+// of write requests, then reads it back and checks the read data.
+//
+func memoryModelMaster(
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64,
+	regionAddr uint64,
+	seed uint8) error {
+
+	writeData := make([]byte, 400)
+	for i := range writeData {
+		writeData[i] = seed + uint8(i*7)
+	}
+	for offset := 0; offset < len(writeData); offset += 100 {
+		sendFrame64(smiRequest, WriteReqFrames64(regionAddr+uint64(offset),
+			writeData[offset:offset+100], nil, uint8(offset)))
+		respFrame := receiveFrame64(smiResponse)
+		if respFrame[0].Data[0] != SmiMemWriteResp || respFrame[0].Data[3] != uint8(offset) {
+			return fmt.Errorf("unexpected write response %v", respFrame)
+		}
+	}
+	readData := []byte{}
+	for offset := 0; offset < len(writeData); offset += 200 {
+		sendFrame64(smiRequest, ReadReqFrames64(regionAddr+uint64(offset), 200, seed))
+		fragment, tag, err := ParseReadResp64(smiResponse)
+		if err != nil {
+			return err
+		}
+		if tag != seed {
+			return fmt.Errorf("unexpected read response tag %d", tag)
+		}
+		readData = append(readData, fragment...)
+	}
+	if !bytes.Equal(readData, writeData) {
+		return fmt.Errorf("read data mismatch at 0x%X", regionAddr)
+	}
+	return nil
+}
+
+func TestMemoryModelArbitrated(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := make([]byte, 1024)
+	go ArbitrateX2(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// Both masters run concurrently on distinct regions.
+	results := make(chan error, 2)
+	for port := 0; port != 2; port++ {
+		port := port
+		go func() {
+			results <- memoryModelMaster(upstreamRequests[port], upstreamResponses[port],
+				uint64(port*512), uint8(port*100+1))
+		}()
+	}
+	for i := 0; i != 2; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for masters")
+		}
+	}
+	if backing[400] != 0 || backing[912] != 0 {
+		t.Fatal("write outside master regions")
+	}
+}
+
+func TestLoopbackArbitrateX4(t *testing.T) {
+	upstreamRequests := make([]chan Flit64, 4)
+	upstreamResponses := make([]chan Flit64, 4)
+	for i := range upstreamRequests {
+		upstreamRequests[i] = make(chan Flit64)
+		upstreamResponses[i] = make(chan Flit64, 64)
+	}
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX4(
+		upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		upstreamRequests[2], upstreamResponses[2],
+		upstreamRequests[3], upstreamResponses[3],
+		downstreamRequest, downstreamResponse)
+	go Loopback64(downstreamRequest, downstreamResponse)
+
+	// Each port issues a write and a read with a port specific length.
+	for port := range upstreamRequests {
+		port := port
+		go func() {
+			sendFrame64(upstreamRequests[port], WriteReqFrames64(0, make([]byte, 8), nil, uint8(0x10+port)))
+			sendFrame64(upstreamRequests[port], ReadReqFrames64(0, uint32(8*port+3), uint8(0x20+port)))
+		}()
+	}
+	for port := range upstreamResponses {
+		writeResp := recvFrame(t, upstreamResponses[port])
+		if len(writeResp) != 1 || writeResp[0].Data[0] != SmiMemWriteResp ||
+			respTag(writeResp[0]) != uint16(0x10+port)<<8 {
+			t.Fatalf("unexpected write response on port %d: %v", port+1, writeResp)
+		}
+		readData, tag, err := ParseReadResp64(upstreamResponses[port])
+		if err != nil || tag != uint8(0x20+port) ||
+			!bytes.Equal(readData, make([]byte, 8*port+3)) {
+			t.Fatalf("unexpected read response on port %d: %v %d %v",
+				port+1, readData, tag, err)
+		}
+	}
+}
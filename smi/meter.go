@@ -0,0 +1,73 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Instrumentation counters which accumulate over the lifetime of a stage use
+// 64-bit unsigned values, so that they do not wrap during long running
+// tests. At one flit per clock cycle with a 500MHz clock, a 64-bit flit
+// counter takes over a thousand years to wrap, whereas a 32-bit counter
+// wraps in under nine seconds. Counters wrap modulo 2^64 if they do overflow,
+// and reported values are always cumulative totals. The number of events
+// between two reports should therefore be calculated as the unsigned
+// difference between the later and earlier values, which remains correct
+// across a single wraparound.
+//
+
+//
+// Type TrafficCounts holds the cumulative traffic counts reported by
+// MeterTraffic64.
+//
+type TrafficCounts struct {
+	FrameCount uint64
+	FlitCount  uint64
+	ByteCount  uint64
+}
+
+//
+// MeterTraffic64 is a goroutine which forwards Flit64 based SMI frames from
+// its input to its output unchanged, counting the number of frames, flits and
+// valid bytes transferred. The cumulative counts are sent on the meter output
+// channel at the end of each frame. This is a non-blocking send, so
+// intermediate reports will be discarded if the meter output channel is not
+// being serviced, but the counts themselves are never lost.
+//
+func MeterTraffic64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	meterOutput chan<- TrafficCounts) {
+
+	meterTrafficFrom64(smiInput, smiOutput, meterOutput, TrafficCounts{})
+}
+
+//
+// meterTrafficFrom64 implements MeterTraffic64, starting from the specified
+// counts. This allows counter behaviour at large values to be checked
+// without transferring billions of flits.
+//
+func meterTrafficFrom64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	meterOutput chan<- TrafficCounts,
+	trafficCounts TrafficCounts) {
+
+	for {
+		frameFlit := <-smiInput
+		smiOutput <- frameFlit
+		trafficCounts.FlitCount++
+		if frameFlit.Eofc == 0 {
+			trafficCounts.ByteCount += 8
+		} else {
+			trafficCounts.ByteCount += uint64(frameFlit.Eofc)
+			trafficCounts.FrameCount++
+			select {
+			case meterOutput <- trafficCounts:
+			default:
+			}
+		}
+	}
+}
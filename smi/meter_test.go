@@ -0,0 +1,45 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestMeterTraffic(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 8)
+	meterOutput := make(chan TrafficCounts, 8)
+	go MeterTraffic64(smiInput, smiOutput, meterOutput)
+
+	sendFrame(t, smiInput, WriteReqFrames64(0, []byte{1, 2, 3}, nil, 0))
+	counts := <-meterOutput
+	if counts != (TrafficCounts{FrameCount: 1, FlitCount: 3, ByteCount: 17}) {
+		t.Fatalf("unexpected counts %+v", counts)
+	}
+}
+
+func TestMeterTrafficPast32Bits(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 8)
+	meterOutput := make(chan TrafficCounts, 8)
+
+	// Start just below the 32-bit boundary, which is equivalent to having
+	// already metered 2^32 - 2 flits.
+	const start = uint64(1)<<32 - 2
+	go meterTrafficFrom64(smiInput, smiOutput, meterOutput,
+		TrafficCounts{FrameCount: start, FlitCount: start, ByteCount: 8 * start})
+	for i := 0; i != 2; i++ {
+		sendFrame(t, smiInput, ReadReqFrames64(0, 8, 0))
+	}
+	<-meterOutput
+	counts := <-meterOutput
+	if counts.FrameCount != start+2 || counts.FlitCount != start+4 ||
+		counts.ByteCount != 8*start+28 {
+		t.Fatalf("unexpected counts %+v", counts)
+	}
+}
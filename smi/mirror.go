@@ -0,0 +1,161 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// MirrorWrites64 is a goroutine which mirrors write requests to a pair of
+// downstream memory ports for redundancy. Each write request frame from the
+// upstream port is copied to both the primary and secondary downstream
+// ports, and a single write response is returned upstream once both
+// downstream ports have responded. The upstream write response carries the
+// combined status flags of the two downstream responses, so an error on
+// either port is reported as a failed write. Read requests are only sent to
+// the primary port, and their responses are forwarded upstream unchanged.
+//
+// The upstream tag bytes are passed unchanged to both downstream ports, which
+// maintain independent tag spaces, and are used to match the pairs of write
+// responses. The upstream master must therefore use a unique tag for each
+// outstanding request. Either response of a pair may be an error response,
+// in which case the error response is returned upstream in place of the
+// combined write response. Request flits are copied to the two ports in
+// lockstep, so a stalled secondary port will also stall the primary port.
+//
+func MirrorWrites64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	primaryRequest chan<- Flit64,
+	primaryResponse <-chan Flit64,
+	secondaryRequest chan<- Flit64,
+	secondaryResponse <-chan Flit64) {
+
+	// The tags of outstanding writes are recorded before the write requests
+	// are forwarded, so that error responses for writes can be distinguished
+	// from error responses for reads.
+	var writeLock sync.Mutex
+	pendingWrites := make(map[uint16]bool)
+
+	// Start goroutine for duplicating write requests.
+	go func() {
+		for {
+			headerFlit := <-upstreamRequest
+			isWrite := headerFlit.Data[0] == SmiMemWriteReq
+			if isWrite {
+				writeLock.Lock()
+				pendingWrites[uint16(headerFlit.Data[2])|(uint16(headerFlit.Data[3])<<8)] = true
+				writeLock.Unlock()
+			}
+			reqFlit := headerFlit
+			moreFlits := true
+			for moreFlits {
+				primaryRequest <- reqFlit
+				if isWrite {
+					secondaryRequest <- reqFlit
+				}
+				moreFlits = reqFlit.Eofc == 0
+				if moreFlits {
+					reqFlit = <-upstreamRequest
+				}
+			}
+		}
+	}()
+
+	// Combine the pairs of write responses, recording the header of the first
+	// response received for each tag.
+	pendingHeaders := make(map[uint16]Flit64)
+	for {
+		var headerFlit Flit64
+		isPrimary := true
+		select {
+		case headerFlit = <-primaryResponse:
+		case headerFlit = <-secondaryResponse:
+			isPrimary = false
+		}
+		tagId := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+		writeLock.Lock()
+		isWriteResp := pendingWrites[tagId] && (headerFlit.Data[0] == SmiMemWriteResp ||
+			headerFlit.Data[0] == SmiMemErrorResp)
+		writeLock.Unlock()
+
+		// Forward primary responses other than writes unchanged.
+		moreFlits := headerFlit.Eofc == 0
+		if isPrimary && !isWriteResp {
+			upstreamResponse <- headerFlit
+			for moreFlits {
+				bodyFlit := <-primaryResponse
+				moreFlits = bodyFlit.Eofc == 0
+				upstreamResponse <- bodyFlit
+			}
+			continue
+		}
+
+		// Discard any response body flits. Error responses carry their error
+		// code in the header flit.
+		for moreFlits {
+			var bodyFlit Flit64
+			if isPrimary {
+				bodyFlit = <-primaryResponse
+			} else {
+				bodyFlit = <-secondaryResponse
+			}
+			moreFlits = bodyFlit.Eofc == 0
+		}
+		if !isWriteResp {
+			continue
+		}
+
+		// Send the combined write response once both are available, giving
+		// precedence to error responses.
+		firstHeader, isPending := pendingHeaders[tagId]
+		if !isPending {
+			pendingHeaders[tagId] = headerFlit
+			continue
+		}
+		delete(pendingHeaders, tagId)
+		writeLock.Lock()
+		delete(pendingWrites, tagId)
+		writeLock.Unlock()
+		if firstHeader.Data[0] == SmiMemErrorResp {
+			headerFlit = firstHeader
+		}
+		if headerFlit.Data[0] == SmiMemErrorResp {
+			if headerFlit.Eofc == 0 || headerFlit.Eofc > 5 {
+				headerFlit.Eofc = 5
+			}
+		} else {
+			headerFlit.Data[1] |= firstHeader.Data[1]
+			headerFlit.Eofc = 4
+		}
+		upstreamResponse <- headerFlit
+	}
+}
+
+//
+// BroadcastWrite2 is a goroutine which broadcasts write requests to a pair of
+// downstream memory ports, such as when initialising mirrored buffers in two
+// memory banks. Each write request frame is copied to both downstream ports
+// and a single combined write response is returned upstream once both
+// writes have completed, with the status flags of both responses combined as
+// described for MirrorWrites64. Read requests are only sent to downstream
+// port A. The upstream master must use a unique tag for each outstanding
+// write.
+//
+func BroadcastWrite2(
+	upstreamRequest <-chan Flit64,
+	downstreamRequestA chan<- Flit64,
+	downstreamRequestB chan<- Flit64,
+	downstreamResponseA <-chan Flit64,
+	downstreamResponseB <-chan Flit64,
+	upstreamResponse chan<- Flit64) {
+
+	MirrorWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequestA, downstreamResponseA,
+		downstreamRequestB, downstreamResponseB)
+}
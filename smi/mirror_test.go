@@ -0,0 +1,90 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMirrorWritesSecondaryError(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	primaryRequest := make(chan Flit64)
+	primaryResponse := make(chan Flit64)
+	secondaryRequest := make(chan Flit64)
+	secondaryResponse := make(chan Flit64)
+	go MirrorWrites64(upstreamRequest, upstreamResponse,
+		primaryRequest, primaryResponse, secondaryRequest, secondaryResponse)
+	go MemoryModel64(primaryRequest, primaryResponse, make([]byte, 1024))
+	go MemoryModel64(secondaryRequest, secondaryResponse, make([]byte, 256))
+
+	// The write fails on the secondary port only, so a single error
+	// response is expected.
+	go sendFrame64(upstreamRequest, WriteReqFrames64(512, make([]byte, 8), nil, 5))
+	frame := recvFrame(t, upstreamResponse)
+	ok, errorCode := ResponseStatus(frame[0])
+	if frame[0].Data[0] != SmiMemErrorResp || ok || errorCode != SmiMemErrAddress ||
+		respTag(frame[0]) != 5<<8 || len(frame) != 1 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+
+	// Read errors on the primary port are forwarded unchanged.
+	go sendFrame64(upstreamRequest, ReadReqFrames64(2048, 8, 6))
+	frame = recvFrame(t, upstreamResponse)
+	if frame[0].Data[0] != SmiMemErrorResp || respTag(frame[0]) != 6<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+
+	// Successful writes give a single combined write response.
+	go sendFrame64(upstreamRequest, WriteReqFrames64(0, make([]byte, 8), nil, 7))
+	frame = recvFrame(t, upstreamResponse)
+	if frame[0].Data[0] != SmiMemWriteResp || respTag(frame[0]) != 7<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+}
+
+func TestBroadcastWrite2(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 16)
+	downstreamRequestA := make(chan Flit64)
+	downstreamResponseA := make(chan Flit64)
+	downstreamRequestB := make(chan Flit64)
+	downstreamResponseB := make(chan Flit64)
+	backingA := make([]byte, 1024)
+	backingB := make([]byte, 1024)
+	go BroadcastWrite2(upstreamRequest, downstreamRequestA, downstreamRequestB,
+		downstreamResponseA, downstreamResponseB, upstreamResponse)
+	go MemoryModel64(downstreamRequestA, downstreamResponseA, backingA)
+	go MemoryModel64(downstreamRequestB, downstreamResponseB, backingB)
+
+	writeData := make([]byte, 300)
+	for i := range writeData {
+		writeData[i] = uint8(i * 3)
+	}
+	go func() {
+		sendFrame64(upstreamRequest, WriteReqFrames64(0x40, writeData[:200], nil, 0x21))
+		sendFrame64(upstreamRequest, WriteReqFrames64(0x40+200, writeData[200:], nil, 0x22))
+	}()
+
+	// Each write gets a single combined response with the original tag.
+	for i := 0; i != 2; i++ {
+		frame := recvFrame(t, upstreamResponse)
+		if len(frame) != 1 || frame[0].Data[0] != SmiMemWriteResp ||
+			respTag(frame[0]) != uint16(0x21+i)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+	if !bytes.Equal(backingA[0x40:0x40+300], writeData) ||
+		!bytes.Equal(backingA, backingB) {
+		t.Fatal("mismatched backing memory contents")
+	}
+}
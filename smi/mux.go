@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// MuxFlit64 is a goroutine which provides a simple frame based multiplexer
+// for building custom SMI topologies. Each time a select value is received,
+// a single complete frame is copied from the selected input to the output.
+// A select value of zero selects input A and any other value selects input
+// B. Frames are never interleaved, since the next select value is only
+// accepted once the current frame has been copied.
+//
+func MuxFlit64(
+	smiInputA <-chan Flit64,
+	smiInputB <-chan Flit64,
+	selectInput <-chan uint8,
+	smiOutput chan<- Flit64) {
+
+	for {
+		inputSel := <-selectInput
+		var inputFlit Flit64
+		moreFlits := true
+		for moreFlits {
+			if inputSel == 0 {
+				inputFlit = <-smiInputA
+			} else {
+				inputFlit = <-smiInputB
+			}
+			smiOutput <- inputFlit
+			moreFlits = inputFlit.Eofc == 0
+		}
+	}
+}
+
+//
+// DemuxFlit64 is a goroutine which provides a simple frame based
+// demultiplexer for building custom SMI topologies. Each time a select value
+// is received, a single complete frame is copied from the input to the
+// selected output. A select value of zero selects output A and any other
+// value selects output B.
+//
+func DemuxFlit64(
+	smiInput <-chan Flit64,
+	selectOutput <-chan uint8,
+	smiOutputA chan<- Flit64,
+	smiOutputB chan<- Flit64) {
+
+	for {
+		outputSel := <-selectOutput
+		moreFlits := true
+		for moreFlits {
+			outputFlit := <-smiInput
+			if outputSel == 0 {
+				smiOutputA <- outputFlit
+			} else {
+				smiOutputB <- outputFlit
+			}
+			moreFlits = outputFlit.Eofc == 0
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+//
+// muxTestFrame builds a frame with a source specific length, with each flit
+// recording the source, frame index and flit index.
+//
+func muxTestFrame(source uint8, frameIndex int) []Flit64 {
+	frame := make([]Flit64, 1+(frameIndex*int(source+2))%5)
+	for i := range frame {
+		frame[i].Data = [8]uint8{source, uint8(frameIndex), uint8(i)}
+	}
+	frame[len(frame)-1].Eofc = 8
+	return frame
+}
+
+func TestMuxDemuxFrameAtomicity(t *testing.T) {
+	smiInputs := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	muxSelect := make(chan uint8, 64)
+	demuxSelect := make(chan uint8, 64)
+	muxOutput := make(chan Flit64)
+	demuxOutputs := []chan Flit64{make(chan Flit64, 256), make(chan Flit64, 256)}
+	go MuxFlit64(smiInputs[0], smiInputs[1], muxSelect, muxOutput)
+
+	// Both inputs are saturated, so any interleaving would be visible.
+	const frameCount = 20
+	for source := range smiInputs {
+		source := source
+		go func() {
+			for i := 0; i != frameCount; i++ {
+				sendFrame64(smiInputs[source], muxTestFrame(uint8(source), i))
+			}
+		}()
+	}
+	for i := 0; i != frameCount; i++ {
+		muxSelect <- 0
+		muxSelect <- 1
+	}
+
+	// Each multiplexed frame must be complete and come from the selected
+	// input. The frames are then passed through the demultiplexer.
+	demuxInput := make(chan Flit64)
+	go DemuxFlit64(demuxInput, demuxSelect, demuxOutputs[0], demuxOutputs[1])
+	for i := 0; i != 2*frameCount; i++ {
+		source := uint8(i % 2)
+		expect := muxTestFrame(source, i/2)
+		frame := recvFrame(t, muxOutput)
+		if len(frame) != len(expect) {
+			t.Fatalf("frame %d: unexpected length %d", i, len(frame))
+		}
+		for j := range frame {
+			if frame[j] != expect[j] {
+				t.Fatalf("frame %d: unexpected flit %d %v", i, j, frame[j])
+			}
+		}
+		demuxSelect <- source
+		sendFrame(t, demuxInput, frame)
+	}
+	for source := range demuxOutputs {
+		for i := 0; i != frameCount; i++ {
+			expect := muxTestFrame(uint8(source), i)
+			frame := recvFrame(t, demuxOutputs[source])
+			if !reflect.DeepEqual(frame, expect) {
+				t.Fatalf("output %d frame %d: unexpected frame %v", source, i, frame)
+			}
+		}
+	}
+}
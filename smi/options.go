@@ -0,0 +1,101 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Constants specifying additional SMI memory access options.
+//
+const (
+	MemOptByteStrobes = uint8(0x04) // Write request carries byte strobes.
+)
+
+//
+// Write requests with the byte strobe option set carry a strobe mask between
+// the header and the write data. The strobe mask holds one byte for each
+// (possibly partial) 8 byte beat of write data, with bit N of each mask byte
+// enabling byte N of the corresponding beat. Only the enabled bytes are
+// written to memory. The length field continues to specify the number of
+// write data bytes, excluding the strobe mask. The amount of write data in a
+// single frame with byte strobes is limited to SmiMemStrobedBurstSize so that
+// the frame does not exceed SmiMemFrame64Size flits.
+//
+const SmiMemStrobedBurstSize = SmiMemBurstSize * 7 / 8
+
+//
+// Mask specifying all the memory access option bits that are currently
+// defined. The remaining bits in the options byte are reserved.
+//
+const memOptMask = MemOptUnbuffered | MemOptByteStrobes
+
+//
+// Type Options provides a typed representation of the SMI memory access
+// options byte. The zero value corresponds to DefaultOptions, and individual
+// options can be combined using the option methods. For example:
+//
+//     optionsByte := smi.Options{}.Unbuffered().ByteStrobes().Byte()
+//
+type Options struct {
+	optionBits uint8
+}
+
+//
+// ParseOptions converts an SMI memory access options byte to the typed
+// representation. The boolean 'parseOk' flag will be false if any of the
+// reserved option bits are set, in which case they are discarded.
+//
+func ParseOptions(optionsByte uint8) (Options, bool) {
+	parseOk := (optionsByte & ^memOptMask) == uint8(0)
+	return Options{optionBits: optionsByte & memOptMask}, parseOk
+}
+
+//
+// Unbuffered returns a copy of the options with direct unbuffered memory
+// access selected.
+//
+func (options Options) Unbuffered() Options {
+	options.optionBits |= MemOptUnbuffered
+	return options
+}
+
+//
+// ByteStrobes returns a copy of the options with byte strobes selected. This
+// is only meaningful for write requests.
+//
+func (options Options) ByteStrobes() Options {
+	options.optionBits |= MemOptByteStrobes
+	return options
+}
+
+//
+// IsUnbuffered indicates whether direct unbuffered memory access is selected.
+//
+func (options Options) IsUnbuffered() bool {
+	return (options.optionBits & MemOptUnbuffered) != uint8(0)
+}
+
+//
+// IsByteStrobes indicates whether the write data carries byte strobes.
+//
+func (options Options) IsByteStrobes() bool {
+	return (options.optionBits & MemOptByteStrobes) != uint8(0)
+}
+
+//
+// ValidFor checks whether the options may be used with the specified request
+// frame type. Byte strobes are not valid for read requests.
+//
+func (options Options) ValidFor(frameType uint8) bool {
+	return !(frameType == SmiMemReadReq && options.IsByteStrobes())
+}
+
+//
+// Byte returns the SMI memory access options byte for use in the request
+// frame header and the memory access functions.
+//
+func (options Options) Byte() uint8 {
+	return options.optionBits
+}
@@ -0,0 +1,35 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestOptionsBuilder(t *testing.T) {
+	optionsByte := Options{}.Unbuffered().ByteStrobes().Byte()
+	if optionsByte != MemOptUnbuffered|MemOptByteStrobes {
+		t.Fatalf("unexpected options byte 0x%02X", optionsByte)
+	}
+	if (Options{}).Byte() != DefaultOptions {
+		t.Fatal("zero options do not match the default options")
+	}
+	options, parseOk := ParseOptions(optionsByte)
+	if !parseOk || !options.IsUnbuffered() || !options.IsByteStrobes() {
+		t.Fatalf("options byte 0x%02X not parsed", optionsByte)
+	}
+	if options.ValidFor(SmiMemReadReq) || !options.ValidFor(SmiMemWriteReq) {
+		t.Fatal("byte strobes accepted for read requests")
+	}
+}
+
+func TestParseOptionsReserved(t *testing.T) {
+	options, parseOk := ParseOptions(0x82)
+	if parseOk || options.Byte() != 0 {
+		t.Fatalf("reserved bits accepted, giving 0x%02X", options.Byte())
+	}
+}
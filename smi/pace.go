@@ -0,0 +1,41 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// PaceFrames64 is a goroutine which forwards Flit64 based SMI frames from its
+// input to its output, inserting an idle gap between consecutive frames. The
+// SMI protocol has no null flit, so the gap is implemented as idle time rather
+// than explicit idle flits. After the final flit of each frame has been
+// forwarded, the next frame is held until the specified number of ticks has
+// been received on the tick channel. A gap size of zero forwards frames
+// back to back. This is intended for use on the downstream request path to
+// satisfy the timing requirements of memory controllers which can not accept
+// back to back frames.
+//
+func PaceFrames64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	tick <-chan struct{},
+	gapTicks uint32) {
+
+	for {
+
+		// Copy the frame from input to output.
+		moreFlits := true
+		for moreFlits {
+			frameFlit := <-smiInput
+			smiOutput <- frameFlit
+			moreFlits = frameFlit.Eofc == 0
+		}
+
+		// Insert the idle gap.
+		for i := gapTicks; i != 0; i-- {
+			<-tick
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Type FlitPeeker wraps a Flit64 input channel and provides a single flit of
+// lookahead. This allows routing and validation logic to inspect the header
+// flit of a frame, typically the frame type byte, before deciding where the
+// frame should be sent.
+//
+type FlitPeeker struct {
+	smiInput  <-chan Flit64
+	peekFlit  Flit64
+	peekValid bool
+}
+
+//
+// NewFlitPeeker creates a new flit lookahead wrapper for the specified input
+// channel. The wrapper must be the only reader of the input channel.
+//
+func NewFlitPeeker(smiInput <-chan Flit64) FlitPeeker {
+	return FlitPeeker{smiInput: smiInput}
+}
+
+//
+// Peek returns the next flit from the input channel without consuming it.
+// Repeated calls return the same flit until Next is called. If no flit is
+// currently buffered this blocks until one is available on the input.
+//
+func (peeker *FlitPeeker) Peek() Flit64 {
+	if !peeker.peekValid {
+		peeker.peekFlit = <-peeker.smiInput
+		peeker.peekValid = true
+	}
+	return peeker.peekFlit
+}
+
+//
+// Next returns the next flit from the input channel and consumes it. This
+// will return the buffered flit if one has previously been peeked.
+//
+func (peeker *FlitPeeker) Next() Flit64 {
+	nextFlit := peeker.Peek()
+	peeker.peekValid = false
+	return nextFlit
+}
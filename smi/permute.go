@@ -0,0 +1,74 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// TagLatency returns a deterministic simulated latency for the response frame
+// with the specified header flit, derived from its tag bytes. The local tag
+// byte (Data[3]) is bit reversed so that consecutively allocated tags are
+// given widely separated latencies, and the result is combined with the port
+// ID byte (Data[2]) so that responses for different ports are interleaved.
+//
+func TagLatency(headerFlit Flit64) uint8 {
+	tagByte := headerFlit.Data[3]
+	tagByte = (tagByte >> 4) | (tagByte << 4)
+	tagByte = ((tagByte & 0xCC) >> 2) | ((tagByte & 0x33) << 2)
+	tagByte = ((tagByte & 0xAA) >> 1) | ((tagByte & 0x55) << 1)
+	return tagByte ^ headerFlit.Data[2]
+}
+
+//
+// PermuteResponses64 is a goroutine for use in simulation which reorders the
+// response frames from a memory model or loopback responder in a
+// reproducible way. Response frames are collected in windows of the
+// specified size, and each complete window is released in order of
+// increasing TagLatency, with equal latencies being released in arrival
+// order. Since the release order only depends on the tags, this allows exact
+// output tests to be written for response reordering logic. Frames are only
+// released once a window is full, so the number of issued requests must be a
+// multiple of the window size. Any frames in a partially filled window are
+// held until further responses complete the window, since flushing them on a
+// timeout would make the release order depend on scheduling. Window sizes of
+// less than one are treated as one, which forwards frames unchanged.
+//
+func PermuteResponses64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	windowSize int) {
+
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	frameWindow := make([][]Flit64, windowSize)
+	for {
+
+		// Collect a complete window of response frames.
+		for frameIndex := 0; frameIndex != windowSize; frameIndex++ {
+			frame := frameWindow[frameIndex][:0]
+			moreFlits := true
+			for moreFlits {
+				respFlit := <-smiInput
+				frame = append(frame, respFlit)
+				moreFlits = respFlit.Eofc == 0
+			}
+			frameWindow[frameIndex] = frame
+		}
+
+		// Release the frames in latency order using a stable insertion sort.
+		for i := 1; i < windowSize; i++ {
+			for j := i; j > 0 &&
+				TagLatency(frameWindow[j][0]) < TagLatency(frameWindow[j-1][0]); j-- {
+				frameWindow[j], frameWindow[j-1] = frameWindow[j-1], frameWindow[j]
+			}
+		}
+		for _, frame := range frameWindow {
+			for _, respFlit := range frame {
+				smiOutput <- respFlit
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// permuteRespFrame builds a header only write response frame with the
+// specified port ID and local tag bytes.
+//
+func permuteRespFrame(portId uint8, tagId uint8) []Flit64 {
+	return []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, portId, tagId}, Eofc: 4}}
+}
+
+func TestPermuteResponsesOrder(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 8)
+	go PermuteResponses64(smiInput, smiOutput, 4)
+
+	// Tags 0 to 3 have bit reversed latencies of 0x00, 0x80, 0x40 and 0xC0.
+	go func() {
+		for tagId := uint8(0); tagId != 4; tagId++ {
+			sendFrame64(smiInput, permuteRespFrame(1, tagId))
+		}
+	}()
+	for _, tagId := range []uint8{0, 2, 1, 3} {
+		if frame := recvFrame(t, smiOutput); frame[0].Data[3] != tagId {
+			t.Fatalf("unexpected frame %v, expected tag %d", frame, tagId)
+		}
+	}
+}
+
+func TestPermuteResponsesPartialWindow(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 8)
+	go PermuteResponses64(smiInput, smiOutput, 2)
+
+	// A single frame is held until the window is completed.
+	sendFrame(t, smiInput, permuteRespFrame(1, 1))
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	sendFrame(t, smiInput, permuteRespFrame(1, 0))
+	for _, tagId := range []uint8{0, 1} {
+		if frame := recvFrame(t, smiOutput); frame[0].Data[3] != tagId {
+			t.Fatalf("unexpected frame %v, expected tag %d", frame, tagId)
+		}
+	}
+}
+
+func TestPermuteResponsesInvalidWindow(t *testing.T) {
+	for _, windowSize := range []int{0, -1} {
+		smiInput := make(chan Flit64)
+		smiOutput := make(chan Flit64, 8)
+		go PermuteResponses64(smiInput, smiOutput, windowSize)
+		sendFrame(t, smiInput, permuteRespFrame(1, 1))
+		if frame := recvFrame(t, smiOutput); frame[0].Data[3] != 1 {
+			t.Fatalf("window size %d: unexpected frame %v", windowSize, frame)
+		}
+	}
+}
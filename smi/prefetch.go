@@ -0,0 +1,105 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Prefetch64 is a goroutine which reads a contiguous region of memory for a
+// strictly sequential consumer, hiding the memory latency by keeping several
+// read requests in flight ahead of the consumer. The region is read using
+// bursts of up to SmiMemBurstSize bytes, with up to the specified number of
+// bursts being outstanding at any time. This is limited to the range 1 to
+// SmiMemInFlightLimit. The read data is delivered in order on the output
+// channel, with each Flit64 carrying eight consecutive bytes and the Eofc
+// field of the final flit holding the number of valid bytes in that flit, as
+// for the payload output of StreamReadResponse64. The local tag in byte 3 of
+// each request header identifies the burst, so responses may be returned out
+// of order. Failed or short bursts are padded with zero valued data. The
+// goroutine exits once the entire region has been delivered. Zero length
+// regions do not generate any output.
+//
+func Prefetch64(
+	readAddr uint64,
+	readLength uint32,
+	aheadBursts int,
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64,
+	dataOutput chan<- Flit64) {
+
+	if aheadBursts < 1 {
+		aheadBursts = 1
+	} else if aheadBursts > SmiMemInFlightLimit {
+		aheadBursts = SmiMemInFlightLimit
+	}
+	burstCount := (readLength + SmiMemBurstSize - 1) / SmiMemBurstSize
+	burstLength := func(burstIndex uint32) uint32 {
+		if burstIndex == burstCount-1 {
+			return readLength - burstIndex*SmiMemBurstSize
+		}
+		return SmiMemBurstSize
+	}
+
+	// Issue read requests, waiting for a free slot before each one.
+	freeSlots := make(chan struct{}, aheadBursts)
+	for i := 0; i != aheadBursts; i++ {
+		freeSlots <- struct{}{}
+	}
+	go func() {
+		for burstIndex := uint32(0); burstIndex != burstCount; burstIndex++ {
+			<-freeSlots
+			sendFrame64(smiRequest, packFrame64(readReqBytes(
+				readAddr+uint64(burstIndex)*SmiMemBurstSize, DefaultOptions,
+				0, uint8(burstIndex%SmiMemInFlightLimit),
+				uint16(burstLength(burstIndex)))))
+		}
+	}()
+
+	// Collect the responses, delivering the burst data in order.
+	heldData := make(map[uint8][]byte)
+	var outputData []byte
+	for burstIndex := uint32(0); burstIndex != burstCount; burstIndex++ {
+		tagId := uint8(burstIndex % SmiMemInFlightLimit)
+		burstData, isHeld := heldData[tagId]
+		for !isHeld {
+			respBytes := unpackFrame64(receiveFrame64(smiResponse))
+			if len(respBytes) < smiMemRespHeaderSize {
+				// Discard invalid frame.
+				continue
+			}
+			respData := []byte{}
+			if respBytes[0] == SmiMemReadResp && (respBytes[1]&0x02) == uint8(0x00) {
+				respData = respBytes[smiMemRespHeaderSize:]
+			}
+			heldData[respBytes[3]] = respData
+			burstData, isHeld = heldData[tagId]
+		}
+		delete(heldData, tagId)
+		freeSlots <- struct{}{}
+
+		// Pad or truncate the burst data to the expected length.
+		expectedLength := int(burstLength(burstIndex))
+		if len(burstData) > expectedLength {
+			burstData = burstData[:expectedLength]
+		}
+		outputData = append(outputData, burstData...)
+		outputData = append(outputData, make([]byte, expectedLength-len(burstData))...)
+
+		// Send all the complete output flits, holding back the final flit
+		// until the end of the region so that it can be marked.
+		isLastBurst := burstIndex == burstCount-1
+		for len(outputData) > 8 || (isLastBurst && len(outputData) != 0) {
+			outputFlit := Flit64{}
+			copy(outputFlit.Data[:], outputData)
+			if len(outputData) <= 8 {
+				outputFlit.Eofc = uint8(len(outputData))
+				outputData = nil
+			} else {
+				outputData = outputData[8:]
+			}
+			dataOutput <- outputFlit
+		}
+	}
+}
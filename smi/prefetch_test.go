@@ -0,0 +1,100 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefetchMemoryModel(t *testing.T) {
+	backing := make([]byte, 4096)
+	for i := range backing {
+		backing[i] = uint8(i * 13)
+	}
+	for _, aheadBursts := range []int{1, 2, 4, 8} {
+		smiRequest := make(chan Flit64)
+		smiResponse := make(chan Flit64)
+		modelRequest := make(chan Flit64)
+		modelResponse := make(chan Flit64)
+		dataOutput := make(chan Flit64)
+		go MemoryModel64(modelRequest, modelResponse, backing)
+
+		// Count the outstanding requests between the prefetcher and the
+		// memory model, which must never exceed the configured limit.
+		var countLock sync.Mutex
+		inFlight := 0
+		maxInFlight := 0
+		go func() {
+			for {
+				frame := receiveFrame64(smiRequest)
+				countLock.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				countLock.Unlock()
+				sendFrame64(modelRequest, frame)
+			}
+		}()
+		go func() {
+			for {
+				frame := receiveFrame64(modelResponse)
+				countLock.Lock()
+				inFlight--
+				countLock.Unlock()
+				sendFrame64(smiResponse, frame)
+			}
+		}()
+
+		go Prefetch64(100, 3000, aheadBursts, smiRequest, smiResponse, dataOutput)
+		readData := []byte{}
+		moreFlits := true
+		for moreFlits {
+			dataFlit := recvFlit(t, dataOutput)
+			readData = append(readData, dataFlit.Data[:dataFlit.PayloadLen()]...)
+			moreFlits = dataFlit.Eofc == 0
+		}
+		if !bytes.Equal(readData, backing[100:3100]) {
+			t.Fatalf("ahead %d: read data mismatch", aheadBursts)
+		}
+		countLock.Lock()
+		expectMax := aheadBursts
+		if expectMax > SmiMemInFlightLimit {
+			expectMax = SmiMemInFlightLimit
+		}
+		if maxInFlight > expectMax {
+			t.Errorf("ahead %d: %d requests in flight, expected %d",
+				aheadBursts, maxInFlight, expectMax)
+		}
+		countLock.Unlock()
+	}
+}
+
+func TestPrefetchWithheldResponses(t *testing.T) {
+	smiRequest := make(chan Flit64, 64)
+	smiResponse := make(chan Flit64)
+	dataOutput := make(chan Flit64, 256)
+	go Prefetch64(0, 8*SmiMemBurstSize, 3, smiRequest, smiResponse, dataOutput)
+
+	// Exactly the configured number of bursts is requested ahead.
+	reqHeaders := []Flit64{}
+	for i := 0; i != 3; i++ {
+		reqHeaders = append(reqHeaders, recvFrame(t, smiRequest)[0])
+	}
+	expectIdle(t, smiRequest, 10*time.Millisecond)
+
+	// Completing the first burst releases a slot for the next request.
+	sendFrame(t, smiResponse, packFrame64(append(
+		[]byte{SmiMemReadResp, 0, 0, reqHeaders[0].Data[3]}, make([]byte, SmiMemBurstSize)...)))
+	if frame := recvFrame(t, smiRequest); frame[0].Data[3] != 3 {
+		t.Fatalf("unexpected request %v", frame)
+	}
+	expectIdle(t, smiRequest, 10*time.Millisecond)
+}
@@ -18,6 +18,10 @@ const (
 	SmiMemWriteResp = 0xFE // SMI memory write response.
 	SmiMemReadReq   = 0x02 // SMI memory read request.
 	SmiMemReadResp  = 0xFD // SMI memory read response.
+	SmiMemErrorResp = 0xFC // SMI memory error response.
+
+	SmiMemAtomicCasReq  = 0x04 // SMI atomic compare and swap request.
+	SmiMemAtomicCasResp = 0xFB // SMI atomic compare and swap response.
 )
 
 //
@@ -46,6 +50,43 @@ const SmiMemBurstSize = 256
 //
 const SmiMemFrame64Size = 2 + SmiMemBurstSize/8
 
+//
+// The maximum frame size for the 128-bit datapath is derived from the
+// SmiMemBurstSize parameter in the same way, with the header information
+// occupying a single additional flit.
+//
+const SmiMemFrame128Size = 1 + SmiMemBurstSize/16
+
+//
+// The maximum frame size for the 32-bit datapath is derived from the
+// SmiMemBurstSize parameter in the same way, with the header information
+// occupying up to four additional flits.
+//
+const SmiMemFrame32Size = 4 + SmiMemBurstSize/4
+
+//
+// Frame buffers which are implemented as channels or arrays must currently
+// have their sizes specified as integer literals, which are annotated with
+// the corresponding frame size constant. The literal values are recorded
+// here, and the following declarations will fail to compile if they no
+// longer match the frame sizes derived from SmiMemBurstSize. Any change to
+// the burst size must therefore be accompanied by updating these values and
+// all the annotated literals which use them.
+// TODO: Remove once there is a fix for the channel size compiler limitation.
+//
+const (
+	smiMemFrame64Literal  = 34
+	smiMemFrame128Literal = 17
+	smiMemFrame32Literal  = 68
+)
+
+var _ [smiMemFrame64Literal - SmiMemFrame64Size]struct{}
+var _ [SmiMemFrame64Size - smiMemFrame64Literal]struct{}
+var _ [smiMemFrame128Literal - SmiMemFrame128Size]struct{}
+var _ [SmiMemFrame128Size - smiMemFrame128Literal]struct{}
+var _ [smiMemFrame32Literal - SmiMemFrame32Size]struct{}
+var _ [SmiMemFrame32Size - smiMemFrame32Literal]struct{}
+
 //
 // Specify the number of in-flight transactions supported by each
 // arbitrated SMI port.
@@ -53,13 +94,61 @@ const SmiMemFrame64Size = 2 + SmiMemBurstSize/8
 const SmiMemInFlightLimit = 4
 
 //
-// Type Flit64 specifies an SMI flit format with a 64-bit datapath.
+// Type Flit64 specifies an SMI flit format with a 64-bit datapath. The Eofc
+// field is zero for all flits other than the last in a frame and is set to
+// the number of valid bytes in the range 1 to 8 for the last flit. Frames may
+// consist of a single header flit with the Eofc field set, such as a short
+// response frame, so frame handling components must always check the Eofc
+// field of the header flit before reading any further flits.
 //
 type Flit64 struct {
 	Data [8]uint8
 	Eofc uint8
 }
 
+//
+// PayloadLen returns the number of valid data bytes in the flit. This is
+// always 8 for flits which are not the last in a frame (Eofc is zero). For
+// the last flit in a frame it is the number of valid bytes specified by the
+// Eofc field, with invalid Eofc values greater than 8 being treated as 8.
+//
+func (f Flit64) PayloadLen() int {
+	if f.Eofc == 0 || f.Eofc > 8 {
+		return 8
+	}
+	return int(f.Eofc)
+}
+
+//
+// IsLast returns true if the flit is the last flit in a frame.
+//
+func (f Flit64) IsLast() bool {
+	return f.Eofc != 0
+}
+
+//
+// Type Flit128 specifies an SMI flit format with a 128-bit datapath. The Eofc
+// field has the same meaning as for Flit64, being zero for all flits other
+// than the last in a frame and set to the number of valid bytes in the range
+// 1 to 16 for the last flit.
+//
+type Flit128 struct {
+	Data [16]uint8
+	Eofc uint8
+}
+
+//
+// Type Flit32 specifies an SMI flit format with a 32-bit datapath, for use
+// in resource constrained designs. The Eofc field has the same meaning as for
+// Flit64, being zero for all flits other than the last in a frame and set to
+// the number of valid bytes in the range 1 to 4 for the last flit. The frame
+// type, options and tag bytes of the header all fit in the first flit.
+//
+type Flit32 struct {
+	Data [4]uint8
+	Eofc uint8
+}
+
 //
 // Forwards a single Flit64 based SMI frame from an input channel to an output
 // channel with intermediate buffering. The buffer has capacity to store a
@@ -96,6 +185,43 @@ func ForwardFrame64(
 	}
 }
 
+//
+// ForwardFrame64N is a variant of ForwardFrame64 which uses the specified
+// buffer capacity in flits instead of SmiMemFrame64Size. This allows frames
+// with bursts larger than SmiMemBurstSize to be fully buffered. Since the
+// buffer size is not an integer literal, this is only suitable for use in
+// simulation.
+//
+func ForwardFrame64N(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	forwardDone chan<- bool,
+	maxFrameSize int) {
+	smiBuffer := make(chan Flit64, maxFrameSize)
+
+	doForward := <-forwardReq
+	for doForward {
+		go func() {
+			hasNextInputFlit := true
+			for hasNextInputFlit {
+				inputFlitData := <-smiInput
+				smiBuffer <- inputFlitData
+				hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+			}
+		}()
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		doForward = <-forwardReq
+	}
+}
+
 //
 // Assembles a single Flit64 based SMI frame from an input channel, copying the
 // frame to the output channel once the entire frame has been received. The
@@ -131,6 +257,250 @@ func AssembleFrame64(
 	}
 }
 
+//
+// AssembleFrame64N is a variant of AssembleFrame64 which uses the specified
+// maximum frame size in flits instead of SmiMemFrame64Size. Frames which are
+// longer than the maximum frame size can not be assembled and will cause the
+// input to stall, so this must be used for frames with bursts larger than
+// SmiMemBurstSize. Since the buffer size is not an integer literal, this is
+// only suitable for use in simulation.
+//
+func AssembleFrame64N(
+	assembleReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	assembleDone chan<- bool,
+	maxFrameSize int) {
+	smiBuffer := make(chan Flit64, maxFrameSize)
+
+	doAssemble := <-assembleReq
+	for doAssemble {
+		hasNextInputFlit := true
+		for hasNextInputFlit {
+			inputFlitData := <-smiInput
+			smiBuffer <- inputFlitData
+			hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+		}
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		assembleDone <- true
+		doAssemble = <-assembleReq
+	}
+}
+
+//
+// Passes a single Flit64 based SMI frame directly from an input channel to an
+// output channel, using the same request and completion handshake as
+// ForwardFrame64. No buffering is used and no additional goroutine is
+// started, so each flit is only accepted from the input once the previous
+// flit has been accepted by the output. This provides no decoupling between
+// the input and output, so any backpressure on the output stalls the input
+// immediately. It should only be used in place of ForwardFrame64 for tightly
+// coupled stages where isolation from backpressure is not required.
+//
+func PassThrough64(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	forwardDone chan<- bool) {
+
+	doForward := <-forwardReq
+	for doForward {
+		hasNextFlit := true
+		for hasNextFlit {
+			flitData := <-smiInput
+			smiOutput <- flitData
+			hasNextFlit = flitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		doForward = <-forwardReq
+	}
+}
+
+//
+// Forwards a single Flit64 based SMI frame from an input channel to an output
+// channel, using the specified memory access options to select the amount of
+// intermediate buffering. If the MemOptUnbuffered option is set, the full
+// frame buffer is omitted and the flits are forwarded via a single element
+// handoff buffer, which reduces the latency for control traffic. Otherwise
+// the behaviour is the same as ForwardFrame64.
+//
+func ForwardFrame64Opt(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	forwardDone chan<- bool,
+	forwardOptions uint8) {
+
+	if forwardOptions&MemOptUnbuffered == uint8(0) {
+		ForwardFrame64(forwardReq, smiInput, smiOutput, forwardDone)
+		return
+	}
+	smiBuffer := make(chan Flit64, 1)
+
+	doForward := <-forwardReq
+	for doForward {
+		go func() {
+			hasNextInputFlit := true
+			for hasNextInputFlit {
+				inputFlitData := <-smiInput
+				smiBuffer <- inputFlitData
+				hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+			}
+		}()
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		doForward = <-forwardReq
+	}
+}
+
+//
+// Forwards a single Flit128 based SMI frame from an input channel to an output
+// channel with intermediate buffering. This is the 128-bit datapath
+// equivalent of ForwardFrame64.
+// TODO: Update once there is a fix for the channel size compiler limitation.
+//
+func ForwardFrame128(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit128,
+	smiOutput chan<- Flit128,
+	forwardDone chan<- bool) {
+	smiBuffer := make(chan Flit128, 17 /* SmiMemFrame128Size */)
+
+	doForward := <-forwardReq
+	for doForward {
+		go func() {
+			hasNextInputFlit := true
+			for hasNextInputFlit {
+				inputFlitData := <-smiInput
+				smiBuffer <- inputFlitData
+				hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+			}
+		}()
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		doForward = <-forwardReq
+	}
+}
+
+//
+// Assembles a single Flit128 based SMI frame from an input channel, copying
+// the frame to the output channel once the entire frame has been received.
+// This is the 128-bit datapath equivalent of AssembleFrame64.
+// TODO: Update once there is a fix for the channel size compiler limitation.
+//
+func AssembleFrame128(
+	assembleReq <-chan bool,
+	smiInput <-chan Flit128,
+	smiOutput chan<- Flit128,
+	assembleDone chan<- bool) {
+	smiBuffer := make(chan Flit128, 17 /* SmiMemFrame128Size */)
+
+	doAssemble := <-assembleReq
+	for doAssemble {
+		hasNextInputFlit := true
+		for hasNextInputFlit {
+			inputFlitData := <-smiInput
+			smiBuffer <- inputFlitData
+			hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+		}
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		assembleDone <- true
+		doAssemble = <-assembleReq
+	}
+}
+
+//
+// Forwards a single Flit32 based SMI frame from an input channel to an output
+// channel with intermediate buffering. This is the 32-bit datapath
+// equivalent of ForwardFrame64.
+// TODO: Update once there is a fix for the channel size compiler limitation.
+//
+func ForwardFrame32(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit32,
+	smiOutput chan<- Flit32,
+	forwardDone chan<- bool) {
+	smiBuffer := make(chan Flit32, 68 /* SmiMemFrame32Size */)
+
+	doForward := <-forwardReq
+	for doForward {
+		go func() {
+			hasNextInputFlit := true
+			for hasNextInputFlit {
+				inputFlitData := <-smiInput
+				smiBuffer <- inputFlitData
+				hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+			}
+		}()
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		doForward = <-forwardReq
+	}
+}
+
+//
+// Assembles a single Flit32 based SMI frame from an input channel, copying
+// the frame to the output channel once the entire frame has been received.
+// This is the 32-bit datapath equivalent of AssembleFrame64.
+// TODO: Update once there is a fix for the channel size compiler limitation.
+//
+func AssembleFrame32(
+	assembleReq <-chan bool,
+	smiInput <-chan Flit32,
+	smiOutput chan<- Flit32,
+	assembleDone chan<- bool) {
+	smiBuffer := make(chan Flit32, 68 /* SmiMemFrame32Size */)
+
+	doAssemble := <-assembleReq
+	for doAssemble {
+		hasNextInputFlit := true
+		for hasNextInputFlit {
+			inputFlitData := <-smiInput
+			smiBuffer <- inputFlitData
+			hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+		}
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		assembleDone <- true
+		doAssemble = <-assembleReq
+	}
+}
+
 //
 // Package arbitrate provides reusable arbitrators for SMI transactions.
 //
@@ -218,6 +588,28 @@ func ArbitrateX2(
 	upstreamResponseB chan<- Flit64,
 	downstreamRequest chan<- Flit64,
 	downstreamResponse <-chan Flit64) {
+	ArbitrateX2WithDropped(upstreamRequestA, upstreamResponseA,
+		upstreamRequestB, upstreamResponseB, downstreamRequest,
+		downstreamResponse, nil)
+}
+
+//
+// ArbitrateX2WithDropped is a variant of ArbitrateX2 which reports any
+// response flits that can not be steered to an upstream port, because the
+// port ID in byte 2 of the response header is not valid. Such flits are sent
+// on the dropped flit channel instead of being silently discarded. This is a
+// non-blocking send, so dropped flits will still be discarded if the dropped
+// flit channel is not being serviced. If the dropped flit channel is nil,
+// invalid flits are discarded in the same way as for ArbitrateX2.
+//
+func ArbitrateX2WithDropped(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	dropped chan<- Flit64) {
 
 	// Define local channel connections.
 	taggedRequestA := make(chan Flit64, 1)
@@ -274,7 +666,11 @@ func ArbitrateX2(
 		case 2:
 			taggedResponseB <- respFlit
 		default:
-			// Discard invalid flit.
+			// Report invalid flit.
+			select {
+			case dropped <- respFlit:
+			default:
+			}
 		}
 		isHeaderFlit = respFlit.Eofc != 0
 	}
@@ -295,6 +691,30 @@ func ArbitrateX3(
 	upstreamResponseC chan<- Flit64,
 	downstreamRequest chan<- Flit64,
 	downstreamResponse <-chan Flit64) {
+	ArbitrateX3WithDropped(upstreamRequestA, upstreamResponseA,
+		upstreamRequestB, upstreamResponseB, upstreamRequestC,
+		upstreamResponseC, downstreamRequest, downstreamResponse, nil)
+}
+
+//
+// ArbitrateX3WithDropped is a variant of ArbitrateX3 which reports any
+// response flits that can not be steered to an upstream port, because the
+// port ID in byte 2 of the response header is not valid. Such flits are sent
+// on the dropped flit channel instead of being silently discarded. This is a
+// non-blocking send, so dropped flits will still be discarded if the dropped
+// flit channel is not being serviced. If the dropped flit channel is nil,
+// invalid flits are discarded in the same way as for ArbitrateX3.
+//
+func ArbitrateX3WithDropped(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	dropped chan<- Flit64) {
 
 	// Define local channel connections.
 	taggedRequestA := make(chan Flit64, 1)
@@ -361,7 +781,11 @@ func ArbitrateX3(
 		case 3:
 			taggedResponseC <- respFlit
 		default:
-			// Discard invalid flit.
+			// Report invalid flit.
+			select {
+			case dropped <- respFlit:
+			default:
+			}
 		}
 		isHeaderFlit = respFlit.Eofc != 0
 	}
@@ -384,6 +808,33 @@ func ArbitrateX4(
 	upstreamResponseD chan<- Flit64,
 	downstreamRequest chan<- Flit64,
 	downstreamResponse <-chan Flit64) {
+	ArbitrateX4WithDropped(upstreamRequestA, upstreamResponseA,
+		upstreamRequestB, upstreamResponseB, upstreamRequestC,
+		upstreamResponseC, upstreamRequestD, upstreamResponseD,
+		downstreamRequest, downstreamResponse, nil)
+}
+
+//
+// ArbitrateX4WithDropped is a variant of ArbitrateX4 which reports any
+// response flits that can not be steered to an upstream port, because the
+// port ID in byte 2 of the response header is not valid. Such flits are sent
+// on the dropped flit channel instead of being silently discarded. This is a
+// non-blocking send, so dropped flits will still be discarded if the dropped
+// flit channel is not being serviced. If the dropped flit channel is nil,
+// invalid flits are discarded in the same way as for ArbitrateX4.
+//
+func ArbitrateX4WithDropped(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	upstreamRequestD <-chan Flit64,
+	upstreamResponseD chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	dropped chan<- Flit64) {
 
 	// Define local channel connections.
 	taggedRequestA := make(chan Flit64, 1)
@@ -460,12 +911,25 @@ func ArbitrateX4(
 		case 4:
 			taggedResponseD <- respFlit
 		default:
-			// Discard invalid flit.
+			// Report invalid flit.
+			select {
+			case dropped <- respFlit:
+			default:
+			}
 		}
 		isHeaderFlit = respFlit.Eofc != 0
 	}
 }
 
+//
+// The arbiters for five to eight pairs of SMI request/response channels
+// (ArbitrateX5 to ArbitrateX8) follow the same pattern as ArbitrateX4 and are
+// generated from the arbitrate.go.tmpl template. Variants of ArbitrateX2 with
+// in-flight transaction limits other than SmiMemInFlightLimit (such as
+// ArbitrateX2Depth1) are generated from the arbitrate_depth.go.tmpl template.
+//
+//go:generate go run gen_arbitrate.go
+
 //
 // Package smi/memory provides high level operations for SMI access to memory
 // mapped RAM and I/O. This defines the memory access functions to support
@@ -757,9 +1221,15 @@ func ReadUInt64(
 	smiRequest <- reqFlit1
 	smiRequest <- reqFlit2
 
-	// Accept the response message.
+	// Accept the response message. The second flit is omitted for single
+	// flit error responses, in which case zero data is returned.
 	respFlit1 := <-smiResponse
-	respFlit2 := <-smiResponse
+	var respFlit2 Flit64
+	if respFlit1.Eofc == 0 {
+		respFlit2 = <-smiResponse
+	} else {
+		respFlit1.Data = [8]uint8{}
+	}
 
 	return (((uint64(respFlit1.Data[4])) |
 		(uint64(respFlit1.Data[5]) << 8)) |
@@ -1609,6 +2079,12 @@ func readSingleBurstUInt64(
 		respFlit1.Data[7]}
 	moreFlits := respFlit1.Eofc == 0
 
+	// Single flit error responses carry the error code in place of read
+	// data, so zero data is returned instead.
+	if !moreFlits && respFlit1.Data[0] != SmiMemReadResp {
+		flitData = [4]uint8{}
+	}
+
 	var readOk bool
 	if (respFlit1.Data[1] & 0x02) == uint8(0x00) {
 		readOk = true
@@ -1617,9 +2093,17 @@ func readSingleBurstUInt64(
 	}
 
 	// Pull all the payload flits from the response channel and copy the data
-	// to the output channel.
-	for moreFlits {
-		respFlitN := <-smiResponse
+	// to the output channel. A short response frame, such as a single flit
+	// error response, is padded to the expected length and any excess flits
+	// are discarded, so that the response stream remains synchronised.
+	for i := (readLength >> 3); i != 0; i-- {
+		var respFlitN Flit64
+		if moreFlits {
+			respFlitN = <-smiResponse
+			moreFlits = respFlitN.Eofc == 0
+		} else {
+			readOk = false
+		}
 		readDataVal :=
 			((uint64(flitData[0]) |
 				(uint64(flitData[1]) << 8)) |
@@ -1634,9 +2118,12 @@ func readSingleBurstUInt64(
 			respFlitN.Data[5],
 			respFlitN.Data[6],
 			respFlitN.Data[7]}
-		moreFlits = respFlitN.Eofc == 0
 		readDataChan <- readDataVal
 	}
+	for moreFlits {
+		respFlitN := <-smiResponse
+		moreFlits = respFlitN.Eofc == 0
+	}
 	return readOk
 }
 
@@ -1690,6 +2177,13 @@ func readSingleBurstUInt32(
 		respFlit1.Data[6],
 		respFlit1.Data[7]}
 	readOffset := uint8(4)
+	moreFlits := respFlit1.Eofc == 0
+
+	// Single flit error responses carry the error code in place of read
+	// data, so zero data is returned instead.
+	if !moreFlits && respFlit1.Data[0] != SmiMemReadResp {
+		flitData = [4]uint8{}
+	}
 
 	var readOk bool
 	if (respFlit1.Data[1] & 0x02) == uint8(0x00) {
@@ -1699,7 +2193,9 @@ func readSingleBurstUInt32(
 	}
 
 	// Pull all the payload flits from the response channel and copy the data
-	// to the output channel.
+	// to the output channel. A short response frame, such as a single flit
+	// error response, is padded to the expected length and any excess flits
+	// are discarded, so that the response stream remains synchronised.
 	for i := (readLength >> 2); i != 0; i-- {
 		var readData uint32
 		if readOffset == 4 {
@@ -1710,7 +2206,13 @@ func readSingleBurstUInt32(
 						(uint32(flitData[3]) << 24))
 			readOffset = 0
 		} else {
-			respFlitN := <-smiResponse
+			var respFlitN Flit64
+			if moreFlits {
+				respFlitN = <-smiResponse
+				moreFlits = respFlitN.Eofc == 0
+			} else {
+				readOk = false
+			}
 			flitData = [4]uint8{
 				respFlitN.Data[4],
 				respFlitN.Data[5],
@@ -1725,6 +2227,10 @@ func readSingleBurstUInt32(
 		}
 		readDataChan <- readData
 	}
+	for moreFlits {
+		respFlitN := <-smiResponse
+		moreFlits = respFlitN.Eofc == 0
+	}
 	return readOk
 }
 
@@ -1780,6 +2286,13 @@ func readSingleBurstUInt16(
 		respFlit1.Data[6],
 		respFlit1.Data[7]}
 	readOffset := uint8(4)
+	moreFlits := respFlit1.Eofc == 0
+
+	// Single flit error responses carry the error code in place of read
+	// data, so zero data is returned instead.
+	if !moreFlits && respFlit1.Data[0] != SmiMemReadResp {
+		flitData = [6]uint8{}
+	}
 
 	var readOk bool
 	if (respFlit1.Data[1] & 0x02) == uint8(0x00) {
@@ -1789,7 +2302,9 @@ func readSingleBurstUInt16(
 	}
 
 	// Pull all the payload flits from the response channel and copy the data
-	// to the output channel.
+	// to the output channel. A short response frame, such as a single flit
+	// error response, is padded to the expected length and any excess flits
+	// are discarded, so that the response stream remains synchronised.
 	for i := (readLength >> 1); i != 0; i-- {
 		var readData uint16
 		switch readOffset {
@@ -1809,7 +2324,13 @@ func readSingleBurstUInt16(
 					(uint16(flitData[5]) << 8)
 			readOffset = 0
 		default:
-			respFlitN := <-smiResponse
+			var respFlitN Flit64
+			if moreFlits {
+				respFlitN = <-smiResponse
+				moreFlits = respFlitN.Eofc == 0
+			} else {
+				readOk = false
+			}
 			flitData = [6]uint8{
 				respFlitN.Data[2],
 				respFlitN.Data[3],
@@ -1824,6 +2345,10 @@ func readSingleBurstUInt16(
 		}
 		readDataChan <- readData
 	}
+	for moreFlits {
+		respFlitN := <-smiResponse
+		moreFlits = respFlitN.Eofc == 0
+	}
 	return readOk
 }
 
@@ -1879,6 +2404,13 @@ func readSingleBurstUInt8(
 		respFlit1.Data[6],
 		respFlit1.Data[7]}
 	readOffset := uint8(4)
+	moreFlits := respFlit1.Eofc == 0
+
+	// Single flit error responses carry the error code in place of read
+	// data, so zero data is returned instead.
+	if !moreFlits && respFlit1.Data[0] != SmiMemReadResp {
+		flitData = [7]uint8{}
+	}
 
 	var readOk bool
 	if (respFlit1.Data[1] & 0x02) == uint8(0x00) {
@@ -1888,7 +2420,9 @@ func readSingleBurstUInt8(
 	}
 
 	// Pull all the payload flits from the response channel and copy the data
-	// to the output channel.
+	// to the output channel. A short response frame, such as a single flit
+	// error response, is padded to the expected length and any excess flits
+	// are discarded, so that the response stream remains synchronised.
 	for i := readLength; i != 0; i-- {
 		var readData uint8
 		switch readOffset {
@@ -1914,7 +2448,13 @@ func readSingleBurstUInt8(
 			readData = flitData[6]
 			readOffset = 0
 		default:
-			respFlitN := <-smiResponse
+			var respFlitN Flit64
+			if moreFlits {
+				respFlitN = <-smiResponse
+				moreFlits = respFlitN.Eofc == 0
+			} else {
+				readOk = false
+			}
 			flitData = [7]uint8{
 				respFlitN.Data[1],
 				respFlitN.Data[2],
@@ -1929,6 +2469,10 @@ func readSingleBurstUInt8(
 		}
 		readDataChan <- readData
 	}
+	for moreFlits {
+		respFlitN := <-smiResponse
+		moreFlits = respFlitN.Eofc == 0
+	}
 	return readOk
 }
 
@@ -0,0 +1,140 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlit64PayloadLen(t *testing.T) {
+	cases := []struct {
+		eofc       uint8
+		payloadLen int
+		isLast     bool
+	}{
+		{0, 8, false},
+		{1, 1, true},
+		{2, 2, true},
+		{3, 3, true},
+		{4, 4, true},
+		{5, 5, true},
+		{6, 6, true},
+		{7, 7, true},
+		{8, 8, true},
+	}
+	for _, c := range cases {
+		flit := Flit64{Eofc: c.eofc}
+		if flit.PayloadLen() != c.payloadLen || flit.IsLast() != c.isLast {
+			t.Errorf("eofc %d: got %d %v, expected %d %v", c.eofc,
+				flit.PayloadLen(), flit.IsLast(), c.payloadLen, c.isLast)
+		}
+	}
+}
+
+func TestForwardFrame64OptDepth(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  uint8
+		minDepth int
+		maxDepth int
+	}{
+		// The unbuffered path holds one flit in the handoff buffer and one
+		// in each of the input and output loops.
+		{"unbuffered", MemOptUnbuffered, 1, 3},
+		{"buffered", DefaultOptions, 34, 36},
+	}
+	for _, c := range cases {
+		forwardReq := make(chan bool)
+		smiInput := make(chan Flit64)
+		smiOutput := make(chan Flit64)
+		forwardDone := make(chan bool)
+		go ForwardFrame64Opt(forwardReq, smiInput, smiOutput, forwardDone, c.options)
+		forwardReq <- true
+
+		// Push flits of an oversized frame until the forwarder blocks.
+		frame := make([]Flit64, 40)
+		for i := range frame {
+			frame[i].Data[0] = uint8(i)
+		}
+		frame[len(frame)-1].Eofc = 8
+		depth := 0
+		for depth != len(frame) && tryOffer(smiInput, frame[depth], 10*time.Millisecond) {
+			depth++
+		}
+		if depth < c.minDepth || depth > c.maxDepth {
+			t.Fatalf("%s: buffering depth %d, expected %d to %d",
+				c.name, depth, c.minDepth, c.maxDepth)
+		}
+
+		// Release the output and check the frame is forwarded intact.
+		go sendFrame64(smiInput, frame[depth:])
+		output := recvFrame(t, smiOutput)
+		if len(output) != len(frame) {
+			t.Fatalf("%s: unexpected frame length %d", c.name, len(output))
+		}
+		for i := range output {
+			if output[i] != frame[i] {
+				t.Fatalf("%s: unexpected flit %d %v", c.name, i, output[i])
+			}
+		}
+		<-forwardDone
+		forwardReq <- false
+	}
+}
+
+func TestFrame64NDoubledBurst(t *testing.T) {
+	type frameFunc func(
+		req <-chan bool,
+		smiInput <-chan Flit64,
+		smiOutput chan<- Flit64,
+		done chan<- bool,
+		maxFrameSize int)
+	stages := []struct {
+		name  string
+		stage frameFunc
+	}{
+		{"ForwardFrame64N", ForwardFrame64N},
+		{"AssembleFrame64N", AssembleFrame64N},
+	}
+
+	// A full frame for twice the default burst size, which would stall the
+	// default SmiMemFrame64Size buffers.
+	doubledFrame64Size := 2 + 2*SmiMemBurstSize/8
+	frame := make([]Flit64, doubledFrame64Size)
+	for i := range frame {
+		frame[i].Data[0] = uint8(i)
+	}
+	frame[len(frame)-1].Eofc = 8
+
+	for _, s := range stages {
+		req := make(chan bool)
+		smiInput := make(chan Flit64)
+		smiOutput := make(chan Flit64)
+		done := make(chan bool)
+		go s.stage(req, smiInput, smiOutput, done, doubledFrame64Size)
+		req <- true
+
+		// The whole frame is buffered while the output is stalled.
+		for i, inputFlit := range frame {
+			if !tryOffer(smiInput, inputFlit, testTimeout) {
+				t.Fatalf("%s: flit %d not accepted", s.name, i)
+			}
+		}
+		output := recvFrame(t, smiOutput)
+		if len(output) != len(frame) {
+			t.Fatalf("%s: unexpected frame length %d", s.name, len(output))
+		}
+		for i := range output {
+			if output[i] != frame[i] {
+				t.Fatalf("%s: unexpected flit %d %v", s.name, i, output[i])
+			}
+		}
+		<-done
+		req <- false
+	}
+}
@@ -0,0 +1,47 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// ParseReadResp64 reads a single complete memory read response frame from the
+// input channel, returning the read data and the tag value from byte 3 of
+// the frame header. The entire frame is always consumed, so that subsequent
+// frames can still be received if an error is returned. An error is returned
+// if the frame is an error response, is not a memory read response or ends
+// before the end of the frame header. This is intended for use in simulation
+// and host side test code. The read data may be passed to ReleaseReadData
+// once it is no longer required.
+//
+func ParseReadResp64(smiInput <-chan Flit64) ([]byte, uint8, error) {
+	frame := receiveFrame64(smiInput)
+	frameBytes := unpackFrame64(frame)
+	putFrame64(frame)
+	if len(frameBytes) < smiMemRespHeaderSize {
+		putFrameBytes(frameBytes)
+		return nil, 0, fmt.Errorf("frame ends after %d header bytes", len(frameBytes))
+	}
+	tag := frameBytes[3]
+	if frameBytes[0] == SmiMemErrorResp && len(frameBytes) > smiMemRespHeaderSize {
+		errorCode := frameBytes[smiMemRespHeaderSize]
+		putFrameBytes(frameBytes)
+		return nil, tag, fmt.Errorf("error response code 0x%02X", errorCode)
+	}
+	if frameBytes[0] != SmiMemReadResp {
+		frameType := frameBytes[0]
+		putFrameBytes(frameBytes)
+		return nil, tag, fmt.Errorf("unexpected frame type 0x%02X", frameType)
+	}
+
+	// Move the read data to the start of the buffer, so that the entire
+	// buffer is returned to the pool when the read data is released.
+	readData := frameBytes[:copy(frameBytes, frameBytes[smiMemRespHeaderSize:])]
+	return readData, tag, nil
+}
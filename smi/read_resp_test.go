@@ -0,0 +1,62 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseReadRespRoundTrip(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64, 64)
+	go MemoryModel64(smiRequest, smiResponse, make([]byte, 1024))
+
+	for _, dataLength := range []int{1, 8, 13, 256} {
+		writeData := make([]byte, dataLength)
+		for i := range writeData {
+			writeData[i] = uint8(i*7 + dataLength)
+		}
+		sendFrame(t, smiRequest, WriteReqFrames64(0x100, writeData, nil, 1))
+		if respFrame := recvFrame(t, smiResponse); respFrame[0].Data[0] != SmiMemWriteResp {
+			t.Fatalf("unexpected write response %v", respFrame)
+		}
+		sendFrame(t, smiRequest, ReadReqFrames64(0x100, uint32(dataLength), 0x42))
+		readData, tag, err := ParseReadResp64(smiResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != 0x42 || !bytes.Equal(readData, writeData) {
+			t.Fatalf("length %d: unexpected read data %v tag 0x%02X", dataLength, readData, tag)
+		}
+	}
+}
+
+func TestParseReadRespErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		frame []Flit64
+	}{
+		{"write response", []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 1}, Eofc: 4}}},
+		{"error response", []Flit64{{Data: [8]uint8{SmiMemErrorResp, 0, 0, 1, SmiMemErrDecode}, Eofc: 5}}},
+		{"short header", []Flit64{{Data: [8]uint8{SmiMemReadResp, 0, 0}, Eofc: 3}}},
+	}
+	smiInput := make(chan Flit64, 8)
+	for _, c := range cases {
+		sendFrame(t, smiInput, c.frame)
+		if _, _, err := ParseReadResp64(smiInput); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+
+	// Subsequent frames can still be parsed after an error.
+	sendFrame(t, smiInput, []Flit64{{Data: [8]uint8{SmiMemReadResp, 0, 0, 3, 0xAB}, Eofc: 5}})
+	if readData, tag, err := ParseReadResp64(smiInput); err != nil || tag != 3 ||
+		!bytes.Equal(readData, []byte{0xAB}) {
+		t.Fatalf("unexpected result %v %d %v", readData, tag, err)
+	}
+}
@@ -0,0 +1,25 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// RegisterSlice64 is a goroutine which inserts a single pipeline register
+// stage into an SMI channel, in order to break up long combinational paths
+// for timing closure. Each flit is held in the register until it has been
+// accepted by the output, so exactly one flit is buffered and one beat of
+// latency is added. Flits are forwarded unchanged and in order, so the
+// register slice may be inserted on any request or response channel.
+//
+func RegisterSlice64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	for {
+		flitRegister := <-smiInput
+		smiOutput <- flitRegister
+	}
+}
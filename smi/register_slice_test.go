@@ -0,0 +1,54 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterSliceDepth(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64)
+	go RegisterSlice64(smiInput, smiOutput)
+
+	// Exactly one flit is held while the output is stalled.
+	first := Flit64{Data: [8]uint8{1}}
+	second := Flit64{Data: [8]uint8{2}, Eofc: 1}
+	if !tryOffer(smiInput, first, 10*time.Millisecond) {
+		t.Fatal("first flit not accepted")
+	}
+	if tryOffer(smiInput, second, 10*time.Millisecond) {
+		t.Fatal("second flit accepted while output stalled")
+	}
+	if outputFlit := recvFlit(t, smiOutput); outputFlit != first {
+		t.Fatalf("unexpected flit %v", outputFlit)
+	}
+	if !tryOffer(smiInput, second, 10*time.Millisecond) {
+		t.Fatal("second flit not accepted after first was consumed")
+	}
+	if outputFlit := recvFlit(t, smiOutput); outputFlit != second {
+		t.Fatalf("unexpected flit %v", outputFlit)
+	}
+}
+
+func TestRegisterSliceOrdering(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64)
+	go RegisterSlice64(smiInput, smiOutput)
+
+	frame := WriteReqFrames64(0x40, make([]byte, 200), nil, 3)
+	for i := range frame {
+		frame[i].Data[7] = uint8(i)
+	}
+	go sendFrame64(smiInput, frame)
+	for i, expect := range frame {
+		if outputFlit := recvFlit(t, smiOutput); outputFlit != expect {
+			t.Fatalf("unexpected flit %d %v", i, outputFlit)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// ReorderResponses64 is a goroutine which restores the issue order of
+// response frames from a downstream port which may complete requests out of
+// order. This requires the requests to be issued with local tag values in
+// byte 3 of the header which cycle through the values 0 to
+// SmiMemInFlightLimit-1 in ascending order, as used by the arbitrated
+// upstream ports. Response frames are stored in a per-tag frame buffer and
+// are released in ascending tag order, starting with tag 0, so each response
+// frame is held until the responses for all the previously issued requests
+// have been released. Only the lower bits of the tag are used to select the
+// frame buffer, and response frames which are longer than SmiMemFrame64Size
+// flits are truncated.
+//
+func ReorderResponses64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	// TODO: The array sizes here should be set using the SmiMemInFlightLimit
+	// and SmiMemFrame64Size constants once supported by the compiler.
+	var frameBuffers [4][34]Flit64
+	var frameLengths [4]uint8
+	var frameValid [4]bool
+	nextTag := uint8(0)
+
+	for {
+
+		// Store the next response frame in the buffer for its tag.
+		headerFlit := <-smiInput
+		tagId := headerFlit.Data[3] & 0x03
+		frameBuffers[tagId][0] = headerFlit
+		frameLength := uint8(1)
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-smiInput
+			moreFlits = bodyFlit.Eofc == 0
+			if frameLength != 34 {
+				frameBuffers[tagId][frameLength] = bodyFlit
+				frameLength++
+			}
+		}
+		if frameBuffers[tagId][frameLength-1].Eofc == 0 {
+			frameBuffers[tagId][frameLength-1].Eofc = 8
+		}
+		frameLengths[tagId] = frameLength
+		frameValid[tagId] = true
+
+		// Release all the buffered frames which are now in order.
+		for frameValid[nextTag] {
+			for i := uint8(0); i != frameLengths[nextTag]; i++ {
+				smiOutput <- frameBuffers[nextTag][i]
+			}
+			frameValid[nextTag] = false
+			nextTag = (nextTag + 1) & 0x03
+		}
+	}
+}
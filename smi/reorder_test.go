@@ -0,0 +1,54 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReorderResponses(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 128)
+	go ReorderResponses64(smiInput, smiOutput)
+
+	// Build a read response for each tag, with the payload length and
+	// contents depending on the tag.
+	respFrames := make([][]Flit64, 4)
+	for tag := range respFrames {
+		respBytes := []byte{SmiMemReadResp, 0, 1, uint8(tag)}
+		for i := 0; i != 8*tag+5; i++ {
+			respBytes = append(respBytes, uint8(tag<<4+i))
+		}
+		respFrames[tag] = packFrame64(respBytes)
+	}
+
+	// Responses are held until all earlier tags have been released.
+	for _, tag := range []int{2, 0, 3, 1} {
+		sendFrame(t, smiInput, respFrames[tag])
+		if tag == 2 {
+			expectIdle(t, smiOutput, 10*time.Millisecond)
+		}
+	}
+	for tag := range respFrames {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, respFrames[tag]) {
+			t.Fatalf("unexpected frame %v, expected %v", output, respFrames[tag])
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+
+	// The tag sequence wraps after SmiMemInFlightLimit responses.
+	sendFrame(t, smiInput, respFrames[1])
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	sendFrame(t, smiInput, respFrames[0])
+	for _, tag := range []int{0, 1} {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, respFrames[tag]) {
+			t.Fatalf("unexpected frame %v after wrap", output)
+		}
+	}
+}
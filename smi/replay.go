@@ -0,0 +1,70 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// ReplayFrames replays captured traffic onto an SMI channel for regression
+// testing. The capture consists of the concatenated outputs of MarshalFrame
+// for a sequence of frames, which is split back into individual frames using
+// the end of frame markers. The entire capture is validated before any frames
+// are sent, and an error is returned if it is not valid. If the gap size is
+// non-zero, the frames are paced using PaceFrames64 with the specified number
+// of ticks on the tick channel between frames. Otherwise the frames are sent
+// back to back and the tick channel is not used. If looping is enabled the
+// capture is replayed indefinitely and this function does not return.
+// Otherwise it returns once all the frames have been accepted for output,
+// which may be before the pacing stage has forwarded the final frame.
+//
+func ReplayFrames(
+	capture []byte,
+	smiOutput chan<- Flit64,
+	tick <-chan struct{},
+	gapTicks uint32,
+	isLooping bool) error {
+
+	// Split the capture into frames.
+	frames := make([][]Flit64, 0)
+	frameStart := 0
+	for i := SmiFlit64WireSize; i <= len(capture); i += SmiFlit64WireSize {
+		if capture[i-1] != 0 {
+			frame, err := UnmarshalFrame(capture[frameStart:i])
+			if err != nil {
+				return fmt.Errorf("frame %d: %v", len(frames)+1, err)
+			}
+			frames = append(frames, frame)
+			frameStart = i
+		}
+	}
+	if frameStart != len(capture) {
+		return fmt.Errorf("truncated frame at end of capture")
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("empty capture")
+	}
+
+	// Insert the pacing stage if required.
+	replayOutput := smiOutput
+	if gapTicks != 0 {
+		pacedInput := make(chan Flit64)
+		go PaceFrames64(pacedInput, smiOutput, tick, gapTicks)
+		replayOutput = pacedInput
+	}
+
+	// Replay the frames.
+	moreFrames := true
+	for moreFrames {
+		for _, frame := range frames {
+			sendFrame64(replayOutput, frame)
+		}
+		moreFrames = isLooping
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//
+// replayCapture builds a capture of two frames for the replay tests.
+//
+func replayCapture() ([][]Flit64, []byte) {
+	frames := [][]Flit64{
+		ReadReqFrames64(0x100, 16, 1),
+		WriteReqFrames64(0x200, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, 2),
+	}
+	capture := []byte{}
+	for _, frame := range frames {
+		capture = append(capture, MarshalFrame(frame)...)
+	}
+	return frames, capture
+}
+
+func TestReplayFramesBackToBack(t *testing.T) {
+	frames, capture := replayCapture()
+	smiOutput := make(chan Flit64, 16)
+	if err := ReplayFrames(capture, smiOutput, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range frames {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("unexpected frame %v, expected %v", output, frame)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
+
+func TestReplayFramesPaced(t *testing.T) {
+	frames, capture := replayCapture()
+	smiOutput := make(chan Flit64)
+	tick := make(chan struct{})
+	go ReplayFrames(capture, smiOutput, tick, 3, false)
+
+	if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frames[0]) {
+		t.Fatalf("unexpected frame %v", output)
+	}
+	for i := 0; i != 2; i++ {
+		tick <- struct{}{}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	tick <- struct{}{}
+	if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frames[1]) {
+		t.Fatalf("unexpected frame %v", output)
+	}
+}
+
+func TestReplayFramesLooping(t *testing.T) {
+	frames, capture := replayCapture()
+	smiOutput := make(chan Flit64)
+	go ReplayFrames(capture, smiOutput, nil, 0, true)
+	for i := 0; i != 3*len(frames); i++ {
+		frame := frames[i%len(frames)]
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("unexpected frame %d %v", i, output)
+		}
+	}
+}
+
+func TestReplayFramesInvalid(t *testing.T) {
+	_, capture := replayCapture()
+	badEofc := append([]byte{}, capture...)
+	badEofc[SmiFlit64WireSize-1] = 9
+	cases := []struct {
+		name    string
+		capture []byte
+	}{
+		{"empty", nil},
+		{"truncated", capture[:len(capture)-SmiFlit64WireSize]},
+		{"partial flit", capture[:len(capture)-1]},
+		{"bad eofc", badEofc},
+	}
+	for _, c := range cases {
+		smiOutput := make(chan Flit64, 16)
+		if err := ReplayFrames(c.capture, smiOutput, nil, 0, false); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+		if len(smiOutput) != 0 {
+			t.Errorf("%s: frames sent for invalid capture", c.name)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// WriteReqFrames64 builds the memory write request frames needed to write the
+// specified data to memory, starting at the specified address. Data which is
+// larger than SmiMemBurstSize is split into multiple write request frames,
+// with the address of each frame being incremented by the length of the
+// preceding frames. The frames are returned as a single sequence of flits,
+// with the Eofc field marking the last flit of each frame. The tag value is
+// placed in byte 3 of each frame header, with byte 2 being set to zero and
+// the default options being used. This is intended for use in simulation and
+// host side test code.
+//
+// If a strobe mask is supplied, the byte strobe option is set and the
+// corresponding part of the strobe mask is included in each frame, using the
+// layout described for SmiMemStrobedBurstSize. The strobe mask holds one
+// byte for each 8 byte beat of the write data, and any missing mask bytes
+// disable the corresponding beats. The data is then split into frames of no
+// more than SmiMemStrobedBurstSize bytes. A nil strobe mask enables all
+// bytes.
+//
+func WriteReqFrames64(
+	writeAddr uint64,
+	writeData []byte,
+	writeStrobes []byte,
+	tag uint8) []Flit64 {
+
+	writeOptions := DefaultOptions
+	burstSize := SmiMemBurstSize
+	if writeStrobes != nil {
+		writeOptions = MemOptByteStrobes
+		burstSize = SmiMemStrobedBurstSize
+		strobeCount := (len(writeData) + 7) / 8
+		if len(writeStrobes) < strobeCount {
+			writeStrobes = append(writeStrobes,
+				make([]byte, strobeCount-len(writeStrobes))...)
+		}
+	}
+
+	frames := []Flit64{}
+	fragmentStart := 0
+	for {
+		fragmentEnd := fragmentStart + burstSize
+		if fragmentEnd > len(writeData) {
+			fragmentEnd = len(writeData)
+		}
+		fragmentData := writeData[fragmentStart:fragmentEnd]
+		if writeStrobes != nil {
+			fragmentData = append(append([]byte{},
+				writeStrobes[fragmentStart/8:(fragmentEnd+7)/8]...),
+				fragmentData...)
+		}
+		frameBytes := append(reqHeaderBytes(SmiMemWriteReq, writeOptions, 0, tag,
+			writeAddr+uint64(fragmentStart), uint16(fragmentEnd-fragmentStart)),
+			fragmentData...)
+		frames = append(frames, packFrame64(frameBytes)...)
+		fragmentStart = fragmentEnd
+		if fragmentStart == len(writeData) {
+			return frames
+		}
+	}
+}
+
+//
+// ReadReqFrames64 builds the memory read request frames needed to read the
+// specified number of bytes from memory, starting at the specified address.
+// Reads which are longer than SmiMemBurstSize are split into multiple read
+// request frames, with the address of each frame being incremented by the
+// length of the preceding frames. Each read request frame consists of the
+// header only, with the Eofc field of its last flit being set. The tag and
+// options are set in the same way as for WriteReqFrames64.
+//
+func ReadReqFrames64(readAddr uint64, readLength uint32, tag uint8) []Flit64 {
+	frames := []Flit64{}
+	fragmentStart := uint32(0)
+	for {
+		fragmentLength := readLength - fragmentStart
+		if fragmentLength > SmiMemBurstSize {
+			fragmentLength = SmiMemBurstSize
+		}
+		frameBytes := readReqBytes(readAddr+uint64(fragmentStart),
+			DefaultOptions, 0, tag, uint16(fragmentLength))
+		frames = append(frames, packFrame64(frameBytes)...)
+		fragmentStart += fragmentLength
+		if fragmentStart == readLength {
+			return frames
+		}
+	}
+}
+
+//
+// Type Descriptor specifies a single scatter-gather memory transfer, giving
+// the start address, the length in bytes and the transfer direction.
+//
+type Descriptor struct {
+	Addr  uint64
+	Len   uint32
+	Write bool
+}
+
+//
+// ExecuteDescriptors64 sends the memory request frames needed to carry out a
+// list of scatter-gather descriptors on the specified SMI request channel.
+// The frames for each descriptor are built using WriteReqFrames64 or
+// ReadReqFrames64, so transfers which are longer than SmiMemBurstSize are
+// split into multiple frames. The write data for successive write
+// descriptors is taken in order from the payload. The index of each
+// descriptor in the list is used as the tag for all its request frames. An
+// error is returned without sending any frames if the payload is too short
+// for the write descriptors or if there are more than 256 descriptors.
+//
+func ExecuteDescriptors64(
+	descs []Descriptor,
+	payload []byte,
+	smiRequest chan<- Flit64) error {
+
+	if len(descs) > 256 {
+		return fmt.Errorf("descriptor count %d exceeds 256", len(descs))
+	}
+	writeLength := uint64(0)
+	for _, desc := range descs {
+		if desc.Write {
+			writeLength += uint64(desc.Len)
+		}
+	}
+	if writeLength > uint64(len(payload)) {
+		return fmt.Errorf("payload size %d is less than write length %d",
+			len(payload), writeLength)
+	}
+
+	// Send the request frames for each descriptor.
+	payloadStart := uint64(0)
+	for i, desc := range descs {
+		if desc.Write {
+			payloadEnd := payloadStart + uint64(desc.Len)
+			sendFrame64(smiRequest, WriteReqFrames64(
+				desc.Addr, payload[payloadStart:payloadEnd], nil, uint8(i)))
+			payloadStart = payloadEnd
+		} else {
+			sendFrame64(smiRequest, ReadReqFrames64(desc.Addr, desc.Len, uint8(i)))
+		}
+	}
+	return nil
+}
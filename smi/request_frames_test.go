@@ -0,0 +1,162 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriteReqFramesLayout(t *testing.T) {
+	writeData := []byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, 0xA8, 0xA9}
+	expect := []Flit64{
+		{Data: [8]uint8{SmiMemWriteReq, DefaultOptions, 0x00, 0x5A, 0x08, 0x07, 0x06, 0x05}},
+		{Data: [8]uint8{0x04, 0x03, 0x02, 0x01, 0x0A, 0x00, 0xA0, 0xA1}},
+		{Data: [8]uint8{0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, 0xA8, 0xA9}, Eofc: 8},
+	}
+	frames := WriteReqFrames64(0x0102030405060708, writeData, nil, 0x5A)
+	if !reflect.DeepEqual(frames, expect) {
+		t.Fatalf("unexpected frame %v, expected %v", frames, expect)
+	}
+
+	// A partial final flit sets Eofc to the number of valid bytes.
+	frames = WriteReqFrames64(0x0102030405060708, writeData[:3], nil, 0x5A)
+	if len(frames) != 3 || frames[2].Eofc != 1 || frames[2].Data[0] != 0xA2 ||
+		frames[1].Data[4] != 3 || frames[1].Eofc != 0 {
+		t.Fatalf("unexpected short frame %v", frames)
+	}
+}
+
+func TestWriteReqFramesStrobedLayout(t *testing.T) {
+	writeData := []byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, 0xA8, 0xA9}
+	expect := []Flit64{
+		{Data: [8]uint8{SmiMemWriteReq, MemOptByteStrobes, 0x00, 0x5A, 0x00, 0x10, 0x00, 0x00}},
+		{Data: [8]uint8{0x00, 0x00, 0x00, 0x00, 0x0A, 0x00, 0xF0, 0x03}},
+		{Data: [8]uint8{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7}},
+		{Data: [8]uint8{0xA8, 0xA9}, Eofc: 2},
+	}
+	frames := WriteReqFrames64(0x1000, writeData, []byte{0xF0, 0x03}, 0x5A)
+	if !reflect.DeepEqual(frames, expect) {
+		t.Fatalf("unexpected frame %v, expected %v", frames, expect)
+	}
+}
+
+func TestWriteReqFramesSplit(t *testing.T) {
+	writeData := make([]byte, 300)
+	for i := range writeData {
+		writeData[i] = uint8(i)
+	}
+	flits := WriteReqFrames64(0x1000, writeData, nil, 7)
+
+	// Split the flits into frames and check each header and payload.
+	frames := [][]Flit64{}
+	frameStart := 0
+	for i, flit := range flits {
+		if flit.Eofc != 0 {
+			frames = append(frames, flits[frameStart:i+1])
+			frameStart = i + 1
+		}
+	}
+	expectLengths := []int{SmiMemBurstSize, 300 - SmiMemBurstSize}
+	if len(frames) != len(expectLengths) || frameStart != len(flits) {
+		t.Fatalf("unexpected frame count %d", len(frames))
+	}
+	payloadStart := 0
+	for i, frame := range frames {
+		header := [2]Flit64{frame[0], frame[1]}
+		if frame[0].Data[0] != SmiMemWriteReq || frame[0].Data[3] != 7 ||
+			ReadAddr(header) != 0x1000+uint64(payloadStart) ||
+			int(ReadLength(header)) != expectLengths[i] {
+			t.Fatalf("unexpected header for frame %d %v", i, header)
+		}
+		frameBytes := unpackFrame64(frame)
+		payload := frameBytes[smiMemReqHeaderSize:]
+		if !reflect.DeepEqual(payload, writeData[payloadStart:payloadStart+expectLengths[i]]) {
+			t.Fatalf("unexpected payload for frame %d", i)
+		}
+		payloadStart += expectLengths[i]
+	}
+}
+
+func TestReadReqFramesFragments(t *testing.T) {
+	flits := ReadReqFrames64(0x2000, 1000, 9)
+	expectLengths := []uint16{256, 256, 256, 232}
+	if len(flits) != 2*len(expectLengths) {
+		t.Fatalf("unexpected flit count %d", len(flits))
+	}
+	for i, expectLength := range expectLengths {
+		header := [2]Flit64{flits[2*i], flits[2*i+1]}
+		if header[0].Data[0] != SmiMemReadReq || header[0].Data[3] != 9 ||
+			header[0].Eofc != 0 || header[1].Eofc != 6 ||
+			ReadAddr(header) != 0x2000+256*uint64(i) ||
+			ReadLength(header) != expectLength {
+			t.Fatalf("unexpected header for fragment %d %v", i, header)
+		}
+	}
+}
+
+func TestExecuteDescriptors(t *testing.T) {
+	descs := []Descriptor{
+		{Addr: 0x1000, Len: 64, Write: false},
+		{Addr: 0x2000, Len: 300, Write: true},
+		{Addr: 0x3000, Len: 600, Write: false},
+		{Addr: 0x4000, Len: 20, Write: true},
+		{Addr: 0x5000, Len: 8, Write: false},
+	}
+	payload := make([]byte, 320)
+	for i := range payload {
+		payload[i] = uint8(i)
+	}
+	type expectFrame struct {
+		frameType uint8
+		tag       uint8
+		addr      uint64
+		length    uint16
+	}
+	expect := []expectFrame{
+		{SmiMemReadReq, 0, 0x1000, 64},
+		{SmiMemWriteReq, 1, 0x2000, 256},
+		{SmiMemWriteReq, 1, 0x2100, 44},
+		{SmiMemReadReq, 2, 0x3000, 256},
+		{SmiMemReadReq, 2, 0x3100, 256},
+		{SmiMemReadReq, 2, 0x3200, 88},
+		{SmiMemWriteReq, 3, 0x4000, 20},
+		{SmiMemReadReq, 4, 0x5000, 8},
+	}
+	smiRequest := make(chan Flit64, 1024)
+	if err := ExecuteDescriptors64(descs, payload, smiRequest); err != nil {
+		t.Fatal(err)
+	}
+	payloadStart := 0
+	for i, e := range expect {
+		frame := recvFrame(t, smiRequest)
+		header := [2]Flit64{frame[0], frame[1]}
+		if frame[0].Data[0] != e.frameType || frame[0].Data[3] != e.tag ||
+			ReadAddr(header) != e.addr || ReadLength(header) != e.length {
+			t.Fatalf("unexpected header for frame %d %v", i, header)
+		}
+		if e.frameType == SmiMemWriteReq {
+			payloadEnd := payloadStart + int(e.length)
+			if !reflect.DeepEqual(unpackFrame64(frame)[smiMemReqHeaderSize:],
+				payload[payloadStart:payloadEnd]) {
+				t.Fatalf("unexpected payload for frame %d", i)
+			}
+			payloadStart = payloadEnd
+		}
+	}
+	if len(smiRequest) != 0 {
+		t.Fatal("unexpected additional frames")
+	}
+
+	// Short payloads are rejected without sending any frames.
+	if err := ExecuteDescriptors64(descs, payload[:319], smiRequest); err == nil {
+		t.Fatal("short payload not rejected")
+	}
+	if len(smiRequest) != 0 {
+		t.Fatal("frames sent for rejected descriptors")
+	}
+}
@@ -0,0 +1,615 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// ForwardFrame64WithReset is a variant of ForwardFrame64 which can be shut
+// down using the reset channel. Once the reset channel is closed, the current
+// frame is forwarded to completion and the function returns instead of
+// waiting for the next forwarding request. This allows the function to be
+// stopped cleanly in simulation, where goroutines which loop forever would
+// otherwise be leaked.
+//
+func ForwardFrame64WithReset(
+	forwardReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	forwardDone chan<- bool,
+	reset <-chan struct{}) {
+	smiBuffer := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+
+	doForward := false
+	select {
+	case doForward = <-forwardReq:
+	case <-reset:
+	}
+	for doForward {
+		go func() {
+			hasNextInputFlit := true
+			for hasNextInputFlit {
+				inputFlitData := <-smiInput
+				smiBuffer <- inputFlitData
+				hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+			}
+		}()
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		forwardDone <- true
+		select {
+		case doForward = <-forwardReq:
+		case <-reset:
+			doForward = false
+		}
+	}
+}
+
+//
+// AssembleFrame64WithReset is a variant of AssembleFrame64 which can be shut
+// down using the reset channel, in the same way as ForwardFrame64WithReset.
+//
+func AssembleFrame64WithReset(
+	assembleReq <-chan bool,
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	assembleDone chan<- bool,
+	reset <-chan struct{}) {
+	smiBuffer := make(chan Flit64, 34 /* SmiMemFrame64Size */)
+
+	doAssemble := false
+	select {
+	case doAssemble = <-assembleReq:
+	case <-reset:
+	}
+	for doAssemble {
+		hasNextInputFlit := true
+		for hasNextInputFlit {
+			inputFlitData := <-smiInput
+			smiBuffer <- inputFlitData
+			hasNextInputFlit = inputFlitData.Eofc == uint8(0)
+		}
+
+		hasNextOutputFlit := true
+		for hasNextOutputFlit {
+			outputFlitData := <-smiBuffer
+			smiOutput <- outputFlitData
+			hasNextOutputFlit = outputFlitData.Eofc == uint8(0)
+		}
+		assembleDone <- true
+		select {
+		case doAssemble = <-assembleReq:
+		case <-reset:
+			doAssemble = false
+		}
+	}
+}
+
+//
+// manageUpstreamPortWithReset is a variant of manageUpstreamPort which can be
+// shut down using the reset channel. Once the reset channel is closed, the
+// request handling stops at the next frame boundary and the transfer request
+// channel is closed to indicate to the arbiter that no further frames will be
+// issued. A frame whose header has already been received is forwarded in
+// full, even if this means waiting for a tag to be released by an
+// outstanding response after the reset. The response handling stops when
+// the tagged response channel is closed by the arbiter. The function returns
+// once both the request and response handling have stopped.
+//
+func manageUpstreamPortWithReset(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	reset <-chan struct{},
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+	requestsDone := make(chan bool, 1)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		isRunning := true
+		for isRunning {
+
+			// Do tag replacement on header, unless reset. Once the header
+			// has been received the frame is always forwarded.
+			var headerFlit Flit64
+			select {
+			case headerFlit = <-upstreamRequest:
+			case <-reset:
+				isRunning = false
+				continue
+			}
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+		close(transferReq)
+		requestsDone <- true
+	}()
+
+	// Carry out tag replacement on responses until the arbiter closes the
+	// tagged response channel.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit, isOpen := <-taggedResponse
+		if !isOpen {
+			break
+		}
+		tagId := headerFlit.Data[3]
+		headerFlit.Data[2] = tagTableLower[tagId]
+		headerFlit.Data[3] = tagTableUpper[tagId]
+		tagFifo <- tagId
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-taggedResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+	<-requestsDone
+}
+
+//
+// ArbitrateX2WithReset is a variant of ArbitrateX2 which can be shut down
+// using the reset channel. Once the reset channel is closed, each upstream
+// port stops accepting new request frames at the next frame boundary and the
+// arbiter returns once all the frames which have already been accepted have
+// been forwarded on the downstream request channel, so no partial frames are
+// sent downstream. Response steering continues until the arbiter has
+// finished and a response has been received for every request frame it
+// forwarded, so the downstream endpoint is never left blocked on a response.
+// The function only returns once all the goroutines it has started have
+// exited.
+//
+func ArbitrateX2WithReset(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	reset <-chan struct{}) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Track the goroutines which need to exit before returning.
+	var shutdown sync.WaitGroup
+	shutdown.Add(3)
+
+	// Run the upstream port management routines.
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestA, upstreamResponseA,
+			taggedRequestA, taggedResponseA, transferReqA, reset, uint8(1))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestB, upstreamResponseB,
+			taggedRequestB, taggedResponseB, transferReqB, reset, uint8(2))
+		shutdown.Done()
+	}()
+
+	// Arbitrate between transfer requests until all the transfer request
+	// channels have been closed, then report the number of forwarded frames.
+	arbiterDone := make(chan uint32, 1)
+	go func() {
+		frameCount := uint32(0)
+		activeReqA := (<-chan uint8)(transferReqA)
+		activeReqB := (<-chan uint8)(transferReqB)
+		for activeReqA != nil || activeReqB != nil {
+
+			// Gets port ID of active input.
+			var portId uint8
+			isOpen := false
+			select {
+			case portId, isOpen = <-activeReqA:
+				if !isOpen {
+					activeReqA = nil
+				}
+			case portId, isOpen = <-activeReqB:
+				if !isOpen {
+					activeReqB = nil
+				}
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := isOpen
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+			if isOpen {
+				frameCount++
+			}
+		}
+		arbiterDone <- frameCount
+		shutdown.Done()
+	}()
+
+	// Steer transfer responses until the arbiter has finished and all the
+	// forwarded request frames have received responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	isArbiterDone := false
+	reqCount := uint32(0)
+	respCount := uint32(0)
+	for !isArbiterDone || respCount != reqCount || !isHeaderFlit {
+		var respFlit Flit64
+		if isArbiterDone {
+			respFlit = <-downstreamResponse
+		} else {
+			select {
+			case respFlit = <-downstreamResponse:
+			case reqCount = <-arbiterDone:
+				isArbiterDone = true
+				continue
+			}
+		}
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+			respCount++
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+	close(taggedResponseA)
+	close(taggedResponseB)
+	shutdown.Wait()
+}
+
+//
+// ArbitrateX3WithReset is a variant of ArbitrateX3 which can be shut down
+// using the reset channel, as described for ArbitrateX2WithReset.
+//
+func ArbitrateX3WithReset(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	reset <-chan struct{}) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+
+	// Track the goroutines which need to exit before returning.
+	var shutdown sync.WaitGroup
+	shutdown.Add(4)
+
+	// Run the upstream port management routines.
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestA, upstreamResponseA,
+			taggedRequestA, taggedResponseA, transferReqA, reset, uint8(1))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestB, upstreamResponseB,
+			taggedRequestB, taggedResponseB, transferReqB, reset, uint8(2))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestC, upstreamResponseC,
+			taggedRequestC, taggedResponseC, transferReqC, reset, uint8(3))
+		shutdown.Done()
+	}()
+
+	// Arbitrate between transfer requests until all the transfer request
+	// channels have been closed, then report the number of forwarded frames.
+	arbiterDone := make(chan uint32, 1)
+	go func() {
+		frameCount := uint32(0)
+		activeReqA := (<-chan uint8)(transferReqA)
+		activeReqB := (<-chan uint8)(transferReqB)
+		activeReqC := (<-chan uint8)(transferReqC)
+		for activeReqA != nil || activeReqB != nil || activeReqC != nil {
+
+			// Gets port ID of active input.
+			var portId uint8
+			isOpen := false
+			select {
+			case portId, isOpen = <-activeReqA:
+				if !isOpen {
+					activeReqA = nil
+				}
+			case portId, isOpen = <-activeReqB:
+				if !isOpen {
+					activeReqB = nil
+				}
+			case portId, isOpen = <-activeReqC:
+				if !isOpen {
+					activeReqC = nil
+				}
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := isOpen
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				case 2:
+					reqFlit = <-taggedRequestB
+				default:
+					reqFlit = <-taggedRequestC
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+			if isOpen {
+				frameCount++
+			}
+		}
+		arbiterDone <- frameCount
+		shutdown.Done()
+	}()
+
+	// Steer transfer responses until the arbiter has finished and all the
+	// forwarded request frames have received responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	isArbiterDone := false
+	reqCount := uint32(0)
+	respCount := uint32(0)
+	for !isArbiterDone || respCount != reqCount || !isHeaderFlit {
+		var respFlit Flit64
+		if isArbiterDone {
+			respFlit = <-downstreamResponse
+		} else {
+			select {
+			case respFlit = <-downstreamResponse:
+			case reqCount = <-arbiterDone:
+				isArbiterDone = true
+				continue
+			}
+		}
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+			respCount++
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+	close(taggedResponseA)
+	close(taggedResponseB)
+	close(taggedResponseC)
+	shutdown.Wait()
+}
+
+//
+// ArbitrateX4WithReset is a variant of ArbitrateX4 which can be shut down
+// using the reset channel, as described for ArbitrateX2WithReset.
+//
+func ArbitrateX4WithReset(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	upstreamRequestC <-chan Flit64,
+	upstreamResponseC chan<- Flit64,
+	upstreamRequestD <-chan Flit64,
+	upstreamResponseD chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	reset <-chan struct{}) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	taggedRequestC := make(chan Flit64, 1)
+	taggedResponseC := make(chan Flit64, 1)
+	taggedRequestD := make(chan Flit64, 1)
+	taggedResponseD := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	transferReqC := make(chan uint8, 1)
+	transferReqD := make(chan uint8, 1)
+
+	// Track the goroutines which need to exit before returning.
+	var shutdown sync.WaitGroup
+	shutdown.Add(5)
+
+	// Run the upstream port management routines.
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestA, upstreamResponseA,
+			taggedRequestA, taggedResponseA, transferReqA, reset, uint8(1))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestB, upstreamResponseB,
+			taggedRequestB, taggedResponseB, transferReqB, reset, uint8(2))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestC, upstreamResponseC,
+			taggedRequestC, taggedResponseC, transferReqC, reset, uint8(3))
+		shutdown.Done()
+	}()
+	go func() {
+		manageUpstreamPortWithReset(upstreamRequestD, upstreamResponseD,
+			taggedRequestD, taggedResponseD, transferReqD, reset, uint8(4))
+		shutdown.Done()
+	}()
+
+	// Arbitrate between transfer requests until all the transfer request
+	// channels have been closed, then report the number of forwarded frames.
+	arbiterDone := make(chan uint32, 1)
+	go func() {
+		frameCount := uint32(0)
+		activeReqA := (<-chan uint8)(transferReqA)
+		activeReqB := (<-chan uint8)(transferReqB)
+		activeReqC := (<-chan uint8)(transferReqC)
+		activeReqD := (<-chan uint8)(transferReqD)
+		for activeReqA != nil || activeReqB != nil || activeReqC != nil || activeReqD != nil {
+
+			// Gets port ID of active input.
+			var portId uint8
+			isOpen := false
+			select {
+			case portId, isOpen = <-activeReqA:
+				if !isOpen {
+					activeReqA = nil
+				}
+			case portId, isOpen = <-activeReqB:
+				if !isOpen {
+					activeReqB = nil
+				}
+			case portId, isOpen = <-activeReqC:
+				if !isOpen {
+					activeReqC = nil
+				}
+			case portId, isOpen = <-activeReqD:
+				if !isOpen {
+					activeReqD = nil
+				}
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := isOpen
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				case 2:
+					reqFlit = <-taggedRequestB
+				case 3:
+					reqFlit = <-taggedRequestC
+				default:
+					reqFlit = <-taggedRequestD
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+			if isOpen {
+				frameCount++
+			}
+		}
+		arbiterDone <- frameCount
+		shutdown.Done()
+	}()
+
+	// Steer transfer responses until the arbiter has finished and all the
+	// forwarded request frames have received responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	isArbiterDone := false
+	reqCount := uint32(0)
+	respCount := uint32(0)
+	for !isArbiterDone || respCount != reqCount || !isHeaderFlit {
+		var respFlit Flit64
+		if isArbiterDone {
+			respFlit = <-downstreamResponse
+		} else {
+			select {
+			case respFlit = <-downstreamResponse:
+			case reqCount = <-arbiterDone:
+				isArbiterDone = true
+				continue
+			}
+		}
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+			respCount++
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		case 3:
+			taggedResponseC <- respFlit
+		case 4:
+			taggedResponseD <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+	close(taggedResponseA)
+	close(taggedResponseB)
+	close(taggedResponseC)
+	close(taggedResponseD)
+	shutdown.Wait()
+}
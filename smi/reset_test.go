@@ -0,0 +1,148 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//
+// waitShutdown waits for the wait group to complete, failing the test if it
+// does not do so before the timeout.
+//
+func waitShutdown(t *testing.T, shutdown *sync.WaitGroup) {
+	t.Helper()
+	shutdownDone := make(chan bool)
+	go func() {
+		shutdown.Wait()
+		shutdownDone <- true
+	}()
+	select {
+	case <-shutdownDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for shutdown")
+	}
+}
+
+func TestArbitrateX2WithResetShutdown(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 8)
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64, 8)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	reset := make(chan struct{})
+	var shutdown sync.WaitGroup
+	shutdown.Add(1)
+	go func() {
+		ArbitrateX2WithReset(upstreamRequestA, upstreamResponseA,
+			upstreamRequestB, upstreamResponseB,
+			downstreamRequest, downstreamResponse, reset)
+		shutdown.Done()
+	}()
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 256))
+
+	for i := 0; i != 3; i++ {
+		go sendFrame64(upstreamRequestA, WriteReqFrames64(
+			uint64(8*i), make([]byte, 20), nil, uint8(i)))
+		go sendFrame64(upstreamRequestB, ReadReqFrames64(uint64(8*i), 20, uint8(i)))
+		if frame := recvFrame(t, upstreamResponseA); frame[0].Data[0] != SmiMemWriteResp ||
+			respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+		if frame := recvFrame(t, upstreamResponseB); frame[0].Data[0] != SmiMemReadResp ||
+			respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+	close(reset)
+	waitShutdown(t, &shutdown)
+}
+
+func TestArbitrateX2WithResetBlockedDownstream(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 8)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	reset := make(chan struct{})
+	var shutdown sync.WaitGroup
+	shutdown.Add(1)
+	go func() {
+		ArbitrateX2WithReset(upstreamRequestA, upstreamResponseA,
+			make(chan Flit64), make(chan Flit64),
+			downstreamRequest, downstreamResponse, reset)
+		shutdown.Done()
+	}()
+
+	// The arbiter is blocked on the downstream request channel when the
+	// reset is applied, and the response must still be steered afterwards.
+	go sendFrame64(upstreamRequestA, WriteReqFrames64(0, make([]byte, 20), nil, 5))
+	recvFlit(t, downstreamRequest)
+	close(reset)
+	time.Sleep(10 * time.Millisecond)
+	reqFrame := recvFrame(t, downstreamRequest)
+	if len(reqFrame) != 4 {
+		t.Fatalf("unexpected request frame tail %v", reqFrame)
+	}
+	sendFrame(t, downstreamResponse, []Flit64{{
+		Data: [8]uint8{SmiMemWriteResp, 0, 1, 0}, Eofc: 4}})
+	if frame := recvFrame(t, upstreamResponseA); respTag(frame[0]) != 5<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+	waitShutdown(t, &shutdown)
+}
+
+func TestArbitrateX2WithResetWaitingForTag(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 8)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	reset := make(chan struct{})
+	var shutdown sync.WaitGroup
+	shutdown.Add(1)
+	go func() {
+		ArbitrateX2WithReset(upstreamRequestA, upstreamResponseA,
+			make(chan Flit64), make(chan Flit64),
+			downstreamRequest, downstreamResponse, reset)
+		shutdown.Done()
+	}()
+
+	// Use up all the tags, then send the header of a fifth frame which has
+	// to wait for a tag when the reset is applied.
+	reqHeaders := []Flit64{}
+	for i := 0; i != 4; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, uint8(i)))
+		reqHeaders = append(reqHeaders, recvFrame(t, downstreamRequest)[0])
+	}
+	finalFrame := WriteReqFrames64(0, make([]byte, 20), nil, 4)
+	sendFrame(t, upstreamRequestA, finalFrame[:1])
+	close(reset)
+	go sendFrame64(upstreamRequestA, finalFrame[1:])
+
+	// Releasing a tag allows the fifth frame to be forwarded in full.
+	respond := func(reqHeader Flit64) {
+		sendFrame(t, downstreamResponse, []Flit64{{
+			Data: [8]uint8{SmiMemWriteResp, 0, reqHeader.Data[2], reqHeader.Data[3]},
+			Eofc: 4}})
+	}
+	respond(reqHeaders[0])
+	reqFrame := recvFrame(t, downstreamRequest)
+	if len(reqFrame) != len(finalFrame) || reqFrame[0].Data[0] != SmiMemWriteReq {
+		t.Fatalf("unexpected request frame %v", reqFrame)
+	}
+	for _, reqHeader := range append(reqHeaders[1:], reqFrame[0]) {
+		respond(reqHeader)
+	}
+	for i := 0; i != 5; i++ {
+		if frame := recvFrame(t, upstreamResponseA); respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+	waitShutdown(t, &shutdown)
+}
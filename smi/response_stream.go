@@ -0,0 +1,122 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// StreamReadResponse64 is a goroutine which consumes SMI read response frames
+// and delivers the payload data incrementally as it arrives. The payload is
+// realigned so that each Flit64 on the payload output channel carries eight
+// consecutive payload bytes, with the Eofc field of the final payload flit
+// holding the number of valid bytes in that flit, as for SMI frames. Zero
+// length responses do not generate any payload flits. After each payload flit
+// the running payload byte count for the current frame is sent on the
+// progress channel. This is a non-blocking send, so a slow progress consumer
+// will see a subset of the updates but will never stall the payload stream.
+// Once the frame is complete, the read status flag is sent on the completion
+// channel.
+//
+func StreamReadResponse64(
+	smiResponse <-chan Flit64,
+	payloadOutput chan<- Flit64,
+	progressOutput chan<- uint32,
+	completeOutput chan<- bool) {
+
+	for {
+		headerFlit := <-smiResponse
+		readOk := (headerFlit.Data[1] & 0x02) == uint8(0x00)
+		byteCount := uint32(0)
+
+		// The header flit carries up to four payload bytes which are held
+		// until the next payload flit is available.
+		carryData := [4]uint8{
+			headerFlit.Data[4],
+			headerFlit.Data[5],
+			headerFlit.Data[6],
+			headerFlit.Data[7]}
+		moreFlits := headerFlit.Eofc == 0
+		if !moreFlits && headerFlit.Eofc > 4 {
+			byteCount = uint32(headerFlit.Eofc - 4)
+			payloadOutput <- Flit64{
+				Eofc: headerFlit.Eofc - 4,
+				Data: [8]uint8{
+					carryData[0],
+					carryData[1],
+					carryData[2],
+					carryData[3],
+					uint8(0),
+					uint8(0),
+					uint8(0),
+					uint8(0)}}
+			select {
+			case progressOutput <- byteCount:
+			default:
+			}
+		}
+
+		// Realign the remaining payload flits.
+		for moreFlits {
+			respFlit := <-smiResponse
+			moreFlits = respFlit.Eofc == 0
+			outputFlit := Flit64{
+				Eofc: 0,
+				Data: [8]uint8{
+					carryData[0],
+					carryData[1],
+					carryData[2],
+					carryData[3],
+					respFlit.Data[0],
+					respFlit.Data[1],
+					respFlit.Data[2],
+					respFlit.Data[3]}}
+			carryData = [4]uint8{
+				respFlit.Data[4],
+				respFlit.Data[5],
+				respFlit.Data[6],
+				respFlit.Data[7]}
+
+			// On the final flit, determine whether the payload spills over
+			// into an additional output flit.
+			carryCount := uint8(0)
+			if !moreFlits {
+				if respFlit.Eofc > 4 {
+					carryCount = respFlit.Eofc - 4
+				} else {
+					outputFlit.Eofc = respFlit.Eofc + 4
+				}
+			}
+			byteCount += uint32(8)
+			if outputFlit.Eofc != 0 {
+				byteCount -= uint32(8 - outputFlit.Eofc)
+			}
+			payloadOutput <- outputFlit
+			select {
+			case progressOutput <- byteCount:
+			default:
+			}
+
+			if carryCount != 0 {
+				byteCount += uint32(carryCount)
+				payloadOutput <- Flit64{
+					Eofc: carryCount,
+					Data: [8]uint8{
+						carryData[0],
+						carryData[1],
+						carryData[2],
+						carryData[3],
+						uint8(0),
+						uint8(0),
+						uint8(0),
+						uint8(0)}}
+				select {
+				case progressOutput <- byteCount:
+				default:
+				}
+			}
+		}
+		completeOutput <- readOk
+	}
+}
@@ -0,0 +1,36 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// TapResponses64 is a goroutine which forwards flits from its input to the
+// main output, copying each flit to the monitor output for observation. The
+// copy is made using a non-blocking send after the flit has been forwarded,
+// so flits are dropped from the monitor output whenever it is not ready to
+// accept them and a slow monitor never adds latency or backpressure to the
+// main path. A buffered monitor channel should therefore be used. Since
+// individual flits may be dropped, the monitor output does not preserve
+// frame boundaries once it has overflowed, so it is best suited to flit
+// level statistics. Unlike ArbitrateX2Traced, the tap may be placed on any
+// response path and mirrors the flits exactly as they are forwarded. It is
+// normally used for response streams, but may be used with any Flit64 based
+// SMI channel.
+//
+func TapResponses64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	monitorOutput chan<- Flit64) {
+
+	for {
+		frameFlit := <-smiInput
+		smiOutput <- frameFlit
+		select {
+		case monitorOutput <- frameFlit:
+		default:
+		}
+	}
+}
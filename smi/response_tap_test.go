@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTapResponses64SlowMonitor(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64)
+	monitorOutput := make(chan Flit64, 4)
+	go TapResponses64(smiInput, smiOutput, monitorOutput)
+
+	// The monitor is never serviced, so once its buffer is full the
+	// remaining flits are only sent on the main path.
+	respFrames := [][]Flit64{}
+	for i := 0; i != 100; i++ {
+		respFrames = append(respFrames, packFrame64(append(
+			[]byte{SmiMemReadResp, 0, 0, uint8(i)}, make([]byte, 8*(i%8))...)))
+	}
+	go func() {
+		for _, frame := range respFrames {
+			sendFrame64(smiInput, frame)
+		}
+	}()
+	for i, frame := range respFrames {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("response %d: unexpected frame %v", i, output)
+		}
+	}
+
+	// The monitor holds copies of the first flits that were forwarded. The
+	// tap may still be attempting to copy the final flit, so the monitor is
+	// not checked for further flits.
+	expected := append(append([]Flit64{}, respFrames[0]...), respFrames[1]...)
+	expected = append(expected, respFrames[2]...)
+	for i := 0; i != cap(monitorOutput); i++ {
+		if monitorFlit := recvFlit(t, monitorOutput); monitorFlit != expected[i] {
+			t.Fatalf("unexpected monitor flit %d %v", i, monitorFlit)
+		}
+	}
+}
+
+func TestTapResponses64Monitor(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 64)
+	monitorOutput := make(chan Flit64, 64)
+	go TapResponses64(smiInput, smiOutput, monitorOutput)
+
+	// With a serviced monitor, both outputs carry identical flits.
+	frame := packFrame64(append([]byte{SmiMemReadResp, 0, 1, 2}, make([]byte, 40)...))
+	go sendFrame64(smiInput, frame)
+	if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+		t.Fatalf("unexpected main frame %v", output)
+	}
+	if output := recvFrame(t, monitorOutput); !reflect.DeepEqual(output, frame) {
+		t.Fatalf("unexpected monitor frame %v", output)
+	}
+}
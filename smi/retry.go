@@ -0,0 +1,104 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// RetryOnError64 is a goroutine which automatically retries memory access
+// requests that fail with a transient error. It is inserted between an SMI
+// master and the downstream port. Each request frame is buffered until its
+// response has been forwarded, using the 16-bit tag from bytes 2 and 3 of
+// the header to match responses to requests, so the upstream master must use
+// a unique tag for each outstanding request. When an error response frame
+// (SmiMemErrorResp) is received for a buffered request, the error response is
+// discarded and the original request frame is issued again. Once a request
+// has been retried the maximum number of times, any further error response is
+// forwarded upstream. All other response frames are forwarded unchanged.
+//
+func RetryOnError64(
+	upstreamRequest <-chan Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	maxRetries int) {
+
+	var pendingLock sync.Mutex
+	pendingFrames := make(map[uint16][]Flit64)
+	retryCounts := make(map[uint16]int)
+	retryTags := []uint16{}
+	retryReady := make(chan bool, 1)
+
+	// Start goroutine for buffering and issuing new requests, which also
+	// reissues failed requests so that only one goroutine ever sends on the
+	// downstream request channel.
+	go func() {
+		for {
+			select {
+			case headerFlit := <-upstreamRequest:
+				reqFrame := []Flit64{headerFlit}
+				moreFlits := headerFlit.Eofc == 0
+				for moreFlits {
+					bodyFlit := <-upstreamRequest
+					reqFrame = append(reqFrame, bodyFlit)
+					moreFlits = bodyFlit.Eofc == 0
+				}
+				tagId := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+				pendingLock.Lock()
+				pendingFrames[tagId] = reqFrame
+				retryCounts[tagId] = 0
+				pendingLock.Unlock()
+				sendFrame64(downstreamRequest, reqFrame)
+
+			case <-retryReady:
+				pendingLock.Lock()
+				retryFrames := make([][]Flit64, len(retryTags))
+				for i, tagId := range retryTags {
+					retryFrames[i] = pendingFrames[tagId]
+				}
+				retryTags = retryTags[:0]
+				pendingLock.Unlock()
+				for _, reqFrame := range retryFrames {
+					sendFrame64(downstreamRequest, reqFrame)
+				}
+			}
+		}
+	}()
+
+	// Forward responses, queueing requests which failed with an error to be
+	// reissued. The response handling never waits for the request goroutine,
+	// so a downstream port which blocks on its responses can not deadlock.
+	for {
+		respFrame := receiveFrame64(downstreamResponse)
+		tagId := uint16(respFrame[0].Data[2]) | (uint16(respFrame[0].Data[3]) << 8)
+		pendingLock.Lock()
+		_, isPending := pendingFrames[tagId]
+		isRetry := isPending && respFrame[0].Data[0] == SmiMemErrorResp &&
+			retryCounts[tagId] < maxRetries
+		if isRetry {
+			retryCounts[tagId]++
+			retryTags = append(retryTags, tagId)
+		} else {
+			delete(pendingFrames, tagId)
+			delete(retryCounts, tagId)
+		}
+		pendingLock.Unlock()
+
+		if isRetry {
+			select {
+			case retryReady <- true:
+			default:
+			}
+			putFrame64(respFrame)
+		} else {
+			sendFrame64(upstreamResponse, respFrame)
+			putFrame64(respFrame)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryOnErrorFailTwice(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	go RetryOnError64(upstreamRequest, downstreamRequest,
+		downstreamResponse, upstreamResponse, 3)
+
+	// The request fails twice, then succeeds on the third issue.
+	reqFrame := WriteReqFrames64(0, []byte{1, 2, 3}, nil, 9)
+	go sendFrame64(upstreamRequest, reqFrame)
+	for i := 0; i != 3; i++ {
+		issued := recvFrame(t, downstreamRequest)
+		if len(issued) != len(reqFrame) || issued[0] != reqFrame[0] {
+			t.Fatalf("issue %d: unexpected request frame %v", i, issued)
+		}
+		if i < 2 {
+			sendFrame(t, downstreamResponse, packFrame64(errorRespBytes(0, 9, SmiMemErrAddress)))
+		} else {
+			sendFrame(t, downstreamResponse, []Flit64{{
+				Data: [8]uint8{SmiMemWriteResp, 0, 0, 9}, Eofc: 4}})
+		}
+	}
+	frame := recvFrame(t, upstreamResponse)
+	if frame[0].Data[0] != SmiMemWriteResp || respTag(frame[0]) != 9<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+	expectIdle(t, downstreamRequest, 10*time.Millisecond)
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+}
+
+func TestRetryOnErrorUnbufferedMemory(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 64)
+	go RetryOnError64(upstreamRequest, downstreamRequest,
+		downstreamResponse, upstreamResponse, 2)
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 64))
+
+	// Every request is out of range, so retries are issued while new
+	// requests are still arriving.
+	go func() {
+		for i := 0; i != 8; i++ {
+			sendFrame64(upstreamRequest, ReadReqFrames64(uint64(1024+8*i), 8, uint8(i)))
+		}
+	}()
+	for i := 0; i != 8; i++ {
+		frame := recvFrame(t, upstreamResponse)
+		if frame[0].Data[0] != SmiMemErrorResp {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+}
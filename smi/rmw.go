@@ -0,0 +1,87 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// receiveRespBytes reads a complete response frame from the input channel and
+// checks its status and tag, returning the unpacked frame bytes. The tag is
+// expected in byte 3 of the frame header, with byte 2 being zero, as set by
+// the request frame builders.
+//
+func receiveRespBytes(smiResponse <-chan Flit64, tag uint8) ([]byte, error) {
+	frame := receiveFrame64(smiResponse)
+	frameBytes := unpackFrame64(frame)
+	if len(frameBytes) < smiMemRespHeaderSize {
+		return nil, fmt.Errorf("frame ends after %d header bytes", len(frameBytes))
+	}
+	if frameBytes[2] != 0 || frameBytes[3] != tag {
+		return nil, fmt.Errorf("response tag 0x%02X%02X does not match request tag 0x%02X",
+			frameBytes[3], frameBytes[2], tag)
+	}
+	if ok, errorCode := ResponseStatus(frame[0]); !ok {
+		return nil, fmt.Errorf("response error code 0x%02X", errorCode)
+	}
+	return frameBytes, nil
+}
+
+//
+// ReadModifyWrite64 carries out a read-modify-write of the 64-bit little
+// endian word at the specified address. The word is read, the bits which are
+// set in the mask are replaced by the corresponding bits of the new value and
+// the result is written back, waiting for the write response before
+// returning. The same tag is used for the read and write requests, since the
+// read response is always received before the write request is issued. Tag
+// bytes are set as for ReadReqFrames64, so this may be used behind any of the
+// arbiters. The operation is not atomic with respect to other masters which
+// share the same memory. An error is returned if either response is an error
+// response or carries an unexpected tag, in which case no write is issued if
+// the read failed. This is intended for use in simulation and host side test
+// code.
+//
+func ReadModifyWrite64(
+	rmwAddr uint64,
+	mask uint64,
+	value uint64,
+	tag uint8,
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64) error {
+
+	// Read the current contents of the addressed word.
+	sendFrame64(smiRequest, ReadReqFrames64(rmwAddr, 8, tag))
+	respBytes, err := receiveRespBytes(smiResponse, tag)
+	if err != nil {
+		return fmt.Errorf("read failed: %v", err)
+	}
+	if respBytes[0] != SmiMemReadResp || len(respBytes) != smiMemRespHeaderSize+8 {
+		return fmt.Errorf("read response type 0x%02X with %d bytes is not valid",
+			respBytes[0], len(respBytes))
+	}
+	readData := uint64(0)
+	for i := uint(0); i != 8; i++ {
+		readData |= uint64(respBytes[smiMemRespHeaderSize+i]) << (8 * i)
+	}
+
+	// Write back the modified word.
+	writeData := (readData &^ mask) | (value & mask)
+	writeBytes := make([]byte, 8)
+	for i := uint(0); i != 8; i++ {
+		writeBytes[i] = uint8(writeData >> (8 * i))
+	}
+	sendFrame64(smiRequest, WriteReqFrames64(rmwAddr, writeBytes, nil, tag))
+	respBytes, err = receiveRespBytes(smiResponse, tag)
+	if err != nil {
+		return fmt.Errorf("write failed: %v", err)
+	}
+	if respBytes[0] != SmiMemWriteResp {
+		return fmt.Errorf("unexpected write response type 0x%02X", respBytes[0])
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadModifyWrite64PartialMask(t *testing.T) {
+	upstreamRequestB := make(chan Flit64)
+	upstreamResponseB := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := make([]byte, 64)
+	for i := range backing {
+		backing[i] = uint8(0x80 + i)
+	}
+	initial := append([]byte{}, backing...)
+	go ArbitrateX2(make(chan Flit64), make(chan Flit64),
+		upstreamRequestB, upstreamResponseB,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// Only bytes 2 and 3 of the word at address 16 are replaced, with the
+	// upper nibble of byte 7 also being replaced.
+	err := ReadModifyWrite64(16, 0xF0000000FFFF0000, 0x123456789ABCDEF0, 0x5A,
+		upstreamRequestB, upstreamResponseB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append([]byte{}, initial...)
+	expected[18] = 0xBC
+	expected[19] = 0x9A
+	expected[23] = (initial[23] & 0x0F) | 0x10
+	if !bytes.Equal(backing, expected) {
+		t.Fatalf("unexpected memory contents %v", backing)
+	}
+}
+
+func TestReadModifyWrite64ReadError(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 64)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// A read outside the backing memory fails without issuing a write, so
+	// the next request gets the next response.
+	if err := ReadModifyWrite64(64, 0xFF, 0xFF, 1, smiRequest, smiResponse); err == nil {
+		t.Fatal("expected read error")
+	}
+	if err := ReadModifyWrite64(8, 0xFF, 0xA5, 2, smiRequest, smiResponse); err != nil {
+		t.Fatal(err)
+	}
+	if backing[8] != 0xA5 || backing[9] != 0 {
+		t.Fatalf("unexpected memory contents %v", backing[8:16])
+	}
+}
@@ -0,0 +1,88 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// RouteByAddress2 is a goroutine which routes SMI request frames from a
+// single upstream port to one of two downstream ports, depending on the
+// memory address in the request header. This is the inverse of arbitration
+// and allows a single master to access two physical memory regions behind
+// separate downstream ports. Request frames with an address less than the
+// split address are sent to downstream port A and all other request frames
+// are sent to downstream port B. The routing of each request is recorded in a
+// FIFO so that the response frames can be collected from the correct
+// downstream port and returned upstream in the original request order. Up to
+// SmiMemInFlightLimit requests may be outstanding at any time, after which
+// further requests are stalled until a response has been returned. Frames are
+// not modified, so the tags used by the upstream port are passed through
+// unchanged.
+//
+func RouteByAddress2(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequestA chan<- Flit64,
+	downstreamResponseA <-chan Flit64,
+	downstreamRequestB chan<- Flit64,
+	downstreamResponseB <-chan Flit64,
+	splitAddr uint64) {
+
+	// The response routine holds the routing of the oldest outstanding
+	// request, so the FIFO only needs to hold the remainder.
+	// TODO: The channel size here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	routeFifo := make(chan uint8, 3)
+
+	// Route request frames. The address is split across the first two flits,
+	// so both are received before the routing decision is made.
+	go func() {
+		for {
+			headerFlit := <-upstreamRequest
+			var addrFlit Flit64
+			if headerFlit.Eofc == 0 {
+				addrFlit = <-upstreamRequest
+			}
+			reqAddr := ReadAddr([2]Flit64{headerFlit, addrFlit})
+			portId := uint8(1)
+			downstreamRequest := downstreamRequestA
+			if reqAddr >= splitAddr {
+				portId = 2
+				downstreamRequest = downstreamRequestB
+			}
+			routeFifo <- portId
+
+			// Copy the frame to the selected downstream port.
+			downstreamRequest <- headerFlit
+			moreFlits := headerFlit.Eofc == 0
+			if moreFlits {
+				downstreamRequest <- addrFlit
+				moreFlits = addrFlit.Eofc == 0
+			}
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				downstreamRequest <- bodyFlit
+				moreFlits = bodyFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Return response frames in request order.
+	for {
+		portId := <-routeFifo
+		var respFlit Flit64
+		moreFlits := true
+		for moreFlits {
+			switch portId {
+			case 1:
+				respFlit = <-downstreamResponseA
+			default:
+				respFlit = <-downstreamResponseB
+			}
+			upstreamResponse <- respFlit
+			moreFlits = respFlit.Eofc == 0
+		}
+	}
+}
@@ -0,0 +1,92 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouteByAddress2Steering(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 16)
+	downstreamRequestA := make(chan Flit64, 64)
+	downstreamResponseA := make(chan Flit64, 16)
+	downstreamRequestB := make(chan Flit64, 64)
+	downstreamResponseB := make(chan Flit64, 16)
+	go RouteByAddress2(upstreamRequest, upstreamResponse,
+		downstreamRequestA, downstreamResponseA,
+		downstreamRequestB, downstreamResponseB, 0x1000)
+
+	// Interleave requests either side of the split address.
+	reqFrames := [][]Flit64{
+		WriteReqFrames64(0x0FF8, []byte{1, 2, 3, 4, 5, 6, 7, 8}, nil, 0),
+		ReadReqFrames64(0x1000, 16, 1),
+		ReadReqFrames64(0x0000, 8, 2),
+		WriteReqFrames64(0x1FF0, []byte{9, 10, 11}, nil, 3),
+	}
+	expectPorts := []chan Flit64{downstreamRequestA, downstreamRequestB,
+		downstreamRequestA, downstreamRequestB}
+	go func() {
+		for _, frame := range reqFrames {
+			sendFrame64(upstreamRequest, frame)
+		}
+	}()
+	for i, frame := range reqFrames {
+		if output := recvFrame(t, expectPorts[i]); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("request %d: unexpected frame %v", i, output)
+		}
+	}
+	if len(downstreamRequestA) != 0 || len(downstreamRequestB) != 0 {
+		t.Fatal("unexpected additional requests")
+	}
+
+	// Complete the port B requests first. The responses must still be
+	// returned upstream in request order.
+	respFrames := [][]Flit64{
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 0}, Eofc: 4}},
+		packFrame64(append([]byte{SmiMemReadResp, 0, 0, 1}, make([]byte, 16)...)),
+		packFrame64([]byte{SmiMemReadResp, 0, 0, 2, 1, 2, 3, 4, 5, 6, 7, 8}),
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 3}, Eofc: 4}},
+	}
+	sendFrame(t, downstreamResponseB, respFrames[1])
+	sendFrame(t, downstreamResponseB, respFrames[3])
+	expectIdle(t, upstreamResponse, 10*time.Millisecond)
+	sendFrame(t, downstreamResponseA, respFrames[0])
+	sendFrame(t, downstreamResponseA, respFrames[2])
+	for i, frame := range respFrames {
+		if output := recvFrame(t, upstreamResponse); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("response %d: unexpected frame %v", i, output)
+		}
+	}
+}
+
+func TestRouteByAddress2InFlightLimit(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	downstreamRequestA := make(chan Flit64, 64)
+	downstreamResponseA := make(chan Flit64, 16)
+	go RouteByAddress2(upstreamRequest, make(chan Flit64, 16),
+		downstreamRequestA, downstreamResponseA,
+		make(chan Flit64, 64), make(chan Flit64), 0x1000)
+
+	// The fifth outstanding request is stalled until a response returns.
+	go func() {
+		for i := 0; i != SmiMemInFlightLimit+1; i++ {
+			sendFrame64(upstreamRequest, ReadReqFrames64(0, 8, uint8(i)))
+		}
+	}()
+	for i := 0; i != SmiMemInFlightLimit; i++ {
+		recvFrame(t, downstreamRequestA)
+	}
+	expectIdle(t, downstreamRequestA, 10*time.Millisecond)
+	sendFrame(t, downstreamResponseA, []Flit64{{
+		Data: [8]uint8{SmiMemErrorResp, 0, 0, 0, SmiMemErrDecode}, Eofc: 5}})
+	if frame := recvFrame(t, downstreamRequestA); frame[0].Data[3] != SmiMemInFlightLimit {
+		t.Fatalf("unexpected request %v", frame)
+	}
+}
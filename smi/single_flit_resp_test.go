@@ -0,0 +1,143 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+//
+// singleFlitMemory starts a memory model with 64 bytes of backing memory.
+// Reads beyond the end of memory are answered with single flit error
+// responses, which carry no read data.
+//
+func singleFlitMemory() (chan Flit64, chan Flit64) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 64)
+	for i := 0; i != 8; i++ {
+		binary.LittleEndian.PutUint64(backing[8*i:], uint64(0x1111111111111111*(i+1)))
+	}
+	go MemoryModel64(smiRequest, smiResponse, backing)
+	return smiRequest, smiResponse
+}
+
+func TestReadUInt64SingleFlitResponse(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	if readData := ReadUInt64(smiRequest, smiResponse, 1024, DefaultOptions); readData != 0 {
+		t.Fatalf("unexpected data 0x%X for failed read", readData)
+	}
+	if readData := ReadUInt64(smiRequest, smiResponse, 8, DefaultOptions); readData != 0x2222222222222222 {
+		t.Fatalf("unexpected data 0x%X after failed read", readData)
+	}
+}
+
+func TestReadBurstSingleFlitResponse(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	readData := make(chan uint64, 8)
+	if ReadBurstUInt64(smiRequest, smiResponse, 1024, DefaultOptions, 4, readData) {
+		t.Fatal("failed burst read reported as successful")
+	}
+	if len(readData) != 4 {
+		t.Fatalf("failed burst read padded to %d words", len(readData))
+	}
+	for i := 0; i != 4; i++ {
+		if word := <-readData; word != 0 {
+			t.Fatalf("unexpected data 0x%X for failed burst read", word)
+		}
+	}
+	if !ReadBurstUInt64(smiRequest, smiResponse, 16, DefaultOptions, 2, readData) {
+		t.Fatal("burst read failed")
+	}
+	if <-readData != 0x3333333333333333 || <-readData != 0x4444444444444444 {
+		t.Fatal("unexpected data after failed burst read")
+	}
+}
+
+func TestReadBurstUInt32SingleFlitResponse(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	readData := make(chan uint32, 8)
+	if ReadBurstUInt32(smiRequest, smiResponse, 1024, DefaultOptions, 4, readData) {
+		t.Fatal("failed burst read reported as successful")
+	}
+	for i := 0; i != 4; i++ {
+		if word := <-readData; word != 0 {
+			t.Fatalf("unexpected data 0x%X for failed burst read", word)
+		}
+	}
+
+	// A single word read also fits in a single flit response.
+	if !ReadBurstUInt32(smiRequest, smiResponse, 8, DefaultOptions, 1, readData) {
+		t.Fatal("burst read failed")
+	}
+	if word := <-readData; word != 0x22222222 {
+		t.Fatalf("unexpected data 0x%X after failed burst read", word)
+	}
+}
+
+func TestReadBurstUInt16SingleFlitResponse(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	readData := make(chan uint16, 8)
+	if ReadBurstUInt16(smiRequest, smiResponse, 1024, DefaultOptions, 4, readData) {
+		t.Fatal("failed burst read reported as successful")
+	}
+	for i := 0; i != 4; i++ {
+		if word := <-readData; word != 0 {
+			t.Fatalf("unexpected data 0x%X for failed burst read", word)
+		}
+	}
+	if !ReadBurstUInt16(smiRequest, smiResponse, 8, DefaultOptions, 2, readData) {
+		t.Fatal("burst read failed")
+	}
+	if <-readData != 0x2222 || <-readData != 0x2222 {
+		t.Fatal("unexpected data after failed burst read")
+	}
+}
+
+func TestReadBurstUInt8SingleFlitResponse(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	readData := make(chan uint8, 8)
+	if ReadBurstUInt8(smiRequest, smiResponse, 1024, DefaultOptions, 4, readData) {
+		t.Fatal("failed burst read reported as successful")
+	}
+	for i := 0; i != 4; i++ {
+		if word := <-readData; word != 0 {
+			t.Fatalf("unexpected data 0x%X for failed burst read", word)
+		}
+	}
+	if !ReadBurstUInt8(smiRequest, smiResponse, 16, DefaultOptions, 4, readData) {
+		t.Fatal("burst read failed")
+	}
+	for i := 0; i != 4; i++ {
+		if word := <-readData; word != 0x33 {
+			t.Fatalf("unexpected data 0x%X after failed burst read", word)
+		}
+	}
+}
+
+func TestArbitrateSingleFlitResponses(t *testing.T) {
+	smiRequest, smiResponse := singleFlitMemory()
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64)
+	go ArbitrateX2(upstreamRequestA, upstreamResponseA,
+		make(chan Flit64), make(chan Flit64), smiRequest, smiResponse)
+
+	// More failed reads than there are tags, so each tag must be returned.
+	for i := 0; i != 2*SmiMemInFlightLimit; i++ {
+		go sendFrame64(upstreamRequestA, ReadReqFrames64(1024, 8, uint8(i)))
+		frame := recvFrame(t, upstreamResponseA)
+		if len(frame) != 1 || frame[0].Data[0] != SmiMemErrorResp || respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+	}
+	go sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, 9))
+	frame := recvFrame(t, upstreamResponseA)
+	if frame[0].Data[0] != SmiMemReadResp || respTag(frame[0]) != 9<<8 || frame[0].Data[4] != 0x11 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+}
@@ -0,0 +1,89 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// SplitBurst64 is a goroutine which splits oversized read and write request
+// frames into a sequence of request frames which do not exceed
+// SmiMemBurstSize. This allows upstream logic to issue a single large
+// request frame, which may be longer than SmiMemFrame64Size flits, rather
+// than fragmenting the transfer itself. The address of each fragment is
+// incremented by the length of the preceding fragments and the options and
+// tag bytes of the original request are preserved, with the final fragment
+// carrying any non burst aligned tail. The fragment length for write requests
+// is taken from the write payload. Write requests with byte strobes are split
+// into fragments of no more than SmiMemStrobedBurstSize bytes, each carrying
+// the part of the strobe mask for its own write data, and are forwarded
+// unchanged if the strobe mask and write data do not match the length field.
+// All other frames are forwarded unchanged.
+// A separate response is generated for each fragment, so where a single
+// response is required LimitBurst64 should be used instead.
+//
+func SplitBurst64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	for {
+		frame := receiveFrame64(smiInput)
+		frameBytes := unpackFrame64(frame)
+		isWrite := len(frameBytes) >= smiMemReqHeaderSize &&
+			frameBytes[0] == SmiMemWriteReq
+		isRead := len(frameBytes) >= smiMemReqHeaderSize &&
+			frameBytes[0] == SmiMemReadReq
+		if !isWrite && !isRead {
+			sendFrame64(smiOutput, frame)
+			continue
+		}
+
+		// Determine the total transfer length.
+		reqAddr := frameBytesAddr(frameBytes)
+		reqLength := int(frameBytesLength(frameBytes))
+		var reqEnables []bool
+		var reqData []byte
+		if isWrite {
+			reqStrobes, writeData, isValid := writePayload(frameBytes)
+			if !isValid {
+				sendFrame64(smiOutput, frame)
+				continue
+			}
+			reqData = writeData
+			reqLength = len(reqData)
+			reqEnables = writeEnables(reqStrobes, reqLength)
+		}
+		burstSize := SmiMemBurstSize
+		if reqEnables != nil {
+			burstSize = SmiMemStrobedBurstSize
+		}
+
+		// Issue the fragments.
+		fragmentStart := 0
+		moreFragments := true
+		for moreFragments {
+			fragmentEnd := fragmentStart + burstSize
+			if fragmentEnd > reqLength {
+				fragmentEnd = reqLength
+			}
+			var fragmentBytes []byte
+			if isWrite {
+				var fragmentEnables []bool
+				if reqEnables != nil {
+					fragmentEnables = reqEnables[fragmentStart:fragmentEnd]
+				}
+				fragmentBytes = strobedWriteReqBytes(reqAddr+uint64(fragmentStart),
+					frameBytes[1], frameBytes[2], frameBytes[3],
+					fragmentEnables, reqData[fragmentStart:fragmentEnd])
+			} else {
+				fragmentBytes = readReqBytes(reqAddr+uint64(fragmentStart),
+					frameBytes[1], frameBytes[2], frameBytes[3],
+					uint16(fragmentEnd-fragmentStart))
+			}
+			sendFrame64(smiOutput, packFrame64(fragmentBytes))
+			fragmentStart = fragmentEnd
+			moreFragments = fragmentStart != reqLength
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitBurstWrite700(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 128)
+	go SplitBurst64(smiInput, smiOutput)
+
+	writeData := make([]byte, 700)
+	for i := range writeData {
+		writeData[i] = uint8(i * 5)
+	}
+	go sendFrame64(smiInput, packFrame64(writeReqBytes(0x10000, DefaultOptions, 0x11, 0x22, writeData)))
+
+	expectLengths := []int{256, 256, 188}
+	payloadStart := 0
+	for i, expectLength := range expectLengths {
+		frame := recvFrame(t, smiOutput)
+		frameBytes := unpackFrame64(frame)
+		header := [2]Flit64{frame[0], frame[1]}
+		if frameBytes[0] != SmiMemWriteReq || frameBytes[2] != 0x11 || frameBytes[3] != 0x22 ||
+			ReadAddr(header) != 0x10000+uint64(payloadStart) ||
+			int(ReadLength(header)) != expectLength {
+			t.Fatalf("unexpected header for fragment %d %v", i, header)
+		}
+		if !bytes.Equal(frameBytes[smiMemReqHeaderSize:],
+			writeData[payloadStart:payloadStart+expectLength]) {
+			t.Fatalf("unexpected payload for fragment %d", i)
+		}
+		expectEofc := uint8((smiMemReqHeaderSize+expectLength-1)%8 + 1)
+		if frame[len(frame)-1].Eofc != expectEofc {
+			t.Fatalf("unexpected eofc %d for fragment %d", frame[len(frame)-1].Eofc, i)
+		}
+		payloadStart += expectLength
+	}
+}
+
+func TestSplitBurstRead700(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 16)
+	go SplitBurst64(smiInput, smiOutput)
+
+	go sendFrame64(smiInput, packFrame64(readReqBytes(0x10000, DefaultOptions, 0, 7, 700)))
+	expectLengths := []uint16{256, 256, 188}
+	for i, expectLength := range expectLengths {
+		frame := recvFrame(t, smiOutput)
+		header := [2]Flit64{frame[0], frame[1]}
+		if len(frame) != 2 || frame[0].Data[0] != SmiMemReadReq || frame[0].Data[3] != 7 ||
+			ReadAddr(header) != 0x10000+256*uint64(i) || ReadLength(header) != expectLength {
+			t.Fatalf("unexpected fragment %d %v", i, frame)
+		}
+	}
+}
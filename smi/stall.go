@@ -0,0 +1,40 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// PeriodicStall64 is a goroutine which forwards flits from its input to its
+// output, periodically stalling to model backend unavailability such as
+// memory ECC scrubbing. After every stall interval of forwarded flits, no
+// further flits are accepted until the specified number of stall ticks has
+// been received on the tick channel. The handshake clock is therefore the
+// flit transfer while forwarding and the tick channel while stalled, since
+// no flit transfers take place during a stall. Stalls may occur in the middle
+// of a frame, as they would for a real backend. No flits are dropped. A zero
+// stall interval or stall tick count disables stalling.
+//
+func PeriodicStall64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	tick <-chan struct{},
+	stallInterval uint32,
+	stallTicks uint32) {
+
+	flitCount := uint32(0)
+	for {
+		smiOutput <- <-smiInput
+		flitCount++
+
+		// Insert the stall period.
+		if stallInterval != 0 && flitCount == stallInterval {
+			flitCount = 0
+			for i := stallTicks; i != 0; i-- {
+				<-tick
+			}
+		}
+	}
+}
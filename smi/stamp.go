@@ -0,0 +1,99 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Frame stamps are incrementing 8-bit frame identifiers which may be written
+// into an otherwise unused frame byte for debugging. This allows individual
+// frames to be correlated across multiple pipeline stages when viewing
+// simulation or hardware waveforms. The stamp position is specified as a
+// flit index within the frame and a byte index within that flit. Positions
+// which would overwrite the frame type or tag bytes of the header flit are
+// not permitted, since these are used for frame steering by the arbiters.
+// The upper address byte (flit 1, byte 3) is also not permitted, since it
+// carries the deadline hint used by ArbitrateX2Deadline. The stamp
+// overwrites whatever was originally at the selected position, so the
+// position should be chosen to be unused in the design under test. For
+// example, the second highest address byte (flit 1, byte 2) of request
+// frames is unused by memory controllers with less than 48 address bits.
+//
+
+//
+// isValidStampPosition checks that a frame stamp position does not overlap
+// the frame type or tag bytes of the header flit or the deadline hint byte.
+//
+func isValidStampPosition(stampFlit uint8, stampByte uint8) bool {
+	return stampByte < 8 &&
+		!(stampFlit == 0 && (stampByte == 0 || stampByte == 2 || stampByte == 3)) &&
+		!(stampFlit == 1 && stampByte == 3)
+}
+
+//
+// StampFrames64 is a goroutine which forwards Flit64 based SMI frames from
+// its input to its output, writing an incrementing frame stamp into the
+// specified frame position. Frames which are too short to contain the stamp
+// position are forwarded unchanged, but still consume a stamp value. If the
+// stamp position is not valid all frames are forwarded unchanged.
+//
+func StampFrames64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	stampFlit uint8,
+	stampByte uint8) {
+
+	isValid := isValidStampPosition(stampFlit, stampByte)
+	frameStamp := uint8(0)
+	flitIndex := uint8(0)
+	for {
+		frameFlit := <-smiInput
+		if isValid && flitIndex == stampFlit {
+			frameFlit.Data[stampByte&0x07] = frameStamp
+		}
+		smiOutput <- frameFlit
+		if frameFlit.Eofc != 0 {
+			flitIndex = 0
+			frameStamp++
+		} else if flitIndex != 0xFF {
+			flitIndex++
+		}
+	}
+}
+
+//
+// ReadFrameStamps64 is a goroutine which forwards Flit64 based SMI frames
+// from its input to its output unchanged, extracting the frame stamp from
+// the specified frame position of each frame and sending it on the frame
+// stamp channel. This is a non-blocking send, so stamps will be discarded if
+// the frame stamp channel is not being serviced. No stamp is reported for
+// frames which are too short to contain the stamp position or if the stamp
+// position is not valid.
+//
+func ReadFrameStamps64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	stampFlit uint8,
+	stampByte uint8,
+	frameStamps chan<- uint8) {
+
+	isValid := isValidStampPosition(stampFlit, stampByte)
+	flitIndex := uint8(0)
+	for {
+		frameFlit := <-smiInput
+		if isValid && flitIndex == stampFlit {
+			select {
+			case frameStamps <- frameFlit.Data[stampByte&0x07]:
+			default:
+			}
+		}
+		smiOutput <- frameFlit
+		if frameFlit.Eofc != 0 {
+			flitIndex = 0
+		} else if flitIndex != 0xFF {
+			flitIndex++
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Type LatencyStats holds the cumulative latency statistics reported by
+// StatsTap64. Latencies are measured in ticks of the caller supplied tick
+// channel. The mean latency may be calculated by dividing the total latency
+// by the transaction count.
+//
+type LatencyStats struct {
+	Count      uint64
+	TotalTicks uint64
+	MaxTicks   uint64
+}
+
+//
+// StatsTap64 is a goroutine which forwards SMI request and response frames
+// unchanged, collecting request latency statistics. It is inserted between
+// an SMI master and the upstream port of an arbiter in order to profile the
+// latency experienced by that port. The issue time is sampled from the tick
+// count when the request header flit is forwarded and the latency is
+// calculated when the response header flit with the same 16-bit tag is
+// received. The cumulative statistics are sent on the stats output channel
+// after each response header, using a non-blocking send so that the caller
+// can poll the stats output channel at any rate. Responses which do not match
+// an outstanding request are forwarded without being counted. Since the tap
+// does not alter the frames, stats collection may be disabled by leaving it
+// out of the design.
+//
+func StatsTap64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	tick <-chan struct{},
+	statsOutput chan<- LatencyStats) {
+
+	// Issue tags are passed to the statistics loop so that all tick counts
+	// are sampled in one place.
+	issueTags := make(chan uint16)
+
+	// Start goroutine for reporting request issue times.
+	go func() {
+		isHeaderFlit := true
+		for {
+			reqFlit := <-upstreamRequest
+			if isHeaderFlit {
+				issueTags <- uint16(reqFlit.Data[2]) | (uint16(reqFlit.Data[3]) << 8)
+			}
+			downstreamRequest <- reqFlit
+			isHeaderFlit = reqFlit.Eofc != 0
+		}
+	}()
+
+	// Count ticks, record issue times and forward responses, updating the
+	// statistics on each response header. Only one of the response input
+	// and output channels is enabled at a time, so that ticks and issue
+	// times continue to be processed while a response flit is blocked.
+	tickCount := uint64(0)
+	issueTicks := make(map[uint16]uint64)
+	latencyStats := LatencyStats{}
+	isHeaderFlit := true
+	var respFlit Flit64
+	hasRespFlit := false
+	for {
+		var respInput <-chan Flit64
+		var respOutput chan<- Flit64
+		if hasRespFlit {
+			respOutput = upstreamResponse
+		} else {
+			respInput = downstreamResponse
+		}
+		select {
+		case <-tick:
+			tickCount++
+
+		case tagId := <-issueTags:
+			issueTicks[tagId] = tickCount
+
+		case respFlit = <-respInput:
+			hasRespFlit = true
+			if isHeaderFlit {
+				tagId := uint16(respFlit.Data[2]) | (uint16(respFlit.Data[3]) << 8)
+				issueTick, isValid := issueTicks[tagId]
+				delete(issueTicks, tagId)
+				if isValid {
+					latency := tickCount - issueTick
+					latencyStats.Count++
+					latencyStats.TotalTicks += latency
+					if latency > latencyStats.MaxTicks {
+						latencyStats.MaxTicks = latency
+					}
+					select {
+					case statsOutput <- latencyStats:
+					default:
+					}
+				}
+			}
+			isHeaderFlit = respFlit.Eofc != 0
+
+		case respOutput <- respFlit:
+			hasRespFlit = false
+		}
+	}
+}
@@ -0,0 +1,62 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsTapLatency(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 8)
+	downstreamRequest := make(chan Flit64, 8)
+	downstreamResponse := make(chan Flit64)
+	tick := make(chan struct{})
+	statsOutput := make(chan LatencyStats, 1)
+	go StatsTap64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, tick, statsOutput)
+
+	// Each request is answered after a known number of ticks.
+	expect := []LatencyStats{
+		{Count: 1, TotalTicks: 5, MaxTicks: 5},
+		{Count: 2, TotalTicks: 8, MaxTicks: 5},
+		{Count: 3, TotalTicks: 15, MaxTicks: 7},
+	}
+	latencies := []int{5, 3, 7}
+	for i, latency := range latencies {
+		sendFrame(t, upstreamRequest, ReadReqFrames64(0, 8, uint8(i+1)))
+		reqHeader := recvFrame(t, downstreamRequest)[0]
+		for j := 0; j != latency; j++ {
+			tick <- struct{}{}
+		}
+		sendFrame(t, downstreamResponse, packFrame64([]byte{SmiMemReadResp, 0,
+			reqHeader.Data[2], reqHeader.Data[3], 1, 2, 3, 4, 5, 6, 7, 8}))
+		if frame := recvFrame(t, upstreamResponse); respTag(frame[0]) != uint16(i+1)<<8 {
+			t.Fatalf("unexpected response %v", frame)
+		}
+		select {
+		case stats := <-statsOutput:
+			if stats != expect[i] {
+				t.Fatalf("unexpected stats %+v, expected %+v", stats, expect[i])
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for stats")
+		}
+	}
+
+	// Unmatched responses are forwarded without being counted.
+	sendFrame(t, downstreamResponse, []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 9}, Eofc: 4}})
+	if frame := recvFrame(t, upstreamResponse); respTag(frame[0]) != 9<<8 {
+		t.Fatalf("unexpected response %v", frame)
+	}
+	select {
+	case stats := <-statsOutput:
+		t.Fatalf("unexpected stats %+v", stats)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,53 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Constants specifying the SMI memory access error codes. Error response
+// frames consist of the standard response header, with the error status flag
+// set in the status byte, followed by a single error code byte.
+//
+const (
+	SmiMemErrNone        = uint8(0x00) // No error.
+	SmiMemErrUnspecified = uint8(0x01) // Error status without an error code.
+	SmiMemErrAddress     = uint8(0x02) // Access to an invalid memory address.
+	SmiMemErrDecode      = uint8(0x03) // Unsupported or malformed request.
+)
+
+//
+// ResponseStatus decodes the status of a memory access from the header flit
+// of a response frame. The 'ok' flag is true for read and write responses
+// which do not have the error status flag set, in which case the error code
+// is SmiMemErrNone. For error response frames the error code is taken from
+// byte 4 of the frame. Read and write responses with the error status flag
+// set give the unspecified error code and frames which are not responses
+// give the decode error code.
+//
+func ResponseStatus(headerFlit Flit64) (bool, uint8) {
+	isError := (headerFlit.Data[1] & 0x02) != uint8(0x00)
+	switch headerFlit.Data[0] {
+	case SmiMemReadResp, SmiMemWriteResp:
+		if isError {
+			return false, SmiMemErrUnspecified
+		}
+		return true, SmiMemErrNone
+	case SmiMemErrorResp:
+		if headerFlit.Eofc == 0 || headerFlit.Eofc > smiMemRespHeaderSize {
+			return false, headerFlit.Data[4]
+		}
+		return false, SmiMemErrUnspecified
+	default:
+		return false, SmiMemErrDecode
+	}
+}
+
+//
+// errorRespBytes assembles the unpacked bytes of an error response frame.
+//
+func errorRespBytes(tagLower uint8, tagUpper uint8, errorCode uint8) []byte {
+	return []byte{SmiMemErrorResp, 0x02, tagLower, tagUpper, errorCode}
+}
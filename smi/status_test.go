@@ -0,0 +1,56 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestResponseStatusReadPastEnd(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	go MemoryModel64(smiRequest, smiResponse, make([]byte, 64))
+
+	go sendFrame64(smiRequest, ReadReqFrames64(60, 8, 7))
+	frame := recvFrame(t, smiResponse)
+	if frame[0].Data[0] != SmiMemErrorResp {
+		t.Fatalf("frame type 0x%02X, want error response", frame[0].Data[0])
+	}
+	if frame[0].Data[2] != 0 || frame[0].Data[3] != 7 {
+		t.Errorf("tag bytes %v, want [0 7]", frame[0].Data[2:4])
+	}
+	ok, errorCode := ResponseStatus(frame[0])
+	if ok || errorCode != SmiMemErrAddress {
+		t.Errorf("status (%v, 0x%02X), want (false, 0x%02X)", ok, errorCode, SmiMemErrAddress)
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	cases := []struct {
+		name      string
+		header    Flit64
+		ok        bool
+		errorCode uint8
+	}{
+		{"read", Flit64{Data: [8]uint8{SmiMemReadResp, 0}, Eofc: 4}, true, SmiMemErrNone},
+		{"write", Flit64{Data: [8]uint8{SmiMemWriteResp, 0}, Eofc: 4}, true, SmiMemErrNone},
+		{"write error flag", Flit64{Data: [8]uint8{SmiMemWriteResp, 0x02}, Eofc: 4},
+			false, SmiMemErrUnspecified},
+		{"error code", Flit64{Data: [8]uint8{SmiMemErrorResp, 0x02, 0, 0, SmiMemErrDecode}, Eofc: 5},
+			false, SmiMemErrDecode},
+		{"error without code", Flit64{Data: [8]uint8{SmiMemErrorResp, 0x02}, Eofc: 4},
+			false, SmiMemErrUnspecified},
+		{"request", Flit64{Data: [8]uint8{SmiMemReadReq}}, false, SmiMemErrDecode},
+	}
+	for _, c := range cases {
+		ok, errorCode := ResponseStatus(c.header)
+		if ok != c.ok || errorCode != c.errorCode {
+			t.Errorf("%s: got (%v, 0x%02X), want (%v, 0x%02X)",
+				c.name, ok, errorCode, c.ok, c.errorCode)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+//
+// strobedTestData builds write data and a strobe mask which enables every
+// byte apart from those at multiples of three, along with the expected
+// memory contents when written to the start of memory filled with 0xAA.
+//
+func strobedTestData(writeLength int) ([]byte, []byte, []byte) {
+	writeData := make([]byte, writeLength)
+	writeStrobes := make([]byte, (writeLength+7)/8)
+	expected := make([]byte, writeLength)
+	for i := range writeData {
+		writeData[i] = uint8(i + 1)
+		expected[i] = 0xAA
+		if i%3 != 0 {
+			writeStrobes[i/8] |= 1 << uint(i%8)
+			expected[i] = writeData[i]
+		}
+	}
+	return writeData, writeStrobes, expected
+}
+
+//
+// filledBacking allocates memory filled with 0xAA.
+//
+func filledBacking(size int) []byte {
+	return bytes.Repeat([]byte{0xAA}, size)
+}
+
+//
+// expectWriteResps receives the specified number of write responses,
+// failing the test if any of them is not a successful write response.
+//
+func expectWriteResps(t *testing.T, smiResponse <-chan Flit64, count int) {
+	t.Helper()
+	for i := 0; i != count; i++ {
+		frame := recvFrame(t, smiResponse)
+		if ok, _ := ResponseStatus(frame[0]); frame[0].Data[0] != SmiMemWriteResp || !ok {
+			t.Fatalf("response %d: %v", i, frame)
+		}
+	}
+}
+
+func TestMemoryModelStrobedWrite(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := filledBacking(16)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// Byte 2 is masked out and keeps its old value.
+	go sendFrame64(smiRequest, WriteReqFrames64(
+		0, []byte{1, 2, 3, 4, 5}, []byte{0x1B}, 0))
+	expectWriteResps(t, smiResponse, 1)
+	if !bytes.Equal(backing[:6], []byte{1, 2, 0xAA, 4, 5, 0xAA}) {
+		t.Fatalf("unexpected memory contents %v", backing[:6])
+	}
+}
+
+func TestWellFormedStrobedWrite(t *testing.T) {
+	writeData, writeStrobes, _ := strobedTestData(20)
+	frame := WriteReqFrames64(0, writeData, writeStrobes, 0)
+	if ok, reason := WellFormed(frame); !ok {
+		t.Fatalf("strobed write rejected: %s", reason)
+	}
+
+	// Dropping the final data byte leaves the payload one byte short.
+	frameBytes := unpackFrame64(frame)
+	frame = packFrame64(frameBytes[:len(frameBytes)-1])
+	if ok, _ := WellFormed(frame); ok {
+		t.Fatal("truncated strobed write accepted")
+	}
+}
+
+func TestSplitBurstStrobedWrite(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := filledBacking(512)
+	go SplitBurst64(smiInput, smiRequest)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// A single 300 byte frame is built by hand, since WriteReqFrames64
+	// would already split it at the strobed burst size.
+	writeData, writeStrobes, expected := strobedTestData(300)
+	frameBytes := reqHeaderBytes(SmiMemWriteReq, MemOptByteStrobes, 0, 0, 0, 300)
+	frameBytes = append(append(frameBytes, writeStrobes...), writeData...)
+	go sendFrame64(smiInput, packFrame64(frameBytes))
+	expectWriteResps(t, smiResponse, 2)
+	if !bytes.Equal(backing[:300], expected) || backing[300] != 0xAA {
+		t.Fatalf("unexpected memory contents %v", backing[:301])
+	}
+}
+
+func TestAlignBurstsStrobedWrite(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := filledBacking(256)
+	go AlignBursts64(smiInput, smiRequest, 64)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// Writing 100 bytes at offset 30 crosses two line boundaries.
+	writeData, writeStrobes, expected := strobedTestData(100)
+	go sendFrame64(smiInput, WriteReqFrames64(30, writeData, writeStrobes, 0))
+	expectWriteResps(t, smiResponse, 3)
+	if !bytes.Equal(backing[30:130], expected) ||
+		backing[29] != 0xAA || backing[130] != 0xAA {
+		t.Fatalf("unexpected memory contents %v", backing[29:131])
+	}
+}
+
+func TestWriteCombineStrobedWrite(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	backing := filledBacking(64)
+	go CoalesceWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, backing)
+
+	// A plain write followed by a contiguous strobed write must not be
+	// combined, since the strobe mask would be lost.
+	writeData, writeStrobes, expected := strobedTestData(8)
+	go func() {
+		sendFrame64(upstreamRequest, WriteReqFrames64(0, writeData, nil, 0))
+		sendFrame64(upstreamRequest, WriteReqFrames64(8, writeData, writeStrobes, 1))
+		sendFrame64(upstreamRequest, ReadReqFrames64(0, 8, 2))
+	}()
+	expectWriteResps(t, upstreamResponse, 2)
+	recvFrame(t, upstreamResponse)
+	if !bytes.Equal(backing[:8], writeData) || !bytes.Equal(backing[8:16], expected) {
+		t.Fatalf("unexpected memory contents %v", backing[:16])
+	}
+}
+
+func TestFilterFirstPayloadByteStrobedWrite(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64)
+	go FilterByFirstPayloadByte64(smiInput, smiOutput, 0x55)
+
+	// The first strobe mask byte is 0x55, so a frame matching on it rather
+	// than the first write data byte would be passed.
+	go func() {
+		sendFrame64(smiInput, WriteReqFrames64(0, []byte{1, 2, 3}, []byte{0x55}, 0))
+		sendFrame64(smiInput, WriteReqFrames64(0, []byte{0x55, 2, 3}, []byte{0x07}, 1))
+	}()
+	frame := recvFrame(t, smiOutput)
+	if frame[0].Data[3] != 1 {
+		t.Fatalf("unexpected frame passed %v", frame)
+	}
+}
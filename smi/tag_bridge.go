@@ -0,0 +1,85 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// TagBridge64 is a goroutine which connects two independently tagged SMI
+// domains. It uses the same tag substitution scheme as manageUpstreamPort,
+// but as a standalone one to one bridge rather than as part of an arbiter.
+// The tag bytes of each upstream request are replaced by a locally allocated
+// tag, with the local tag ID in byte 2 and zero in byte 3, and the original
+// tag bytes are restored on the corresponding response. Up to
+// SmiMemInFlightLimit requests may be outstanding at any time, after which
+// backpressure is applied to the upstream request channel.
+//
+func TagBridge64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	tagFifo := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		for {
+
+			// Do tag replacement on header.
+			headerFlit := <-upstreamRequest
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			headerFlit.Data[2] = tagId
+			headerFlit.Data[3] = 0
+			downstreamRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				downstreamRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Carry out tag replacement on responses.
+	for {
+
+		// Extract tag ID from header and use it to look up replacement.
+		headerFlit := <-downstreamResponse
+		tagId := headerFlit.Data[2]
+		isValid := tagId < 4 && headerFlit.Data[3] == 0
+		if isValid {
+			headerFlit.Data[2] = tagTableLower[tagId]
+			headerFlit.Data[3] = tagTableUpper[tagId]
+			tagFifo <- tagId
+			upstreamResponse <- headerFlit
+		}
+
+		// Copy remaining flits from downstream to upstream, discarding the
+		// frame if the tag is invalid.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-downstreamResponse
+			moreFlits = bodyFlit.Eofc == 0
+			if isValid {
+				upstreamResponse <- bodyFlit
+			}
+		}
+	}
+}
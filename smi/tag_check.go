@@ -0,0 +1,87 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// CheckTagUniqueness64 is a goroutine for use in simulation which checks that
+// an SMI master which manages its own tags never reuses a tag that is still
+// in flight. It is inserted between the master and the downstream port and
+// forwards all request and response frames unchanged. The 16-bit tag formed
+// from bytes 2 and 3 of each request header is recorded as being in flight
+// until a response with the same tag is received. Any request which reuses
+// an in-flight tag has its header flit sent on the tag error channel. This
+// is a non-blocking send, so errors will be discarded if the error channel
+// is not being serviced. Responses which do not match an in-flight tag are
+// also reported on the tag error channel.
+//
+func CheckTagUniqueness64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	tagErrors chan<- Flit64) {
+
+	var tagLock sync.Mutex
+	tagInFlight := make(map[uint16]bool)
+
+	// Start goroutine for checking request tags.
+	go func() {
+		for {
+			headerFlit := <-upstreamRequest
+			tagId := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+			tagLock.Lock()
+			isDuplicate := tagInFlight[tagId]
+			tagInFlight[tagId] = true
+			tagLock.Unlock()
+			if isDuplicate {
+				select {
+				case tagErrors <- headerFlit:
+				default:
+				}
+			}
+			downstreamRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				downstreamRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Clear response tags before the response reaches the master, so that
+	// the master may immediately reuse them.
+	for {
+		headerFlit := <-downstreamResponse
+		tagId := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+		tagLock.Lock()
+		isInFlight := tagInFlight[tagId]
+		delete(tagInFlight, tagId)
+		tagLock.Unlock()
+		if !isInFlight {
+			select {
+			case tagErrors <- headerFlit:
+			default:
+			}
+		}
+		upstreamResponse <- headerFlit
+
+		// Copy remaining flits from downstream to upstream.
+		moreFlits := headerFlit.Eofc == 0
+		for moreFlits {
+			bodyFlit := <-downstreamResponse
+			moreFlits = bodyFlit.Eofc == 0
+			upstreamResponse <- bodyFlit
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package testbench
+
+import (
+	"github.com/ReconfigureIO/sdaccel/smi"
+)
+
+//
+// CollectFrames64 drains complete frames from an SMI output channel, for use
+// when capturing the output of a stage under test. Collection stops once the
+// specified maximum number of frames has been received, which bounds the
+// memory used if the stage misbehaves, or when a value is received on the
+// stop channel. The stop channel is only checked between flits, and any
+// partially received frame is discarded when collection is stopped. The
+// collected frames are returned in the order they were received. This
+// blocks until collection stops, so it will usually be run in a separate
+// goroutine, with the result being passed back over a channel.
+//
+func CollectFrames64(
+	smiOutput <-chan smi.Flit64,
+	maxFrames int,
+	stop <-chan bool) [][]smi.Flit64 {
+
+	frames := make([][]smi.Flit64, 0)
+	var frame []smi.Flit64
+	for len(frames) < maxFrames {
+		select {
+		case flit := <-smiOutput:
+			frame = append(frame, flit)
+			if flit.Eofc != 0 {
+				frames = append(frames, frame)
+				frame = nil
+			}
+		case <-stop:
+			return frames
+		}
+	}
+	return frames
+}
+
+//
+// FrameEqual compares two frames, returning true if they have the same number
+// of flits, the same end of frame markers and the same valid data bytes. Data
+// bytes beyond the end of frame marker in the final flit are ignored.
+//
+func FrameEqual(frameA []smi.Flit64, frameB []smi.Flit64) bool {
+	if len(frameA) != len(frameB) {
+		return false
+	}
+	for i := range frameA {
+		if frameA[i].Eofc != frameB[i].Eofc {
+			return false
+		}
+		validBytes := 8
+		if frameA[i].Eofc != 0 && frameA[i].Eofc < 8 {
+			validBytes = int(frameA[i].Eofc)
+		}
+		for j := 0; j != validBytes; j++ {
+			if frameA[i].Data[j] != frameB[i].Data[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
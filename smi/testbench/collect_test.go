@@ -0,0 +1,94 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package testbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ReconfigureIO/sdaccel/smi"
+)
+
+func TestCollectFramesMax(t *testing.T) {
+	smiOutput := make(chan smi.Flit64, 16)
+	for i := 0; i != 3; i++ {
+		smiOutput <- smi.Flit64{Data: [8]uint8{uint8(i)}}
+		smiOutput <- smi.Flit64{Data: [8]uint8{uint8(i), 1}, Eofc: 2}
+	}
+	frames := CollectFrames64(smiOutput, 2, make(chan bool))
+	if len(frames) != 2 {
+		t.Fatalf("unexpected frame count %d", len(frames))
+	}
+	for i, frame := range frames {
+		if len(frame) != 2 || frame[0].Data[0] != uint8(i) || frame[1].Eofc != 2 {
+			t.Fatalf("unexpected frame %d %v", i, frame)
+		}
+	}
+	if len(smiOutput) != 2 {
+		t.Fatalf("collected beyond max, %d flits left", len(smiOutput))
+	}
+}
+
+func TestCollectFramesStop(t *testing.T) {
+	smiOutput := make(chan smi.Flit64, 4)
+	stop := make(chan bool)
+	result := make(chan [][]smi.Flit64)
+	go func() {
+		result <- CollectFrames64(smiOutput, 100, stop)
+	}()
+
+	// The partial second frame is discarded on stop.
+	smiOutput <- smi.Flit64{Eofc: 8}
+	smiOutput <- smi.Flit64{}
+	for len(smiOutput) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	stop <- true
+	select {
+	case frames := <-result:
+		if len(frames) != 1 || len(frames[0]) != 1 {
+			t.Fatalf("unexpected frames %v", frames)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("collection did not stop")
+	}
+}
+
+func TestFrameEqual(t *testing.T) {
+	frame := []smi.Flit64{
+		{Data: [8]uint8{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Data: [8]uint8{9, 10, 0xAA}, Eofc: 2},
+	}
+	sameValid := []smi.Flit64{
+		{Data: [8]uint8{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Data: [8]uint8{9, 10, 0xBB}, Eofc: 2},
+	}
+	badData := []smi.Flit64{
+		{Data: [8]uint8{1, 2, 3, 4, 5, 6, 7, 0}},
+		{Data: [8]uint8{9, 10}, Eofc: 2},
+	}
+	badEofc := []smi.Flit64{
+		{Data: [8]uint8{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Data: [8]uint8{9, 10, 0xAA}, Eofc: 3},
+	}
+	cases := []struct {
+		name   string
+		other  []smi.Flit64
+		expect bool
+	}{
+		{"identical", frame, true},
+		{"ignored trailing bytes", sameValid, true},
+		{"data mismatch", badData, false},
+		{"eofc mismatch", badEofc, false},
+		{"length mismatch", frame[:1], false},
+	}
+	for _, c := range cases {
+		if FrameEqual(frame, c.other) != c.expect {
+			t.Errorf("%s: expected %v", c.name, c.expect)
+		}
+	}
+}
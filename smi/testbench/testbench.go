@@ -0,0 +1,306 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+//
+// Package smi/testbench exposes the core SMI protocol building blocks as a
+// set of plain functions and data types which do not depend on any goroutine
+// wiring. This includes tag allocation, frame building and parsing, frame
+// classification and payload packing. It is intended for use by external
+// test frameworks which need to script SMI transactions declaratively and
+// then apply them to the goroutine based components in the smi package.
+//
+package testbench
+
+import (
+	"fmt"
+
+	"github.com/ReconfigureIO/sdaccel/smi"
+)
+
+//
+// Specify the number of header bytes which precede the payload data in
+// request and response frames.
+//
+const (
+	RequestHeaderSize  = smi.SmiMemReqHeaderSize
+	ResponseHeaderSize = smi.SmiMemRespHeaderSize
+)
+
+//
+// Type TagAllocator manages a pool of local tag values in the same way as the
+// arbitrated upstream ports, with tags being allocated in first in, first out
+// order.
+//
+type TagAllocator struct {
+	freeTags []uint8
+	tagCount int
+}
+
+//
+// NewTagAllocator creates a new tag allocator which manages the specified
+// number of tags, numbered from zero. The tag count is normally set to
+// smi.SmiMemInFlightLimit.
+//
+func NewTagAllocator(tagCount int) TagAllocator {
+	freeTags := make([]uint8, tagCount)
+	for i := range freeTags {
+		freeTags[i] = uint8(i)
+	}
+	return TagAllocator{freeTags: freeTags, tagCount: tagCount}
+}
+
+//
+// Alloc allocates the next free tag. The boolean 'allocOk' flag will be false
+// if all the tags are currently in flight.
+//
+func (allocator *TagAllocator) Alloc() (uint8, bool) {
+	if len(allocator.freeTags) == 0 {
+		return 0, false
+	}
+	tagId := allocator.freeTags[0]
+	allocator.freeTags = allocator.freeTags[1:]
+	return tagId, true
+}
+
+//
+// Release returns a previously allocated tag to the pool of free tags.
+//
+func (allocator *TagAllocator) Release(tagId uint8) {
+	allocator.freeTags = append(allocator.freeTags, tagId)
+}
+
+//
+// InFlight returns the number of tags which are currently allocated.
+//
+func (allocator *TagAllocator) InFlight() int {
+	return allocator.tagCount - len(allocator.freeTags)
+}
+
+//
+// Type FrameClass identifies the type of an SMI frame.
+//
+type FrameClass uint8
+
+//
+// Constants specifying the supported frame classes.
+//
+const (
+	ClassUnknown FrameClass = iota
+	ClassReadReq
+	ClassWriteReq
+	ClassReadResp
+	ClassWriteResp
+	ClassErrorResp
+	ClassCasReq
+	ClassCasResp
+)
+
+//
+// Classify determines the class of an SMI frame from its header flit.
+//
+func Classify(headerFlit smi.Flit64) FrameClass {
+	return ClassifyType(headerFlit.Data[0])
+}
+
+//
+// ClassifyType determines the class of an SMI frame from its frame type byte.
+//
+func ClassifyType(frameType uint8) FrameClass {
+	switch frameType {
+	case smi.SmiMemReadReq:
+		return ClassReadReq
+	case smi.SmiMemWriteReq:
+		return ClassWriteReq
+	case smi.SmiMemReadResp:
+		return ClassReadResp
+	case smi.SmiMemWriteResp:
+		return ClassWriteResp
+	case smi.SmiMemErrorResp:
+		return ClassErrorResp
+	case smi.SmiMemAtomicCasReq:
+		return ClassCasReq
+	case smi.SmiMemAtomicCasResp:
+		return ClassCasResp
+	default:
+		return ClassUnknown
+	}
+}
+
+//
+// IsRequest indicates whether the frame class is a request.
+//
+func (class FrameClass) IsRequest() bool {
+	return class == ClassReadReq || class == ClassWriteReq ||
+		class == ClassCasReq
+}
+
+//
+// IsResponse indicates whether the frame class is a response.
+//
+func (class FrameClass) IsResponse() bool {
+	return class == ClassReadResp || class == ClassWriteResp ||
+		class == ClassErrorResp || class == ClassCasResp
+}
+
+//
+// Type Frame provides a decoded representation of an SMI frame. The flags
+// byte holds the options for request frames and the status for response
+// frames. The address and length fields are only used for request frames,
+// and the length of a write request is always taken from the payload.
+//
+type Frame struct {
+	Type    uint8
+	Flags   uint8
+	Tag     uint16
+	Addr    uint64
+	Length  uint16
+	Payload []byte
+}
+
+//
+// BuildReadReq builds the flits for a read request frame.
+//
+func BuildReadReq(addr uint64, length uint16, tag uint16, options smi.Options) []smi.Flit64 {
+	return Frame{
+		Type:   smi.SmiMemReadReq,
+		Flags:  options.Byte(),
+		Tag:    tag,
+		Addr:   addr,
+		Length: length}.Flits()
+}
+
+//
+// BuildWriteReq builds the flits for a write request frame. The payload must
+// not be larger than smi.SmiMemBurstSize.
+//
+func BuildWriteReq(addr uint64, payload []byte, tag uint16, options smi.Options) []smi.Flit64 {
+	return Frame{
+		Type:    smi.SmiMemWriteReq,
+		Flags:   options.Byte(),
+		Tag:     tag,
+		Addr:    addr,
+		Payload: payload}.Flits()
+}
+
+//
+// BuildReadResp builds the flits for a read response frame.
+//
+func BuildReadResp(tag uint16, status uint8, payload []byte) []smi.Flit64 {
+	return Frame{
+		Type:    smi.SmiMemReadResp,
+		Flags:   status,
+		Tag:     tag,
+		Payload: payload}.Flits()
+}
+
+//
+// BuildWriteResp builds the flits for a write response frame.
+//
+func BuildWriteResp(tag uint16, status uint8) []smi.Flit64 {
+	return Frame{
+		Type:  smi.SmiMemWriteResp,
+		Flags: status,
+		Tag:   tag}.Flits()
+}
+
+//
+// Flits encodes the frame as a sequence of flits.
+//
+func (frame Frame) Flits() []smi.Flit64 {
+	frameBytes := []byte{
+		frame.Type,
+		frame.Flags,
+		uint8(frame.Tag),
+		uint8(frame.Tag >> 8)}
+	if ClassifyType(frame.Type).IsRequest() {
+		length := frame.Length
+		if frame.Type == smi.SmiMemWriteReq {
+			length = uint16(len(frame.Payload))
+		}
+		frameBytes = smi.ReqHeaderBytes(frame.Type, frame.Flags,
+			uint8(frame.Tag), uint8(frame.Tag>>8), frame.Addr, length)
+	}
+	frameBytes = append(frameBytes, frame.Payload...)
+	return PackBytes(frameBytes)
+}
+
+//
+// ParseFrame decodes a complete frame from a sequence of flits. An error is
+// returned if the flits do not form a single valid frame.
+//
+func ParseFrame(flits []smi.Flit64) (Frame, error) {
+	frameBytes, err := UnpackBytes(flits)
+	if err != nil {
+		return Frame{}, err
+	}
+	if len(frameBytes) < ResponseHeaderSize {
+		return Frame{}, fmt.Errorf("frame too short: %d bytes", len(frameBytes))
+	}
+	frame := Frame{
+		Type:  frameBytes[0],
+		Flags: frameBytes[1],
+		Tag:   uint16(frameBytes[2]) | (uint16(frameBytes[3]) << 8)}
+
+	switch ClassifyType(frame.Type) {
+	case ClassReadReq, ClassWriteReq, ClassCasReq:
+		if len(frameBytes) < RequestHeaderSize {
+			return Frame{}, fmt.Errorf("request frame too short: %d bytes", len(frameBytes))
+		}
+		header := [2]smi.Flit64{flits[0], flits[1]}
+		frame.Addr = smi.ReadAddr(header)
+		frame.Length = smi.ReadLength(header)
+		frame.Payload = frameBytes[RequestHeaderSize:]
+		if frame.Type == smi.SmiMemWriteReq && int(frame.Length) != len(frame.Payload) {
+			return Frame{}, fmt.Errorf("write length %d does not match payload size %d",
+				frame.Length, len(frame.Payload))
+		}
+		if frame.Type == smi.SmiMemReadReq && len(frame.Payload) != 0 {
+			return Frame{}, fmt.Errorf("read request has %d payload bytes", len(frame.Payload))
+		}
+		if frame.Type == smi.SmiMemAtomicCasReq && 2*int(frame.Length) != len(frame.Payload) {
+			return Frame{}, fmt.Errorf("compare and swap length %d does not match payload size %d",
+				frame.Length, len(frame.Payload))
+		}
+	case ClassReadResp, ClassWriteResp, ClassErrorResp, ClassCasResp:
+		frame.Payload = frameBytes[ResponseHeaderSize:]
+	default:
+		return Frame{}, fmt.Errorf("unknown frame type 0x%02X", frame.Type)
+	}
+	return frame, nil
+}
+
+//
+// PackBytes packs a sequence of frame bytes into flits using
+// smi.PackFrame64, setting the Eofc field of the final flit to the number of
+// valid bytes it contains. An empty byte sequence gives a nil result.
+//
+func PackBytes(frameBytes []byte) []smi.Flit64 {
+	if len(frameBytes) == 0 {
+		return nil
+	}
+	return smi.PackFrame64(frameBytes)
+}
+
+//
+// UnpackBytes extracts the valid frame bytes from a sequence of flits using
+// smi.UnpackFrame64. An error is returned unless only the final flit has a
+// non-zero Eofc field with a valid byte count.
+//
+func UnpackBytes(flits []smi.Flit64) ([]byte, error) {
+	if len(flits) == 0 {
+		return nil, fmt.Errorf("empty frame")
+	}
+	for i, flit := range flits {
+		isLast := i == len(flits)-1
+		if flit.Eofc != 0 && !isLast {
+			return nil, fmt.Errorf("end of frame marker on flit %d of %d", i+1, len(flits))
+		}
+		if isLast && (flit.Eofc == 0 || flit.Eofc > 8) {
+			return nil, fmt.Errorf("invalid end of frame marker %d", flit.Eofc)
+		}
+	}
+	return smi.UnpackFrame64(flits), nil
+}
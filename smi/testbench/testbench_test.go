@@ -0,0 +1,116 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package testbench
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ReconfigureIO/sdaccel/smi"
+)
+
+func TestBuildParseRoundTrip(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	cases := []struct {
+		flits  []smi.Flit64
+		class  FrameClass
+		expect Frame
+	}{
+		{BuildReadReq(0x123456789A, 200, 0x0500, smi.Options{}), ClassReadReq,
+			Frame{Type: smi.SmiMemReadReq, Tag: 0x0500, Addr: 0x123456789A, Length: 200, Payload: []byte{}}},
+		{BuildWriteReq(0x1008, payload, 0x0601, smi.Options{}.Unbuffered()), ClassWriteReq,
+			Frame{Type: smi.SmiMemWriteReq, Flags: smi.MemOptUnbuffered, Tag: 0x0601,
+				Addr: 0x1008, Length: uint16(len(payload)), Payload: payload}},
+		{BuildReadResp(0x0702, 0, payload), ClassReadResp,
+			Frame{Type: smi.SmiMemReadResp, Tag: 0x0702, Payload: payload}},
+		{BuildWriteResp(0x0803, 0x02), ClassWriteResp,
+			Frame{Type: smi.SmiMemWriteResp, Flags: 0x02, Tag: 0x0803, Payload: []byte{}}},
+	}
+	for i, c := range cases {
+		if class := Classify(c.flits[0]); class != c.class {
+			t.Fatalf("case %d: unexpected class %d", i, class)
+		}
+		frame, err := ParseFrame(c.flits)
+		if err != nil || !reflect.DeepEqual(frame, c.expect) {
+			t.Fatalf("case %d: unexpected frame %+v %v", i, frame, err)
+		}
+		if !reflect.DeepEqual(frame.Flits(), c.flits) {
+			t.Fatalf("case %d: re-encoded frame differs", i)
+		}
+	}
+}
+
+func TestBuildMatchesSmiBuilders(t *testing.T) {
+	// The request builders produce the same frames as the smi package
+	// builders, which place the tag in the upper tag byte.
+	if flits := BuildReadReq(0x4000, 64, 0x2A00, smi.Options{}); !reflect.DeepEqual(
+		flits, smi.ReadReqFrames64(0x4000, 64, 0x2A)) {
+		t.Fatalf("unexpected read request %v", flits)
+	}
+	writeData := make([]byte, 100)
+	for i := range writeData {
+		writeData[i] = uint8(i)
+	}
+	if flits := BuildWriteReq(0x4000, writeData, 0x2B00, smi.Options{}); !reflect.DeepEqual(
+		flits, smi.WriteReqFrames64(0x4000, writeData, nil, 0x2B)) {
+		t.Fatalf("unexpected write request %v", flits)
+	}
+}
+
+func TestPackUnpackBytes(t *testing.T) {
+	for _, length := range []int{1, 7, 8, 9, 270} {
+		frameBytes := make([]byte, length)
+		for i := range frameBytes {
+			frameBytes[i] = uint8(i * 3)
+		}
+		flits := PackBytes(frameBytes)
+		unpacked, err := UnpackBytes(flits)
+		if err != nil || !bytes.Equal(unpacked, frameBytes) {
+			t.Fatalf("length %d: unexpected bytes %v %v", length, unpacked, err)
+		}
+	}
+	if flits := PackBytes(nil); flits != nil {
+		t.Fatalf("unexpected flits %v", flits)
+	}
+}
+
+func TestParseFrameErrors(t *testing.T) {
+	readReq := BuildReadReq(0, 8, 0, smi.Options{})
+	badMarker := append([]smi.Flit64{}, readReq...)
+	badMarker[0].Eofc = 8
+	cases := [][]smi.Flit64{
+		nil,
+		{{Data: [8]uint8{smi.SmiMemReadResp, 0, 0}, Eofc: 3}},
+		{{Data: [8]uint8{0x55, 0, 0, 0}, Eofc: 4}},
+		readReq[:1],
+		badMarker,
+		PackBytes(append(smi.ReqHeaderBytes(smi.SmiMemWriteReq, 0, 0, 0, 0, 4), 1, 2)),
+		PackBytes(append(smi.ReqHeaderBytes(smi.SmiMemReadReq, 0, 0, 0, 0, 4), 1)),
+	}
+	for i, flits := range cases {
+		if _, err := ParseFrame(flits); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestTagAllocator(t *testing.T) {
+	allocator := NewTagAllocator(smi.SmiMemInFlightLimit)
+	for i := 0; i != smi.SmiMemInFlightLimit; i++ {
+		if tagId, ok := allocator.Alloc(); !ok || tagId != uint8(i) {
+			t.Fatalf("unexpected tag %d %v", tagId, ok)
+		}
+	}
+	if _, ok := allocator.Alloc(); ok || allocator.InFlight() != smi.SmiMemInFlightLimit {
+		t.Fatal("allocated beyond tag count")
+	}
+	allocator.Release(2)
+	if tagId, ok := allocator.Alloc(); !ok || tagId != 2 {
+		t.Fatalf("unexpected reused tag %d %v", tagId, ok)
+	}
+}
@@ -0,0 +1,44 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Throttle64 is a goroutine which forwards flits from its input to its
+// output, limiting the bandwidth to the specified number of flits per tick
+// received on the tick channel. Each tick replaces any unused allowance from
+// the previous tick, so the allowance does not accumulate while the input is
+// idle and no more than the specified number of flits are ever forwarded
+// between consecutive ticks. Frames may be split across tick boundaries, but
+// the order of flits is always preserved. Flit forwarding is blocked until the
+// first tick is received and a limit of zero blocks all flits. This may be
+// used to model a slow downstream port in simulation or to cap the bandwidth
+// used by a greedy master.
+//
+func Throttle64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	tick <-chan struct{},
+	flitsPerTick int) {
+
+	flitAllowance := 0
+	for {
+		var throttledInput <-chan Flit64
+		if flitAllowance != 0 {
+			throttledInput = smiInput
+		}
+		select {
+		case <-tick:
+			flitAllowance = flitsPerTick
+			if flitAllowance < 0 {
+				flitAllowance = 0
+			}
+		case frameFlit := <-throttledInput:
+			smiOutput <- frameFlit
+			flitAllowance--
+		}
+	}
+}
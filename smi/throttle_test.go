@@ -0,0 +1,70 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestThrottle64FlitsPerTick(t *testing.T) {
+	smiInput := make(chan Flit64, 64)
+	smiOutput := make(chan Flit64, 64)
+	tick := make(chan struct{})
+	go Throttle64(smiInput, smiOutput, tick, 4)
+
+	inputFlits := []Flit64{}
+	for i := 0; i != 3; i++ {
+		inputFlits = append(inputFlits, WriteReqFrames64(uint64(i), make([]byte, 32), nil, uint8(i))...)
+	}
+	for _, flit := range inputFlits {
+		smiInput <- flit
+	}
+
+	// No flits are forwarded before the first tick.
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+
+	// Each tick releases up to four flits, with frames being split across
+	// tick boundaries.
+	outputFlits := []Flit64{}
+	for len(outputFlits) != len(inputFlits) {
+		tick <- struct{}{}
+		tickFlits := len(inputFlits) - len(outputFlits)
+		if tickFlits > 4 {
+			tickFlits = 4
+		}
+		for i := 0; i != tickFlits; i++ {
+			outputFlits = append(outputFlits, recvFlit(t, smiOutput))
+		}
+		expectIdle(t, smiOutput, 10*time.Millisecond)
+	}
+	if !reflect.DeepEqual(outputFlits, inputFlits) {
+		t.Fatalf("unexpected output flits %v", outputFlits)
+	}
+}
+
+func TestThrottle64NoAccumulation(t *testing.T) {
+	smiInput := make(chan Flit64, 64)
+	smiOutput := make(chan Flit64, 64)
+	tick := make(chan struct{})
+	go Throttle64(smiInput, smiOutput, tick, 2)
+
+	// Unused allowance from idle ticks does not carry over.
+	for i := 0; i != 3; i++ {
+		tick <- struct{}{}
+	}
+	for i := 0; i != 6; i++ {
+		smiInput <- Flit64{Data: [8]uint8{uint8(i)}, Eofc: 1}
+	}
+	for i := 0; i != 2; i++ {
+		if flit := recvFlit(t, smiOutput); flit.Data[0] != uint8(i) {
+			t.Fatalf("unexpected flit %v", flit)
+		}
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+}
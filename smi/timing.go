@@ -0,0 +1,95 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// Type TransactionTiming holds the timing information for a single completed
+// transaction, as reported by TimeTransactions64. Times are measured in
+// handshakes, which are the number of flits transferred on the request and
+// response paths since the stage was started.
+//
+type TransactionTiming struct {
+	Tag           uint16
+	IssueCount    uint64
+	CompleteCount uint64
+	Latency       uint64
+}
+
+//
+// TimeTransactions64 is a goroutine which forwards SMI request and response
+// frames unchanged, reporting timing information for each completed
+// transaction. It is inserted between an SMI master and the downstream port.
+// The simulation clock is a count of flit handshakes across both the request
+// and response paths. The issue count is sampled when the request header
+// flit is forwarded, the completion count is sampled when the final response
+// flit is forwarded and the latency is the difference between the two. The
+// timing information is sent on the timing output channel, using a
+// non-blocking send so that it will be discarded if the timing output is not
+// being serviced. Transactions are matched using their 16-bit tag, so the
+// upstream master must use a unique tag for each outstanding transaction.
+//
+func TimeTransactions64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	timingOutput chan<- TransactionTiming) {
+
+	var clockLock sync.Mutex
+	handshakeCount := uint64(0)
+	issueCounts := make(map[uint16]uint64)
+
+	// Start goroutine for recording request issue times.
+	go func() {
+		isHeaderFlit := true
+		for {
+			reqFlit := <-upstreamRequest
+			downstreamRequest <- reqFlit
+			clockLock.Lock()
+			handshakeCount++
+			if isHeaderFlit {
+				tagId := uint16(reqFlit.Data[2]) | (uint16(reqFlit.Data[3]) << 8)
+				issueCounts[tagId] = handshakeCount
+			}
+			clockLock.Unlock()
+			isHeaderFlit = reqFlit.Eofc != 0
+		}
+	}()
+
+	// Forward responses, recording the completion times.
+	isHeaderFlit := true
+	var transactionTiming TransactionTiming
+	isValid := false
+	for {
+		respFlit := <-downstreamResponse
+		upstreamResponse <- respFlit
+		clockLock.Lock()
+		handshakeCount++
+		if isHeaderFlit {
+			transactionTiming.Tag = uint16(respFlit.Data[2]) | (uint16(respFlit.Data[3]) << 8)
+			transactionTiming.IssueCount, isValid = issueCounts[transactionTiming.Tag]
+			delete(issueCounts, transactionTiming.Tag)
+		}
+		transactionTiming.CompleteCount = handshakeCount
+		clockLock.Unlock()
+
+		// Report the timing at the end of each valid response frame.
+		isHeaderFlit = respFlit.Eofc != 0
+		if isHeaderFlit && isValid {
+			transactionTiming.Latency =
+				transactionTiming.CompleteCount - transactionTiming.IssueCount
+			select {
+			case timingOutput <- transactionTiming:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Constants specifying the direction of traced flits.
+//
+const (
+	TraceDirRequest  = uint8(0x00) // Flit sent on the downstream request channel.
+	TraceDirResponse = uint8(0x01) // Flit received on the downstream response channel.
+)
+
+//
+// Type TracedFlit holds a single flit captured by ArbitrateX2Traced, together
+// with the upstream port ID of the frame it belongs to and the direction in
+// which it crossed the downstream port.
+//
+type TracedFlit struct {
+	PortId uint8
+	Dir    uint8
+	Flit   Flit64
+}
+
+//
+// ArbitrateX2Traced is a variant of ArbitrateX2 which records every flit
+// crossing the downstream request and response channels for post-simulation
+// analysis. Request flits are traced once they have been sent downstream and
+// response flits are traced as they are received, so the trace holds the
+// ordered interleaving of request and response flits. Each traced flit is
+// tagged with the ID of the upstream port it belongs to, using 1 for port A
+// and 2 for port B. Traced flits are sent using a non-blocking send, so they
+// will be discarded rather than stalling the datapath if the trace consumer
+// is too slow. Tracing is disabled if the trace channel is nil.
+//
+func ArbitrateX2Traced(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	trace chan<- TracedFlit) {
+
+	if trace == nil {
+		ArbitrateX2(upstreamRequestA, upstreamResponseA, upstreamRequestB,
+			upstreamResponseB, downstreamRequest, downstreamResponse)
+		return
+	}
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+
+	// Run the upstream port management routines.
+	go manageUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, uint8(1))
+	go manageUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, uint8(2))
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data, tracing each flit.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				select {
+				case trace <- TracedFlit{PortId: portId, Dir: TraceDirRequest, Flit: reqFlit}:
+				default:
+				}
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses, tracing each flit.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		select {
+		case trace <- TracedFlit{PortId: portId, Dir: TraceDirResponse, Flit: respFlit}:
+		default:
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
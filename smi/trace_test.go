@@ -0,0 +1,102 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArbitrateX2TracedInterleaving(t *testing.T) {
+	upstreamRequests := []chan Flit64{make(chan Flit64), make(chan Flit64)}
+	upstreamResponses := []chan Flit64{make(chan Flit64, 16), make(chan Flit64, 16)}
+	downstreamRequest := make(chan Flit64, 16)
+	downstreamResponse := make(chan Flit64)
+	trace := make(chan TracedFlit, 64)
+	go ArbitrateX2Traced(upstreamRequests[0], upstreamResponses[0],
+		upstreamRequests[1], upstreamResponses[1],
+		downstreamRequest, downstreamResponse, trace)
+
+	// Collects the trace entries for a frame once it has crossed the
+	// downstream port, since request flits are traced after being sent.
+	traced := []TracedFlit{}
+	collect := func(frameLen int) {
+		for i := 0; i != frameLen; i++ {
+			select {
+			case tracedFlit := <-trace:
+				traced = append(traced, tracedFlit)
+			case <-time.After(testTimeout):
+				t.Fatal("timed out waiting for trace")
+			}
+		}
+	}
+
+	// Issue a write on port A and a read on port B, then complete the read
+	// before the write.
+	expected := []TracedFlit{}
+	go sendFrame64(upstreamRequests[0], WriteReqFrames64(0x100, []byte{1, 2, 3, 4, 5, 6, 7, 8}, nil, 0x11))
+	writeReq := recvFrame(t, downstreamRequest)
+	collect(len(writeReq))
+	for _, flit := range writeReq {
+		expected = append(expected, TracedFlit{PortId: 1, Dir: TraceDirRequest, Flit: flit})
+	}
+	go sendFrame64(upstreamRequests[1], ReadReqFrames64(0x200, 8, 0x22))
+	readReq := recvFrame(t, downstreamRequest)
+	collect(len(readReq))
+	for _, flit := range readReq {
+		expected = append(expected, TracedFlit{PortId: 2, Dir: TraceDirRequest, Flit: flit})
+	}
+	readResp := packFrame64([]byte{SmiMemReadResp, 0, 2, readReq[0].Data[3], 8, 7, 6, 5, 4, 3, 2, 1})
+	sendFrame(t, downstreamResponse, readResp)
+	collect(len(readResp))
+	for _, flit := range readResp {
+		expected = append(expected, TracedFlit{PortId: 2, Dir: TraceDirResponse, Flit: flit})
+	}
+	writeResp := []Flit64{{Data: [8]uint8{SmiMemWriteResp, 0, 1, writeReq[0].Data[3]}, Eofc: 4}}
+	sendFrame(t, downstreamResponse, writeResp)
+	collect(len(writeResp))
+	expected = append(expected, TracedFlit{PortId: 1, Dir: TraceDirResponse, Flit: writeResp[0]})
+
+	if !reflect.DeepEqual(traced, expected) {
+		t.Fatalf("unexpected trace %v", traced)
+	}
+	select {
+	case tracedFlit := <-trace:
+		t.Fatalf("unexpected trace entry %v", tracedFlit)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if readData, tag, err := ParseReadResp64(upstreamResponses[1]); err != nil || tag != 0x22 ||
+		!reflect.DeepEqual(readData, []byte{8, 7, 6, 5, 4, 3, 2, 1}) {
+		t.Fatalf("unexpected read response %v %d %v", readData, tag, err)
+	}
+	if respFrame := recvFrame(t, upstreamResponses[0]); respTag(respFrame[0]) != 0x1100 {
+		t.Fatalf("unexpected write response %v", respFrame)
+	}
+}
+
+func TestArbitrateX2TracedSlowConsumer(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 16)
+	downstreamResponse := make(chan Flit64)
+	go ArbitrateX2Traced(upstreamRequestA, upstreamResponseA,
+		make(chan Flit64), make(chan Flit64),
+		downstreamRequest, downstreamResponse, make(chan TracedFlit))
+
+	// The trace channel is never read, so all traced flits are discarded
+	// without stalling the datapath.
+	for i := 0; i != 8; i++ {
+		go sendFrame64(upstreamRequestA, WriteReqFrames64(0, make([]byte, 32), nil, uint8(i)))
+		reqHeader := recvFrame(t, downstreamRequest)[0]
+		sendFrame(t, downstreamResponse, []Flit64{{
+			Data: [8]uint8{SmiMemWriteResp, 0, 1, reqHeader.Data[3]}, Eofc: 4}})
+		if respFrame := recvFrame(t, upstreamResponseA); respTag(respFrame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %v", respFrame)
+		}
+	}
+}
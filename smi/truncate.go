@@ -0,0 +1,57 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// TruncateResponse64 is a goroutine which forwards Flit64 based SMI response
+// frames from its input to its output, allowing the consumer to abandon the
+// current frame early. Sending true on the truncate request channel while a
+// frame is in progress stops any further flits of that frame from being
+// forwarded, with the remaining flits being drained from the input and
+// discarded. The consumer should then treat the current frame as complete,
+// since no end of frame marker will be received for it. Subsequent frames are
+// forwarded as normal. Since the full response frame is always drained from
+// the input, the stream remains correctly framed and any upstream arbiter
+// will still release the associated tag. Truncate requests received between
+// frames are ignored.
+//
+func TruncateResponse64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	truncateReq <-chan bool) {
+
+	isInFrame := false
+	isTruncating := false
+	for {
+		var frameFlit Flit64
+		select {
+		case frameFlit = <-smiInput:
+		case doTruncate := <-truncateReq:
+			isTruncating = isTruncating || (doTruncate && isInFrame)
+			continue
+		}
+
+		// Forward the flit unless truncated, also accepting truncate
+		// requests while waiting for the output.
+		isInFrame = true
+		isSent := false
+		for !isTruncating && !isSent {
+			select {
+			case smiOutput <- frameFlit:
+				isSent = true
+			case doTruncate := <-truncateReq:
+				isTruncating = doTruncate
+			}
+		}
+
+		// Reset the truncation state at the end of each frame.
+		if frameFlit.Eofc != 0 {
+			isInFrame = false
+			isTruncating = false
+		}
+	}
+}
@@ -0,0 +1,100 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// Tunnelled SMI frames are carried as the payload of a container frame, which
+// allows them to be transported over another framed link. The container frame
+// consists of a header flit, followed by the inner frame flits and then a
+// trailer flit. The header flit holds the SmiTunnelFrame type in byte 0, zero
+// in byte 1 and the 16-bit tunnel ID in bytes 2 and 3. All eight data bytes of
+// each inner flit are copied unchanged to the container frame, with the
+// container Eofc set to zero. The trailer flit holds the Eofc value of the
+// final inner flit in byte 0 and is the final container flit, with an Eofc of
+// 1. This allows frames to be encapsulated and decapsulated without buffering
+// the entire inner frame.
+//
+const SmiTunnelFrame = uint8(0x80)
+
+//
+// Encapsulate64 is a goroutine which wraps each Flit64 based SMI frame from
+// its input in a container frame with the specified tunnel ID.
+//
+func Encapsulate64(
+	smiInput <-chan Flit64,
+	tunnelOutput chan<- Flit64,
+	tunnelId uint16) {
+
+	headerFlit := Flit64{
+		Eofc: 0,
+		Data: [8]uint8{
+			SmiTunnelFrame,
+			uint8(0),
+			uint8(tunnelId),
+			uint8(tunnelId >> 8),
+			uint8(0),
+			uint8(0),
+			uint8(0),
+			uint8(0)}}
+
+	for {
+		innerFlit := <-smiInput
+		tunnelOutput <- headerFlit
+
+		// Copy the inner frame flits, clearing the end of frame marker.
+		innerEofc := innerFlit.Eofc
+		for {
+			innerFlit.Eofc = 0
+			tunnelOutput <- innerFlit
+			if innerEofc != 0 {
+				break
+			}
+			innerFlit = <-smiInput
+			innerEofc = innerFlit.Eofc
+		}
+
+		// Send the trailer flit with the inner end of frame marker.
+		tunnelOutput <- Flit64{
+			Eofc: 1,
+			Data: [8]uint8{innerEofc, 0, 0, 0, 0, 0, 0, 0}}
+	}
+}
+
+//
+// Decapsulate64 is a goroutine which extracts the inner Flit64 based SMI
+// frames from container frames on its input, restoring the original end of
+// frame marker on the final inner flit. Each inner flit is held until the
+// following container flit has been received, so that the final inner flit
+// can be identified. Container frames which do not have the SmiTunnelFrame
+// type are discarded.
+//
+func Decapsulate64(
+	tunnelInput <-chan Flit64,
+	smiOutput chan<- Flit64) {
+
+	for {
+		headerFlit := <-tunnelInput
+		isValid := headerFlit.Data[0] == SmiTunnelFrame
+		moreFlits := headerFlit.Eofc == 0
+		var heldFlit Flit64
+		isHeld := false
+
+		// Forward the held inner flit once the next flit is available.
+		for moreFlits {
+			tunnelFlit := <-tunnelInput
+			moreFlits = tunnelFlit.Eofc == 0
+			if isHeld && isValid {
+				if !moreFlits {
+					heldFlit.Eofc = tunnelFlit.Data[0]
+				}
+				smiOutput <- heldFlit
+			}
+			heldFlit = tunnelFlit
+			isHeld = true
+		}
+	}
+}
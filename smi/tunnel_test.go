@@ -0,0 +1,72 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncapsulateLayout(t *testing.T) {
+	smiInput := make(chan Flit64)
+	tunnelOutput := make(chan Flit64, 8)
+	go Encapsulate64(smiInput, tunnelOutput, 0x1234)
+
+	innerFrame := WriteReqFrames64(0, []byte{1, 2, 3}, nil, 0)
+	go sendFrame64(smiInput, innerFrame)
+	container := recvFrame(t, tunnelOutput)
+	if len(container) != len(innerFrame)+2 ||
+		container[0].Data != [8]uint8{SmiTunnelFrame, 0, 0x34, 0x12} ||
+		container[len(container)-1] != (Flit64{Data: [8]uint8{innerFrame[2].Eofc}, Eofc: 1}) {
+		t.Fatalf("unexpected container frame %v", container)
+	}
+	for i, innerFlit := range innerFrame {
+		if container[i+1].Data != innerFlit.Data || container[i+1].Eofc != 0 {
+			t.Fatalf("unexpected container flit %d %v", i+1, container[i+1])
+		}
+	}
+}
+
+func TestTunnelNestedRoundTrip(t *testing.T) {
+	smiInput := make(chan Flit64)
+	innerTunnel := make(chan Flit64)
+	outerTunnel := make(chan Flit64)
+	outerDecap := make(chan Flit64)
+	smiOutput := make(chan Flit64, 64)
+	go Encapsulate64(smiInput, innerTunnel, 1)
+	go Encapsulate64(innerTunnel, outerTunnel, 2)
+	go Decapsulate64(outerTunnel, outerDecap)
+	go Decapsulate64(outerDecap, smiOutput)
+
+	frames := [][]Flit64{
+		ReadReqFrames64(0x100, 8, 1),
+		WriteReqFrames64(0x200, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, nil, 2),
+		{{Data: [8]uint8{SmiMemWriteResp, 0, 0, 3}, Eofc: 4}},
+	}
+	go func() {
+		for _, frame := range frames {
+			sendFrame64(smiInput, frame)
+		}
+	}()
+	for _, frame := range frames {
+		if output := recvFrame(t, smiOutput); !reflect.DeepEqual(output, frame) {
+			t.Fatalf("unexpected frame %v, expected %v", output, frame)
+		}
+	}
+}
+
+func TestDecapsulateDiscardsInvalid(t *testing.T) {
+	tunnelInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 8)
+	go Decapsulate64(tunnelInput, smiOutput)
+
+	sendFrame(t, tunnelInput, []Flit64{{Data: [8]uint8{0x55}}, {Data: [8]uint8{1}}, {Data: [8]uint8{8}, Eofc: 1}})
+	sendFrame(t, tunnelInput, []Flit64{{Data: [8]uint8{SmiTunnelFrame}}, {Data: [8]uint8{2}}, {Data: [8]uint8{8}, Eofc: 1}})
+	if frame := recvFrame(t, smiOutput); len(frame) != 1 || frame[0].Data[0] != 2 {
+		t.Fatalf("unexpected frame %v", frame)
+	}
+}
@@ -0,0 +1,60 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// UnalignedReadUInt8 reads an arbitrary byte range from the specified SMI
+// memory endpoint, delivering exactly the requested bytes on the read data
+// channel. The read is issued as a word aligned burst which covers the
+// requested range, with the leading bytes before the start address and the
+// trailing bytes after the end of the range being discarded. This includes
+// the case where the start and end of the range lie within the same word.
+// The burst is automatically segmented to respect page boundaries. The status
+// of the read transaction is returned as the boolean 'readOk' flag.
+//
+func UnalignedReadUInt8(
+	smiRequest chan<- Flit64,
+	smiResponse <-chan Flit64,
+	readAddr uintptr,
+	readOptions uint8,
+	readLength uint32,
+	readDataChan chan<- uint8) bool {
+
+	if readLength == 0 {
+		return true
+	}
+
+	// Determine the word aligned range which covers the requested bytes.
+	headBytes := uint32(readAddr) & 0x7
+	alignedAddr := readAddr & 0xFFFFFFFFFFFFFFF8
+	wordCount := (headBytes + readLength + 7) >> 3
+
+	// Issue the aligned burst read.
+	readWordChan := make(chan uint64, 1)
+	readOkChan := make(chan bool, 1)
+	go func() {
+		readOkChan <- ReadBurstUInt64(
+			smiRequest, smiResponse, alignedAddr, readOptions, wordCount, readWordChan)
+	}()
+
+	// Trim the leading and trailing bytes from the aligned read data.
+	skipBytes := headBytes
+	remainingBytes := readLength
+	for i := wordCount; i != 0; i-- {
+		readWord := <-readWordChan
+		for j := 0; j != 8; j++ {
+			if skipBytes != 0 {
+				skipBytes--
+			} else if remainingBytes != 0 {
+				readDataChan <- uint8(readWord)
+				remainingBytes--
+			}
+			readWord >>= 8
+		}
+	}
+	return <-readOkChan
+}
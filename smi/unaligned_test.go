@@ -0,0 +1,51 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnalignedReadUInt8(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 8192)
+	for i := range backing {
+		backing[i] = uint8(i*7 + i>>8)
+	}
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// The ranges lie within a single word, cross a flit boundary, cross a
+	// burst boundary and span several bursts.
+	cases := []struct {
+		readAddr   uintptr
+		readLength uint32
+	}{
+		{3, 2},
+		{5, 6},
+		{250, 20},
+		{1000, 600},
+		{16, 8},
+	}
+	for _, c := range cases {
+		readDataChan := make(chan uint8, c.readLength+8)
+		if !UnalignedReadUInt8(smiRequest, smiResponse, c.readAddr, DefaultOptions,
+			c.readLength, readDataChan) {
+			t.Fatalf("read at %d failed", c.readAddr)
+		}
+		close(readDataChan)
+		readData := []byte{}
+		for readByte := range readDataChan {
+			readData = append(readData, readByte)
+		}
+		if !bytes.Equal(readData, backing[c.readAddr:c.readAddr+uintptr(c.readLength)]) {
+			t.Fatalf("read at %d length %d: unexpected data %v",
+				c.readAddr, c.readLength, readData)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// ValidateFrames64 is a goroutine for use in simulation which checks a stream
+// of SMI frames, passing valid frames through unchanged and reporting
+// malformed frames on the error channel. Each frame is buffered until its end
+// of frame marker is received and is then checked using WellFormed, so frames
+// with an unknown type byte or which end before the complete header has been
+// received are rejected. Frames which do not terminate within
+// SmiMemFrame64Size flits are rejected as soon as the limit is exceeded, with
+// the remaining flits being discarded up to and including the next end of
+// frame marker. Malformed frames are not forwarded. Errors are reported using
+// a non-blocking send, so they will be discarded if the error channel is not
+// being serviced.
+//
+func ValidateFrames64(
+	smiInput <-chan Flit64,
+	smiOutput chan<- Flit64,
+	frameErrors chan<- error) {
+
+	reportError := func(err error) {
+		select {
+		case frameErrors <- err:
+		default:
+		}
+	}
+
+	frame := make([]Flit64, 0, SmiMemFrame64Size+1)
+	for {
+		inputFlit := <-smiInput
+		frame = append(frame, inputFlit)
+		if len(frame) > SmiMemFrame64Size {
+			reportError(fmt.Errorf("frame type 0x%02X exceeds %d flits",
+				frame[0].Data[0], SmiMemFrame64Size))
+			for inputFlit.Eofc == 0 {
+				inputFlit = <-smiInput
+			}
+			frame = frame[:0]
+			continue
+		}
+		if inputFlit.Eofc == 0 {
+			continue
+		}
+
+		// Check the complete frame before forwarding it.
+		if isValid, reason := WellFormed(frame); isValid {
+			sendFrame64(smiOutput, frame)
+		} else {
+			reportError(fmt.Errorf("malformed frame: %s", reason))
+		}
+		frame = frame[:0]
+	}
+}
@@ -0,0 +1,60 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFramesErrors(t *testing.T) {
+	smiInput := make(chan Flit64)
+	smiOutput := make(chan Flit64, 64)
+	frameErrors := make(chan error, 8)
+	go ValidateFrames64(smiInput, smiOutput, frameErrors)
+
+	// A frame with a bogus type byte is rejected.
+	bogusFrame := ReadReqFrames64(0, 8, 1)
+	bogusFrame[0].Data[0] = 0x7F
+	sendFrame(t, smiInput, bogusFrame)
+
+	// An over length frame is rejected once the limit is exceeded.
+	longFrame := WriteReqFrames64(0, make([]byte, 400), nil, 2)
+	for i := range longFrame {
+		longFrame[i].Eofc = 0
+	}
+	longFrame[len(longFrame)-1].Eofc = 8
+	sendFrame(t, smiInput, longFrame)
+
+	// A frame which ends in the first flit after the limit is also rejected.
+	boundaryFrame := packFrame64(writeReqBytes(0, 0, 0, 3, make([]byte, 266)))
+	if len(boundaryFrame) != SmiMemFrame64Size+1 {
+		t.Fatalf("unexpected boundary frame length %d", len(boundaryFrame))
+	}
+	sendFrame(t, smiInput, boundaryFrame)
+
+	// A frame which ends before the end of the header is rejected.
+	sendFrame(t, smiInput, []Flit64{{Data: [8]uint8{SmiMemWriteReq, 0, 0, 4}, Eofc: 4}})
+
+	// Valid frames are passed through unchanged.
+	goodFrame := WriteReqFrames64(0, make([]byte, 20), nil, 4)
+	sendFrame(t, smiInput, goodFrame)
+	if frame := recvFrame(t, smiOutput); !reflect.DeepEqual(frame, goodFrame) {
+		t.Fatalf("unexpected frame %v", frame)
+	}
+	expectIdle(t, smiOutput, 10*time.Millisecond)
+	if len(frameErrors) != 4 {
+		t.Fatalf("unexpected error count %d", len(frameErrors))
+	}
+	for _, expect := range []string{"unknown frame type 0x7F", "exceeds", "exceeds", "malformed"} {
+		if err := <-frameErrors; !strings.Contains(err.Error(), expect) {
+			t.Errorf("unexpected error %q, expected %q", err, expect)
+		}
+	}
+}
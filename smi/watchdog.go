@@ -0,0 +1,256 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+//
+// manageWatchdogUpstreamPort is a variant of manageUpstreamPort which
+// includes a transaction timeout watchdog. The issue time of each tag is
+// recorded in the tag table, using a count of the ticks received on the tick
+// channel. If no response has been received for a tag after the specified
+// number of ticks, an error response frame is sent upstream in place of the
+// missing response and the tag is returned to the tag FIFO. The error
+// response consists of the response header only, with the error status flag
+// set. In order to distinguish late responses from the responses to later
+// transactions which reuse the same tag, the upper six bits of byte 3 of the
+// downstream header hold a tag generation count, which is incremented each
+// time a tag times out. Responses which do not match an outstanding tag and
+// generation are discarded. A timeout of zero ticks disables the watchdog.
+//
+func manageWatchdogUpstreamPort(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	taggedRequest chan<- Flit64,
+	taggedResponse <-chan Flit64,
+	transferReq chan<- uint8,
+	tick <-chan struct{},
+	timeoutTicks uint32,
+	portId uint8) {
+
+	// Split the tags into upper and lower bytes for efficient access.
+	// TODO: The array and channel sizes here should be set using the
+	// SmiMemInFlightLimit constant once supported by the compiler.
+	var tagTableLower [4]uint8
+	var tagTableUpper [4]uint8
+	var tagTableRespType [4]uint8
+	var tagTableGeneration [4]uint8
+	var tagTableIssueTime [4]uint32
+	var tagTableActive [4]bool
+	tagFifo := make(chan uint8, 4)
+	issuedTags := make(chan uint8, 4)
+
+	// Set up the local tag values.
+	for tagInit := uint8(0); tagInit != 4; tagInit++ {
+		tagFifo <- tagInit
+	}
+
+	// Start goroutine for tag replacement on requests.
+	go func() {
+		for {
+
+			// Do tag replacement on header.
+			headerFlit := <-upstreamRequest
+			tagId := <-tagFifo
+			tagTableLower[tagId] = headerFlit.Data[2]
+			tagTableUpper[tagId] = headerFlit.Data[3]
+			tagTableRespType[tagId] = SmiMemWriteResp
+			if headerFlit.Data[0] == SmiMemReadReq {
+				tagTableRespType[tagId] = SmiMemReadResp
+			}
+			headerFlit.Data[2] = portId
+			headerFlit.Data[3] = tagId | (tagTableGeneration[tagId] << 2)
+			issuedTags <- tagId
+			transferReq <- portId
+			taggedRequest <- headerFlit
+
+			// Copy remaining flits from upstream to downstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-upstreamRequest
+				moreFlits = bodyFlit.Eofc == 0
+				taggedRequest <- bodyFlit
+			}
+		}
+	}()
+
+	// Carry out tag replacement on responses and timeout checking on ticks.
+	tickCount := uint32(0)
+	for {
+		select {
+		case tagId := <-issuedTags:
+			tagTableIssueTime[tagId] = tickCount
+			tagTableActive[tagId] = true
+
+		case <-tick:
+			tickCount++
+			for tagId := uint8(0); tagId != 4; tagId++ {
+				if tagTableActive[tagId] && timeoutTicks != 0 &&
+					tickCount-tagTableIssueTime[tagId] >= timeoutTicks {
+					tagTableActive[tagId] = false
+					tagTableGeneration[tagId] = (tagTableGeneration[tagId] + 1) & 0x3F
+					upstreamResponse <- Flit64{
+						Data: [8]uint8{
+							tagTableRespType[tagId],
+							uint8(0x02),
+							tagTableLower[tagId],
+							tagTableUpper[tagId]},
+						Eofc: 4}
+					tagFifo <- tagId
+				}
+			}
+
+		case headerFlit := <-taggedResponse:
+
+			// Record any tags issued before the response was received.
+			hasIssuedTags := true
+			for hasIssuedTags {
+				select {
+				case tagId := <-issuedTags:
+					tagTableIssueTime[tagId] = tickCount
+					tagTableActive[tagId] = true
+				default:
+					hasIssuedTags = false
+				}
+			}
+
+			// Extract tag ID from header and use it to look up replacement,
+			// discarding responses with no matching transaction.
+			tagId := headerFlit.Data[3] & 0x03
+			isValid := tagTableActive[tagId] &&
+				(headerFlit.Data[3]>>2) == tagTableGeneration[tagId]
+			if isValid {
+				tagTableActive[tagId] = false
+				headerFlit.Data[2] = tagTableLower[tagId]
+				headerFlit.Data[3] = tagTableUpper[tagId]
+				upstreamResponse <- headerFlit
+			}
+
+			// Copy remaining flits from downstream to upstream.
+			moreFlits := headerFlit.Eofc == 0
+			for moreFlits {
+				bodyFlit := <-taggedResponse
+				moreFlits = bodyFlit.Eofc == 0
+				if isValid {
+					upstreamResponse <- bodyFlit
+				}
+			}
+			if isValid {
+				tagFifo <- tagId
+			}
+		}
+	}
+}
+
+//
+// ArbitrateX2Watchdog is a variant of ArbitrateX2 which includes a
+// transaction timeout watchdog on each upstream port, so that a hung
+// downstream port does not permanently consume the upstream port tags. If no
+// response is received for a transaction within the specified number of
+// ticks on the tick channel, an error response is returned to the upstream
+// port and the tag is released, as described for manageWatchdogUpstreamPort.
+// The ticks are distributed to the upstream ports without stalling the tick
+// source, with ticks being queued for a port which is busy transferring a
+// response frame.
+//
+func ArbitrateX2Watchdog(
+	upstreamRequestA <-chan Flit64,
+	upstreamResponseA chan<- Flit64,
+	upstreamRequestB <-chan Flit64,
+	upstreamResponseB chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	tick <-chan struct{},
+	timeoutTicks uint32) {
+
+	// Define local channel connections.
+	taggedRequestA := make(chan Flit64, 1)
+	taggedResponseA := make(chan Flit64, 1)
+	taggedRequestB := make(chan Flit64, 1)
+	taggedResponseB := make(chan Flit64, 1)
+	transferReqA := make(chan uint8, 1)
+	transferReqB := make(chan uint8, 1)
+	tickA := make(chan struct{}, 1)
+	tickB := make(chan struct{}, 1)
+
+	// Run the upstream port management routines.
+	go manageWatchdogUpstreamPort(upstreamRequestA, upstreamResponseA,
+		taggedRequestA, taggedResponseA, transferReqA, tickA, timeoutTicks, uint8(1))
+	go manageWatchdogUpstreamPort(upstreamRequestB, upstreamResponseB,
+		taggedRequestB, taggedResponseB, transferReqB, tickB, timeoutTicks, uint8(2))
+
+	// Distribute the ticks to the upstream ports, counting the ticks which
+	// are yet to be accepted by each port so that the tick source is never
+	// stalled by a busy port.
+	go func() {
+		pendingTicksA := uint32(0)
+		pendingTicksB := uint32(0)
+		for {
+			var pendingTickA chan<- struct{}
+			var pendingTickB chan<- struct{}
+			if pendingTicksA != 0 {
+				pendingTickA = tickA
+			}
+			if pendingTicksB != 0 {
+				pendingTickB = tickB
+			}
+			select {
+			case <-tick:
+				pendingTicksA++
+				pendingTicksB++
+			case pendingTickA <- struct{}{}:
+				pendingTicksA--
+			case pendingTickB <- struct{}{}:
+				pendingTicksB--
+			}
+		}
+	}()
+
+	// Arbitrate between transfer requests.
+	go func() {
+		for {
+
+			// Gets port ID of active input.
+			var portId uint8
+			select {
+			case portId = <-transferReqA:
+			case portId = <-transferReqB:
+			}
+
+			// Copy over input data.
+			var reqFlit Flit64
+			moreFlits := true
+			for moreFlits {
+				switch portId {
+				case 1:
+					reqFlit = <-taggedRequestA
+				default:
+					reqFlit = <-taggedRequestB
+				}
+				downstreamRequest <- reqFlit
+				moreFlits = reqFlit.Eofc == 0
+			}
+		}
+	}()
+
+	// Steer transfer responses.
+	portId := uint8(0)
+	isHeaderFlit := true
+	for {
+		respFlit := <-downstreamResponse
+		if isHeaderFlit {
+			portId = respFlit.Data[2]
+		}
+		switch portId {
+		case 1:
+			taggedResponseA <- respFlit
+		case 2:
+			taggedResponseB <- respFlit
+		default:
+			// Discard invalid flit.
+		}
+		isHeaderFlit = respFlit.Eofc != 0
+	}
+}
@@ -0,0 +1,72 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogTimeout(t *testing.T) {
+	upstreamRequestA := make(chan Flit64)
+	upstreamResponseA := make(chan Flit64, 16)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	tick := make(chan struct{})
+	go ArbitrateX2Watchdog(upstreamRequestA, upstreamResponseA,
+		make(chan Flit64), make(chan Flit64),
+		downstreamRequest, downstreamResponse, tick, 3)
+
+	// Use up all the tags with requests which never get a response. The
+	// next request is stalled waiting for a tag.
+	go func() {
+		for i := 0; i != SmiMemInFlightLimit+1; i++ {
+			sendFrame64(upstreamRequestA, ReadReqFrames64(0, 8, uint8(0x10+i)))
+		}
+	}()
+	withheld := make([]Flit64, SmiMemInFlightLimit)
+	for i := range withheld {
+		withheld[i] = recvFrame(t, downstreamRequest)[0]
+	}
+	expectIdle(t, downstreamRequest, 10*time.Millisecond)
+
+	// The tags are not released before the timeout has expired. The issue
+	// time of each tag may be recorded up to one tick late.
+	for i := 0; i != 2; i++ {
+		tick <- struct{}{}
+	}
+	expectIdle(t, upstreamResponseA, 10*time.Millisecond)
+	tick <- struct{}{}
+	tick <- struct{}{}
+	for i := range withheld {
+		errorFlit := recvFlit(t, upstreamResponseA)
+		ok, _ := ResponseStatus(errorFlit)
+		if ok || errorFlit.Data[0] != SmiMemReadResp || errorFlit.Eofc != 4 ||
+			respTag(errorFlit) != uint16(0x10+i)<<8 {
+			t.Fatalf("unexpected error response %v", errorFlit)
+		}
+	}
+
+	// The freed tag is reused with a new generation count.
+	reqHeader := recvFrame(t, downstreamRequest)[0]
+	if reqHeader.Data[3]&0x03 != withheld[0].Data[3]&0x03 ||
+		reqHeader.Data[3]>>2 != (withheld[0].Data[3]>>2)+1 {
+		t.Fatalf("unexpected reissued tag 0x%02X", reqHeader.Data[3])
+	}
+
+	// A late response to the timed out request is discarded, while the
+	// response to the new request is delivered.
+	lateFlit := Flit64{Data: [8]uint8{SmiMemWriteResp, 0, 1, withheld[0].Data[3]}, Eofc: 4}
+	sendFrame(t, downstreamResponse, []Flit64{lateFlit})
+	expectIdle(t, upstreamResponseA, 10*time.Millisecond)
+	sendFrame(t, downstreamResponse, packFrame64(
+		[]byte{SmiMemReadResp, 0, 1, reqHeader.Data[3], 1, 2, 3, 4, 5, 6, 7, 8}))
+	readData, tag, err := ParseReadResp64(upstreamResponseA)
+	if err != nil || tag != 0x10+SmiMemInFlightLimit || len(readData) != 8 {
+		t.Fatalf("unexpected response %v %d %v", readData, tag, err)
+	}
+}
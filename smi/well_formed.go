@@ -0,0 +1,97 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// WellFormed checks that a Flit64 based SMI frame is structurally valid. A
+// valid frame has a single end of frame marker on its final flit, with a valid
+// byte count. It must also have a known frame type and a size which is
+// consistent with that type. Read requests consist of the request header only
+// and write requests consist of the request header followed by the number of
+// payload bytes given in the length field, with any strobe mask described for
+// SmiMemStrobedBurstSize preceding the payload. Write responses consist of the
+// response header, optionally followed by a coalesced response count as
+// generated by CoalesceWriteResponses64, and read responses consist of the
+// response header followed by any amount of read data. Error responses consist
+// of the response header followed by a single error code byte. Atomic compare
+// and swap requests and responses must have the sizes given for
+// SmiMemCasOperandSize. The boolean result indicates whether the frame is
+// valid and the string result gives the reason if it is not. This is intended
+// for use in simulation and testing.
+//
+func WellFormed(frame []Flit64) (bool, string) {
+	if len(frame) == 0 {
+		return false, "empty frame"
+	}
+	for i, frameFlit := range frame {
+		if frameFlit.Eofc > 8 {
+			return false, fmt.Sprintf("invalid end of frame marker %d on flit %d",
+				frameFlit.Eofc, i+1)
+		}
+		if frameFlit.Eofc != 0 && i != len(frame)-1 {
+			return false, fmt.Sprintf("end of frame marker on flit %d of %d",
+				i+1, len(frame))
+		}
+	}
+	if frame[len(frame)-1].Eofc == 0 {
+		return false, "missing end of frame marker"
+	}
+
+	// Check the frame size for the specified frame type.
+	frameBytes := unpackFrame64(frame)
+	frameSize := len(frameBytes)
+	switch frameBytes[0] {
+	case SmiMemReadReq:
+		if frameSize != smiMemReqHeaderSize {
+			return false, fmt.Sprintf("read request size %d is not %d bytes",
+				frameSize, smiMemReqHeaderSize)
+		}
+	case SmiMemWriteReq:
+		if frameSize < smiMemReqHeaderSize {
+			return false, fmt.Sprintf("write request size %d is too short", frameSize)
+		}
+		payloadSize := frameSize - smiMemReqHeaderSize
+		if frameBytes[1]&MemOptByteStrobes != 0 {
+			strobeCount := (int(frameBytesLength(frameBytes)) + 7) / 8
+			if payloadSize != strobeCount+int(frameBytesLength(frameBytes)) {
+				return false, fmt.Sprintf(
+					"write length %d with %d strobe bytes does not match payload size %d",
+					frameBytesLength(frameBytes), strobeCount, payloadSize)
+			}
+		} else if payloadSize != int(frameBytesLength(frameBytes)) {
+			return false, fmt.Sprintf("write length %d does not match payload size %d",
+				frameBytesLength(frameBytes), payloadSize)
+		}
+	case SmiMemWriteResp:
+		if frameSize != smiMemRespHeaderSize && frameSize != smiMemRespHeaderSize+1 {
+			return false, fmt.Sprintf("write response size %d is not valid", frameSize)
+		}
+	case SmiMemReadResp:
+		if frameSize < smiMemRespHeaderSize {
+			return false, fmt.Sprintf("read response size %d is too short", frameSize)
+		}
+	case SmiMemAtomicCasReq:
+		if frameSize != smiMemReqHeaderSize+2*SmiMemCasOperandSize {
+			return false, fmt.Sprintf("compare and swap request size %d is not valid", frameSize)
+		}
+	case SmiMemAtomicCasResp:
+		if frameSize != smiMemRespHeaderSize+1+SmiMemCasOperandSize {
+			return false, fmt.Sprintf("compare and swap response size %d is not valid", frameSize)
+		}
+	case SmiMemErrorResp:
+		if frameSize != smiMemRespHeaderSize+1 {
+			return false, fmt.Sprintf("error response size %d is not valid", frameSize)
+		}
+	default:
+		return false, fmt.Sprintf("unknown frame type 0x%02X", frameBytes[0])
+	}
+	return true, ""
+}
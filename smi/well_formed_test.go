@@ -0,0 +1,58 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+)
+
+func TestWellFormed(t *testing.T) {
+	writeFrame := WriteReqFrames64(0, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, 0)
+	earlyEnd := append([]Flit64{}, writeFrame...)
+	earlyEnd[0].Eofc = 8
+	badEofc := append([]Flit64{}, writeFrame...)
+	badEofc[len(badEofc)-1].Eofc = 9
+	noEnd := append([]Flit64{}, writeFrame...)
+	noEnd[len(noEnd)-1].Eofc = 0
+	badType := append([]Flit64{}, writeFrame...)
+	badType[0].Data[0] = 0x55
+	longRead := ReadReqFrames64(0, 8, 0)
+	longRead[1].Eofc = 0
+	longRead = append(longRead, Flit64{Eofc: 1})
+	readResp := packFrame64([]byte{SmiMemReadResp, 0, 0, 0, 1, 2, 3})
+	writeResp := packFrame64([]byte{SmiMemWriteResp, 0, 0, 0})
+	errorResp := packFrame64(errorRespBytes(0, 0, SmiMemErrAddress))
+
+	testCases := []struct {
+		name  string
+		frame []Flit64
+		valid bool
+	}{
+		{"write request", writeFrame, true},
+		{"read request", ReadReqFrames64(0, 8, 0), true},
+		{"read response", readResp, true},
+		{"write response", writeResp, true},
+		{"error response", errorResp, true},
+		{"cas request", CasReqFrame64(0, 1, 2, 0), true},
+		{"empty", nil, false},
+		{"early end", earlyEnd, false},
+		{"bad eofc", badEofc, false},
+		{"no end", noEnd, false},
+		{"bad type", badType, false},
+		{"short write", writeFrame[:len(writeFrame)-1], false},
+		{"long read", longRead, false},
+	}
+	for _, testCase := range testCases {
+		valid, reason := WellFormed(testCase.frame)
+		if valid != testCase.valid {
+			t.Errorf("%s: valid %v, expected %v (%s)", testCase.name, valid, testCase.valid, reason)
+		}
+		if !valid && reason == "" {
+			t.Errorf("%s: no reason given", testCase.name)
+		}
+	}
+}
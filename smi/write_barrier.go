@@ -0,0 +1,48 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"fmt"
+)
+
+//
+// WriteBarrier64 waits for the responses to the specified number of
+// previously issued write request frames, such as those built by
+// WriteReqFrames64, returning once they have all been received. Response
+// frames are matched by the SmiMemWriteResp frame type byte, with error
+// response frames also counting as write completions so that failed writes
+// do not stall the barrier. Any other response frames are consumed and
+// discarded, so read requests should not be outstanding on the same port.
+// No frames are sent on the downstream request channel, which is accepted so
+// that the barrier connects to the same port channels as the code issuing
+// the writes. An error is returned after all the write responses have been
+// received if any of them was an error response or had the error status flag
+// set, in which case the error describes the first such response. This is
+// intended for use in simulation and host side test code, for example to
+// make sure that a batch of writes is visible in memory before signalling a
+// consumer.
+//
+func WriteBarrier64(
+	down chan<- Flit64,
+	downResp <-chan Flit64,
+	pending int) error {
+
+	var barrierErr error
+	for i := 0; i < pending; {
+		frame := receiveFrame64(downResp)
+		frameType := frame[0].Data[0]
+		if frameType == SmiMemWriteResp || frameType == SmiMemErrorResp {
+			if ok, errorCode := ResponseStatus(frame[0]); !ok && barrierErr == nil {
+				barrierErr = fmt.Errorf("write response %d has error code 0x%02X", i, errorCode)
+			}
+			i++
+		}
+		putFrame64(frame)
+	}
+	return barrierErr
+}
@@ -0,0 +1,65 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteBarrier(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	backing := make([]byte, 64)
+	go MemoryModel64(smiRequest, smiResponse, backing)
+
+	// Issue five writes with the responses held in the memory model until
+	// the barrier is called.
+	go func() {
+		for i := 0; i != 5; i++ {
+			sendFrame64(smiRequest, WriteReqFrames64(
+				uint64(8*i), []byte{uint8(i + 1)}, nil, uint8(i)))
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	barrierDone := make(chan error)
+	go func() {
+		barrierDone <- WriteBarrier64(smiRequest, smiResponse, 5)
+	}()
+	select {
+	case err := <-barrierDone:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for barrier")
+	}
+	for i := 0; i != 5; i++ {
+		if backing[8*i] != uint8(i+1) {
+			t.Fatalf("write %d not visible", i)
+		}
+	}
+
+	// All five responses have been consumed.
+	expectIdle(t, smiResponse, 10*time.Millisecond)
+}
+
+func TestWriteBarrierError(t *testing.T) {
+	smiRequest := make(chan Flit64)
+	smiResponse := make(chan Flit64)
+	go MemoryModel64(smiRequest, smiResponse, make([]byte, 64))
+
+	// A read response is skipped and the out of range write is reported.
+	go func() {
+		sendFrame64(smiRequest, WriteReqFrames64(0, []byte{1}, nil, 0))
+		sendFrame64(smiRequest, ReadReqFrames64(0, 8, 1))
+		sendFrame64(smiRequest, WriteReqFrames64(128, []byte{1}, nil, 2))
+	}()
+	if err := WriteBarrier64(smiRequest, smiResponse, 2); err == nil {
+		t.Fatal("expected error for out of range write")
+	}
+}
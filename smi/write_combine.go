@@ -0,0 +1,206 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"sync"
+)
+
+//
+// WriteCombine64 is a goroutine which merges small write requests to
+// contiguous addresses into larger burst writes. It is inserted between an
+// SMI master and the downstream port. Write payloads are accumulated in a
+// burst buffer for as long as each new write starts at the address which
+// immediately follows the previous one, uses the same options and fits in
+// the same SmiMemBurstSize aligned burst. The buffered writes are flushed as
+// a single write request when a non-contiguous write or any other request is
+// received, when the burst buffer is full or when the buffer has been held
+// for the specified number of ticks on the tick channel. A zero flush tick
+// count disables the timeout, so writes are only flushed by subsequent
+// requests.
+//
+// Each combined write is issued using the tag of its first constituent
+// write. When the combined write response is received, a separate write
+// response is generated for each of the original writes, in issue order and
+// with the original tag bytes and the combined status. If the combined write
+// fails with an error response, a separate error response with the same
+// error code is generated for each of the original writes instead. Each
+// outstanding request must therefore have a unique tag.
+//
+// Combining adds up to the flush timeout to the latency of each write.
+// Requests are always forwarded in issue order and any pending writes are
+// flushed before a read is forwarded, so reads always observe earlier writes
+// from the same master. Writes to overlapping addresses are never merged, so
+// their ordering is also preserved. Write requests with byte strobes are not
+// combined, and are forwarded unchanged in the same way as reads.
+//
+func WriteCombine64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64,
+	tick <-chan struct{},
+	flushTicks uint32) {
+
+	var groupLock sync.Mutex
+	writeGroups := make(map[uint16][]uint16)
+
+	// Start goroutine for combining write requests.
+	go func() {
+		var bufferData []byte
+		var bufferTags []uint16
+		var bufferAddr uint64
+		var bufferOptions uint8
+		bufferTicks := uint32(0)
+
+		// Flush the contents of the burst buffer as a single write.
+		flushBuffer := func() {
+			if len(bufferTags) == 0 {
+				return
+			}
+			groupTag := bufferTags[0]
+			groupLock.Lock()
+			writeGroups[groupTag] = bufferTags
+			groupLock.Unlock()
+			sendFrame64(downstreamRequest, packFrame64(writeReqBytes(
+				bufferAddr, bufferOptions, uint8(groupTag), uint8(groupTag>>8), bufferData)))
+			bufferData = nil
+			bufferTags = nil
+			bufferTicks = 0
+		}
+
+		for {
+			var headerFlit Flit64
+			select {
+			case headerFlit = <-upstreamRequest:
+			case <-tick:
+				if len(bufferTags) != 0 {
+					bufferTicks++
+					if flushTicks != 0 && bufferTicks >= flushTicks {
+						flushBuffer()
+					}
+				}
+				continue
+			}
+
+			// Receive the remainder of the request frame.
+			frame := []Flit64{headerFlit}
+			if headerFlit.Eofc == 0 {
+				frame = append(frame, receiveFrame64(upstreamRequest)...)
+			}
+
+			// Forward anything other than a write request without byte
+			// strobes after flushing the burst buffer.
+			frameBytes := unpackFrame64(frame)
+			if frameBytes[0] != SmiMemWriteReq || len(frameBytes) < smiMemReqHeaderSize ||
+				frameBytes[1]&MemOptByteStrobes != 0 {
+				flushBuffer()
+				sendFrame64(downstreamRequest, frame)
+				continue
+			}
+
+			// Flush the burst buffer if the write can not be combined.
+			writeAddr := frameBytesAddr(frameBytes)
+			writeData := frameBytes[smiMemReqHeaderSize:]
+			bufferEnd := bufferAddr + uint64(len(bufferData))
+			if len(bufferTags) != 0 && (writeAddr != bufferEnd ||
+				frameBytes[1] != bufferOptions ||
+				len(bufferData)+len(writeData) > SmiMemBurstSize ||
+				(bufferEnd%SmiMemBurstSize)+uint64(len(writeData)) > SmiMemBurstSize) {
+				flushBuffer()
+			}
+
+			// Add the write to the burst buffer.
+			if len(bufferTags) == 0 {
+				bufferAddr = writeAddr
+				bufferOptions = frameBytes[1]
+			}
+			bufferData = append(bufferData, writeData...)
+			bufferTags = append(bufferTags,
+				uint16(frameBytes[2])|(uint16(frameBytes[3])<<8))
+			if (bufferAddr+uint64(len(bufferData)))%SmiMemBurstSize == 0 {
+				flushBuffer()
+			}
+		}
+	}()
+
+	// Split combined write responses.
+	for {
+		headerFlit := <-downstreamResponse
+		moreFlits := headerFlit.Eofc == 0
+		groupTag := uint16(headerFlit.Data[2]) | (uint16(headerFlit.Data[3]) << 8)
+		var groupTags []uint16
+		isGroup := false
+		if headerFlit.Data[0] == SmiMemWriteResp || headerFlit.Data[0] == SmiMemErrorResp {
+			groupLock.Lock()
+			groupTags, isGroup = writeGroups[groupTag]
+			delete(writeGroups, groupTag)
+			groupLock.Unlock()
+		}
+
+		// Forward responses for uncombined requests unchanged.
+		if !isGroup {
+			upstreamResponse <- headerFlit
+			for moreFlits {
+				bodyFlit := <-downstreamResponse
+				moreFlits = bodyFlit.Eofc == 0
+				upstreamResponse <- bodyFlit
+			}
+			continue
+		}
+
+		// Discard any write response body and generate the split responses.
+		for moreFlits {
+			bodyFlit := <-downstreamResponse
+			moreFlits = bodyFlit.Eofc == 0
+		}
+		if headerFlit.Data[0] == SmiMemErrorResp {
+			_, errorCode := ResponseStatus(headerFlit)
+			for _, writeTag := range groupTags {
+				sendFrame64(upstreamResponse, packFrame64(errorRespBytes(
+					uint8(writeTag), uint8(writeTag>>8), errorCode)))
+			}
+			continue
+		}
+		for _, writeTag := range groupTags {
+			upstreamResponse <- Flit64{
+				Eofc: 4,
+				Data: [8]uint8{
+					uint8(SmiMemWriteResp),
+					headerFlit.Data[1],
+					uint8(writeTag),
+					uint8(writeTag >> 8),
+					uint8(0),
+					uint8(0),
+					uint8(0),
+					uint8(0)}}
+		}
+	}
+}
+
+//
+// CoalesceWrites64 is a goroutine which merges consecutive write requests to
+// contiguous addresses into burst writes of up to SmiMemBurstSize bytes. This
+// is equivalent to WriteCombine64 with the flush timeout disabled, so the
+// buffered writes are only flushed when a non-contiguous write or any other
+// request is received, or when the burst is full. Each combined write uses
+// the tag of its first constituent write, and the combined write response is
+// split into separate responses for each of the original writes, with their
+// original tags. Since the final writes in a sequence are held until the
+// next request is received, the upstream master should follow a sequence of
+// writes with a read or a write to a non-contiguous address when it needs to
+// wait for the write responses.
+//
+func CoalesceWrites64(
+	upstreamRequest <-chan Flit64,
+	upstreamResponse chan<- Flit64,
+	downstreamRequest chan<- Flit64,
+	downstreamResponse <-chan Flit64) {
+
+	WriteCombine64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse, nil, 0)
+}
@@ -0,0 +1,86 @@
+//
+// (c) 2018 ReconfigureIO
+//
+// <COPYRIGHT TERMS>
+//
+
+package smi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteCombineErrorSplit(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64)
+	downstreamRequest := make(chan Flit64)
+	downstreamResponse := make(chan Flit64)
+	go CoalesceWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+	go MemoryModel64(downstreamRequest, downstreamResponse, make([]byte, 256))
+
+	// Three contiguous writes beyond the end of memory are combined and
+	// then flushed by a read.
+	go func() {
+		for i := 0; i != 3; i++ {
+			sendFrame64(upstreamRequest, WriteReqFrames64(
+				uint64(512+8*i), make([]byte, 8), nil, uint8(i)))
+		}
+		sendFrame64(upstreamRequest, ReadReqFrames64(0, 8, 3))
+	}()
+	for i := 0; i != 3; i++ {
+		frame := recvFrame(t, upstreamResponse)
+		ok, errorCode := ResponseStatus(frame[0])
+		if frame[0].Data[0] != SmiMemErrorResp || ok || errorCode != SmiMemErrAddress ||
+			respTag(frame[0]) != uint16(i)<<8 {
+			t.Fatalf("response %d: %v", i, frame)
+		}
+	}
+	frame := recvFrame(t, upstreamResponse)
+	if frame[0].Data[0] != SmiMemReadResp || respTag(frame[0]) != 3<<8 {
+		t.Fatalf("unexpected read response %v", frame)
+	}
+}
+
+func TestCoalesceWritesFullBurst(t *testing.T) {
+	upstreamRequest := make(chan Flit64)
+	upstreamResponse := make(chan Flit64, 64)
+	downstreamRequest := make(chan Flit64, 64)
+	downstreamResponse := make(chan Flit64)
+	go CoalesceWrites64(upstreamRequest, upstreamResponse,
+		downstreamRequest, downstreamResponse)
+
+	// Thirty two contiguous 8 byte writes fill a single burst, which is
+	// flushed without waiting for a further request.
+	writeData := make([]byte, SmiMemBurstSize)
+	for i := range writeData {
+		writeData[i] = uint8(i * 3)
+	}
+	go func() {
+		for i := 0; i != 32; i++ {
+			sendFrame64(upstreamRequest, WriteReqFrames64(
+				uint64(0x1000+8*i), writeData[8*i:8*i+8], nil, uint8(i)))
+		}
+	}()
+	frame := recvFrame(t, downstreamRequest)
+	frameBytes := unpackFrame64(frame)
+	header := [2]Flit64{frame[0], frame[1]}
+	if frameBytes[0] != SmiMemWriteReq || respTag(frame[0]) != 0 ||
+		ReadAddr(header) != 0x1000 || ReadLength(header) != SmiMemBurstSize ||
+		!bytes.Equal(frameBytes[smiMemReqHeaderSize:], writeData) {
+		t.Fatalf("unexpected burst frame %v", frame)
+	}
+	expectIdle(t, downstreamRequest, 10*time.Millisecond)
+
+	// The single burst response is split into a response for each write.
+	sendFrame(t, downstreamResponse, []Flit64{{
+		Data: [8]uint8{SmiMemWriteResp, 0, frame[0].Data[2], frame[0].Data[3]}, Eofc: 4}})
+	for i := 0; i != 32; i++ {
+		respFrame := recvFrame(t, upstreamResponse)
+		if respFrame[0].Data[0] != SmiMemWriteResp || respTag(respFrame[0]) != uint16(i)<<8 {
+			t.Fatalf("unexpected response %d %v", i, respFrame)
+		}
+	}
+}